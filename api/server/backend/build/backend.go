@@ -9,9 +9,11 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/system"
 	"github.com/docker/docker/builder"
 	buildkit "github.com/docker/docker/builder/builder-next"
 	daemonevents "github.com/docker/docker/daemon/events"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/pkg/errors"
@@ -20,7 +22,7 @@ import (
 
 // ImageComponent provides an interface for working with images
 type ImageComponent interface {
-	SquashImage(from string, to string) (string, error)
+	SquashImage(ctx context.Context, from string, to string) (string, error)
 	TagImage(context.Context, image.ID, reference.Named) error
 }
 
@@ -49,6 +51,14 @@ func (b *Backend) RegisterGRPC(s *grpc.Server) {
 	}
 }
 
+// InspectBuilder describes the BuildKit builder and its worker.
+func (b *Backend) InspectBuilder(ctx context.Context) (*system.BuilderInfo, error) {
+	if b.buildkit == nil {
+		return nil, errdefs.NotImplemented(errors.New("no buildkit builder available"))
+	}
+	return b.buildkit.Inspect(ctx)
+}
+
 // Build builds an image from a Source
 func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string, error) {
 	options := config.Options
@@ -78,7 +88,7 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 
 	imageID := build.ImageID
 	if options.Squash {
-		if imageID, err = squashBuild(build, b.imageComponent); err != nil {
+		if imageID, err = squashBuild(ctx, build, b.imageComponent); err != nil {
 			return "", err
 		}
 		if config.ProgressWriter.AuxFormatter != nil {
@@ -112,17 +122,67 @@ func (b *Backend) PruneCache(ctx context.Context, opts types.BuildCachePruneOpti
 	return &types.BuildCachePruneReport{SpaceReclaimed: uint64(buildCacheSize), CachesDeleted: cacheIDs}, nil
 }
 
+// CacheMounts lists the `RUN --mount=type=cache` volumes held by the builder.
+func (b *Backend) CacheMounts(ctx context.Context) ([]*types.BuildCacheMount, error) {
+	if b.buildkit == nil {
+		return nil, errdefs.NotImplemented(errors.New("no buildkit builder available"))
+	}
+	return b.buildkit.CacheMounts(ctx)
+}
+
+// PruneCacheMounts resets the cache mounts with the given cache IDs.
+func (b *Backend) PruneCacheMounts(ctx context.Context, ids []string) (*types.BuildCacheMountPruneReport, error) {
+	if b.buildkit == nil {
+		return nil, errdefs.NotImplemented(errors.New("no buildkit builder available"))
+	}
+	report, err := b.buildkit.PruneCacheMounts(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to prune build cache mounts")
+	}
+	b.eventsService.Log("prune", events.BuilderEventType, events.Actor{
+		Attributes: map[string]string{
+			"reclaimed": strconv.FormatUint(report.SpaceReclaimed, 10),
+		},
+	})
+	return report, nil
+}
+
 // Cancel cancels the build by ID
 func (b *Backend) Cancel(ctx context.Context, id string) error {
 	return b.buildkit.Cancel(ctx, id)
 }
 
-func squashBuild(build *builder.Result, imageComponent ImageComponent) (string, error) {
+// RemoveBuildHistory deletes the history record of a single build by its ref.
+func (b *Backend) RemoveBuildHistory(ctx context.Context, ref string) error {
+	if b.buildkit == nil {
+		return errdefs.NotImplemented(errors.New("no buildkit builder available"))
+	}
+	return b.buildkit.RemoveBuildHistory(ctx, ref)
+}
+
+// RemoveCacheRecord deletes a single build-cache record by its ID.
+func (b *Backend) RemoveCacheRecord(ctx context.Context, id string) (*types.BuildCachePruneReport, error) {
+	if b.buildkit == nil {
+		return nil, errdefs.NotImplemented(errors.New("no buildkit builder available"))
+	}
+	size, err := b.buildkit.RemoveCacheRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	b.eventsService.Log("prune", events.BuilderEventType, events.Actor{
+		Attributes: map[string]string{
+			"reclaimed": strconv.FormatInt(size, 10),
+		},
+	})
+	return &types.BuildCachePruneReport{SpaceReclaimed: uint64(size), CachesDeleted: []string{id}}, nil
+}
+
+func squashBuild(ctx context.Context, build *builder.Result, imageComponent ImageComponent) (string, error) {
 	var fromID string
 	if build.FromImage != nil {
 		fromID = build.FromImage.ImageID()
 	}
-	imageID, err := imageComponent.SquashImage(build.ImageID, fromID)
+	imageID, err := imageComponent.SquashImage(ctx, build.ImageID, fromID)
 	if err != nil {
 		return "", errors.Wrap(err, "error squashing image")
 	}
@@ -0,0 +1,36 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/containerd/containerd/namespaces"
+)
+
+// NamespaceMiddleware scopes requests to a containerd namespace based on the
+// TLS identity of the client. Requests from a client whose certificate Common
+// Name appears in the mapping are handled in the mapped namespace instead of
+// the daemon's default one, so each mapped client only sees the images of its
+// own namespace. Content blobs are still shared between namespaces by the
+// containerd content store. Unmapped clients keep the default namespace.
+type NamespaceMiddleware struct {
+	namespaces map[string]string
+}
+
+// NewNamespaceMiddleware creates a new NamespaceMiddleware with the given
+// mapping of client certificate Common Names to containerd namespaces.
+func NewNamespaceMiddleware(mapping map[string]string) NamespaceMiddleware {
+	return NamespaceMiddleware{namespaces: mapping}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m NamespaceMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			if ns, ok := m.namespaces[r.TLS.PeerCertificates[0].Subject.CommonName]; ok {
+				ctx = namespaces.WithNamespace(ctx, ns)
+			}
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containerd/containerd/namespaces"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestNamespaceMiddleware(t *testing.T) {
+	m := NewNamespaceMiddleware(map[string]string{
+		"tenant-a": "tenant-a-images",
+	})
+
+	tests := []struct {
+		doc         string
+		tls         *tls.ConnectionState
+		expectedNS  string
+		expectFound bool
+	}{
+		{
+			doc: "no TLS",
+		},
+		{
+			doc: "unmapped identity",
+			tls: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: "someone-else"}},
+			}},
+		},
+		{
+			doc: "mapped identity",
+			tls: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: "tenant-a"}},
+			}},
+			expectedNS:  "tenant-a-images",
+			expectFound: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.doc, func(t *testing.T) {
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+				ns, ok := namespaces.Namespace(ctx)
+				assert.Check(t, is.Equal(tc.expectFound, ok))
+				assert.Check(t, is.Equal(tc.expectedNS, ns))
+				return nil
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, "/images/json", nil)
+			req.TLS = tc.tls
+			resp := httptest.NewRecorder()
+
+			err := m.WrapHandler(handler)(context.Background(), resp, req, map[string]string{})
+			assert.NilError(t, err)
+		})
+	}
+}
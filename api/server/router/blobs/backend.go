@@ -0,0 +1,16 @@
+package blobs // import "github.com/docker/docker/api/server/router/blobs"
+
+import (
+	"context"
+	"io"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Backend is all the methods that need to be implemented
+// to provide blob specific functionality.
+type Backend interface {
+	// ReadBlob opens the blob with the given digest in the content store
+	// for reading and returns its size.
+	ReadBlob(ctx context.Context, dgst digest.Digest) (io.ReadCloser, int64, error)
+}
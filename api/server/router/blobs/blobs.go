@@ -0,0 +1,31 @@
+package blobs // import "github.com/docker/docker/api/server/router/blobs"
+
+import "github.com/docker/docker/api/server/router"
+
+// blobsRouter is a router to read blobs from the content store
+type blobsRouter struct {
+	backend Backend
+	routes  []router.Route
+}
+
+// NewRouter initializes a new blobs router
+func NewRouter(backend Backend) router.Router {
+	r := &blobsRouter{
+		backend: backend,
+	}
+	r.initRoutes()
+	return r
+}
+
+// Routes returns the available routes
+func (br *blobsRouter) Routes() []router.Route {
+	return br.routes
+}
+
+// initRoutes initializes the routes in the blobs router
+func (br *blobsRouter) initRoutes() {
+	br.routes = []router.Route{
+		// GET
+		router.NewGetRoute("/blobs/{digest}", br.getBlob),
+	}
+}
@@ -0,0 +1,41 @@
+package blobs // import "github.com/docker/docker/api/server/router/blobs"
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// maxBlobSize caps the size of the blobs served by the blob read endpoint.
+// The endpoint is meant for reading configs, manifests and moderately sized
+// layers; anything larger should be exported through "GET /images/get"
+// instead.
+const maxBlobSize = 512 * 1024 * 1024
+
+func (br *blobsRouter) getBlob(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	dgst, err := digest.Parse(vars["digest"])
+	if err != nil {
+		return errdefs.InvalidParameter(errors.Wrapf(err, "invalid digest %q", vars["digest"]))
+	}
+
+	rdr, size, err := br.backend.ReadBlob(ctx, dgst)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	if size > maxBlobSize {
+		return errdefs.InvalidParameter(errors.Errorf("blob %s is %d bytes, larger than the %d bytes this endpoint serves", dgst, size, maxBlobSize))
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Docker-Content-Digest", dgst.String())
+	_, err = io.Copy(w, rdr)
+	return err
+}
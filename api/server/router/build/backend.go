@@ -5,6 +5,7 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/system"
 )
 
 // Backend abstracts an image builder whose only purpose is to build an image referenced by an imageID.
@@ -16,6 +17,22 @@ type Backend interface {
 	// Prune build cache
 	PruneCache(context.Context, types.BuildCachePruneOptions) (*types.BuildCachePruneReport, error)
 	Cancel(context.Context, string) error
+
+	// CacheMounts lists the RUN --mount=type=cache volumes held by the builder.
+	CacheMounts(context.Context) ([]*types.BuildCacheMount, error)
+
+	// PruneCacheMounts resets the cache mounts with the given cache IDs.
+	PruneCacheMounts(context.Context, []string) (*types.BuildCacheMountPruneReport, error)
+
+	// InspectBuilder describes the BuildKit builder and its worker.
+	InspectBuilder(context.Context) (*system.BuilderInfo, error)
+
+	// RemoveBuildHistory deletes the history record of a single build by
+	// its ref.
+	RemoveBuildHistory(context.Context, string) error
+
+	// RemoveCacheRecord deletes a single build-cache record by its ID.
+	RemoveCacheRecord(context.Context, string) (*types.BuildCachePruneReport, error)
 }
 
 type experimentalProvider interface {
@@ -34,6 +34,11 @@ func (r *buildRouter) initRoutes() {
 		router.NewPostRoute("/build", r.postBuild),
 		router.NewPostRoute("/build/prune", r.postPrune),
 		router.NewPostRoute("/build/cancel", r.postCancel),
+		router.NewGetRoute("/build/inspect", r.getInspect),
+		router.NewGetRoute("/build/cachemounts", r.getCacheMounts),
+		router.NewPostRoute("/build/cachemounts/prune", r.postCacheMountsPrune),
+		router.NewDeleteRoute("/build/cache/{id}", r.deleteCacheRecord),
+		router.NewDeleteRoute("/build/{ref}", r.deleteBuild),
 	}
 }
 
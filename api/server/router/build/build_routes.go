@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/containerd/containerd/log"
 	"github.com/docker/docker/api/server/httputils"
@@ -195,6 +196,17 @@ func (br *buildRouter) postPrune(ctx context.Context, w http.ResponseWriter, r *
 		KeepStorage: int64(ks),
 	}
 
+	version := httputils.VersionFromContext(ctx)
+	if versions.GreaterThanOrEqualTo(version, "1.44") {
+		if kdfv := r.FormValue("keep-duration"); kdfv != "" {
+			kd, err := time.ParseDuration(kdfv)
+			if err != nil {
+				return invalidParam{errors.Wrapf(err, "keep-duration expects a duration (e.g., '24h'), got %v", kdfv)}
+			}
+			opts.KeepDuration = kd
+		}
+	}
+
 	report, err := br.backend.PruneCache(ctx, opts)
 	if err != nil {
 		return err
@@ -202,6 +214,38 @@ func (br *buildRouter) postPrune(ctx context.Context, w http.ResponseWriter, r *
 	return httputils.WriteJSON(w, http.StatusOK, report)
 }
 
+func (br *buildRouter) getCacheMounts(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	mounts, err := br.backend.CacheMounts(ctx)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, mounts)
+}
+
+func (br *buildRouter) postCacheMountsPrune(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	ids := r.Form["id"]
+	if len(ids) == 0 {
+		return invalidParam{errors.New("no cache mount IDs provided")}
+	}
+
+	report, err := br.backend.PruneCacheMounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (br *buildRouter) getInspect(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	info, err := br.backend.InspectBuilder(ctx)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, info)
+}
+
 func (br *buildRouter) postCancel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -213,6 +257,32 @@ func (br *buildRouter) postCancel(ctx context.Context, w http.ResponseWriter, r
 	return br.backend.Cancel(ctx, id)
 }
 
+func (br *buildRouter) deleteBuild(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	ref := vars["ref"]
+	if ref == "" {
+		return invalidParam{errors.New("build ref not provided")}
+	}
+
+	if err := br.backend.RemoveBuildHistory(ctx, ref); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (br *buildRouter) deleteCacheRecord(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+	if id == "" {
+		return invalidParam{errors.New("build cache ID not provided")}
+	}
+
+	report, err := br.backend.RemoveCacheRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
 func (br *buildRouter) postBuild(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	var (
 		notVerboseBuffer = bytes.NewBuffer(nil)
@@ -18,6 +18,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/api/types/versions"
 	containerpkg "github.com/docker/docker/container"
 	"github.com/docker/docker/errdefs"
@@ -607,6 +608,24 @@ func (s *containerRouter) postContainersCreate(ctx context.Context, w http.Respo
 		}
 	}
 
+	var (
+		pullPolicy container.PullPolicy
+		authConfig *registry.AuthConfig
+	)
+	if versions.GreaterThanOrEqualTo(version, "1.44") {
+		switch p := container.PullPolicy(r.Form.Get("pull")); p {
+		case "", container.PullPolicyMissing, container.PullPolicyAlways, container.PullPolicyNever:
+			pullPolicy = p
+		default:
+			return errdefs.InvalidParameter(fmt.Errorf("invalid pull policy: %q: must be one of %q, %q or %q", p, container.PullPolicyMissing, container.PullPolicyAlways, container.PullPolicyNever))
+		}
+		if pullPolicy != "" {
+			// Credentials are only used when the pull policy may contact
+			// the registry.
+			authConfig, _ = registry.DecodeAuthConfig(r.Header.Get(registry.AuthHeader))
+		}
+	}
+
 	ccr, err := s.backend.ContainerCreate(ctx, types.ContainerCreateConfig{
 		Name:             name,
 		Config:           config,
@@ -614,6 +633,8 @@ func (s *containerRouter) postContainersCreate(ctx context.Context, w http.Respo
 		NetworkingConfig: networkingConfig,
 		AdjustCPUShares:  adjustCPUShares,
 		Platform:         platform,
+		Pull:             pullPolicy,
+		AuthConfig:       authConfig,
 	})
 	if err != nil {
 		return err
@@ -23,22 +23,38 @@ type Backend interface {
 
 type imageBackend interface {
 	ImageDelete(ctx context.Context, imageRef string, force, prune bool) ([]types.ImageDeleteResponseItem, error)
-	ImageHistory(ctx context.Context, imageName string) ([]*image.HistoryResponseItem, error)
+	ImageHistory(ctx context.Context, imageName string, platform *ocispec.Platform) ([]*image.HistoryResponseItem, error)
+	ImageTags(ctx context.Context, refOrID string) ([]string, error)
 	Images(ctx context.Context, opts types.ImageListOptions) ([]*types.ImageSummary, error)
 	GetImage(ctx context.Context, refOrID string, options image.GetImageOpts) (*dockerimage.Image, error)
 	TagImage(ctx context.Context, id dockerimage.ID, newRef reference.Named) error
-	ImagesPrune(ctx context.Context, pruneFilters filters.Args) (*types.ImagesPruneReport, error)
+	ImagesPrune(ctx context.Context, pruneFilters filters.Args, outStream io.Writer) (*types.ImagesPruneReport, error)
+	MountImage(ctx context.Context, refOrID string, platform *ocispec.Platform) (string, error)
+	UnmountImage(ctx context.Context, refOrID string) error
+	PinImage(ctx context.Context, refOrID string) error
+	UnpinImage(ctx context.Context, refOrID string) error
+	ConvertImage(ctx context.Context, refOrID string, targetRef reference.Named, options image.ConvertOptions) error
+	FlattenImage(ctx context.Context, refOrID string, targetRef reference.Named, outStream io.Writer) error
+	VerifyImages(ctx context.Context, options image.VerifyOptions) (*image.VerifyReport, error)
+	ExportReferences(ctx context.Context) (map[string]string, error)
+	ImportReferences(ctx context.Context, refs map[string]string) (*image.ReferencesRestoreReport, error)
+	UpdateImageLabels(ctx context.Context, refOrID string, update image.LabelsUpdate) (dockerimage.ID, error)
+	GenerateImageSBOM(ctx context.Context, refOrID string, options image.SBOMOptions) (*image.SBOMReport, error)
+	ImageReferrers(ctx context.Context, refOrID string) ([]ocispec.Descriptor, error)
+	ScanImage(ctx context.Context, refOrID string) (*image.ScanReport, error)
+	ImageScanStatus(ctx context.Context, refOrID string) (*image.ScanReport, error)
 }
 
 type importExportBackend interface {
-	LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, quiet bool) error
+	LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, options image.LoadOptions) error
 	ImportImage(ctx context.Context, ref reference.Named, platform *ocispec.Platform, msg string, layerReader io.Reader, changes []string) (dockerimage.ID, error)
-	ExportImage(ctx context.Context, names []string, outStream io.Writer) error
+	ExportImage(ctx context.Context, names []string, outStream io.Writer, options image.SaveOptions) error
 }
 
 type registryBackend interface {
-	PullImage(ctx context.Context, image, tag string, platform *ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
-	PushImage(ctx context.Context, ref reference.Named, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
+	PullImage(ctx context.Context, image, tag string, options image.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
+	PullImageBundle(ctx context.Context, refs []string, options image.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
+	PushImage(ctx context.Context, ref reference.Named, platforms []ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer, withNondistributable bool) error
 }
 
 type Searcher interface {
@@ -45,12 +45,29 @@ func (ir *imageRouter) initRoutes() {
 		router.NewGetRoute("/images/{name:.*}/get", ir.getImagesGet),
 		router.NewGetRoute("/images/{name:.*}/history", ir.getImagesHistory),
 		router.NewGetRoute("/images/{name:.*}/json", ir.getImagesByName),
+		router.NewGetRoute("/images/{name:.*}/tags", ir.getImagesTags),
+		router.NewGetRoute("/images/references", ir.getImagesReferences),
+		router.NewGetRoute("/images/{name:.*}/referrers", ir.getImagesReferrers),
+		router.NewGetRoute("/images/{name:.*}/scan", ir.getImagesScan),
 		// POST
 		router.NewPostRoute("/images/load", ir.postImagesLoad),
 		router.NewPostRoute("/images/create", ir.postImagesCreate),
+		router.NewPostRoute("/images/bundle", ir.postImagesBundle),
+		router.NewPostRoute("/images/pull", ir.postImagesPull),
 		router.NewPostRoute("/images/{name:.*}/push", ir.postImagesPush),
 		router.NewPostRoute("/images/{name:.*}/tag", ir.postImagesTag),
+		router.NewPostRoute("/images/{name:.*}/mount", ir.postImagesMount),
+		router.NewPostRoute("/images/{name:.*}/unmount", ir.postImagesUnmount),
+		router.NewPostRoute("/images/{name:.*}/pin", ir.postImagesPin),
+		router.NewPostRoute("/images/{name:.*}/unpin", ir.postImagesUnpin),
+		router.NewPostRoute("/images/{name:.*}/convert", ir.postImagesConvert),
+		router.NewPostRoute("/images/{name:.*}/flatten", ir.postImagesFlatten),
 		router.NewPostRoute("/images/prune", ir.postImagesPrune),
+		router.NewPostRoute("/images/verify", ir.postImagesVerify),
+		router.NewPostRoute("/images/references", ir.postImagesReferences),
+		router.NewPostRoute("/images/{name:.*}/labels", ir.postImagesLabels),
+		router.NewPostRoute("/images/{name:.*}/sbom", ir.postImagesSBOM),
+		router.NewPostRoute("/images/{name:.*}/scan", ir.postImagesScan),
 		// DELETE
 		router.NewDeleteRoute("/images/{name:.*}", ir.deleteImages),
 	}
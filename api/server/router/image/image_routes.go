@@ -1,15 +1,17 @@
 package image // import "github.com/docker/docker/api/server/router/image"
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/containerd/containerd/platforms"
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
@@ -22,10 +24,15 @@ import (
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/platforms"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/docker/docker/registry/resumable"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 // Creates an image from Pull or from Import
@@ -48,14 +55,29 @@ func (ir *imageRouter) postImagesCreate(ctx context.Context, w http.ResponseWrit
 	w.Header().Set("Content-Type", "application/json")
 
 	version := httputils.VersionFromContext(ctx)
+	var pullOptions opts.PullOptions
 	if versions.GreaterThanOrEqualTo(version, "1.32") {
-		if p := r.FormValue("platform"); p != "" {
-			sp, err := platforms.Parse(p)
-			if err != nil {
-				return err
+		for _, v := range r.Form["platform"] {
+			// Each platform value may itself be a comma-separated list.
+			for _, p := range strings.Split(v, ",") {
+				if p == "" {
+					continue
+				}
+				sp, err := platforms.Parse(p)
+				if err != nil {
+					return errdefs.InvalidParameter(err)
+				}
+				pullOptions.Platforms = append(pullOptions.Platforms, sp)
 			}
-			platform = &sp
 		}
+		if len(pullOptions.Platforms) > 0 {
+			platform = &pullOptions.Platforms[0]
+		}
+	}
+	if versions.GreaterThanOrEqualTo(version, "1.44") {
+		pullOptions.AllPlatforms = httputils.BoolValue(r, "allPlatforms")
+		pullOptions.ContentOnly = httputils.BoolValue(r, "contentOnly")
+		pullOptions.Snapshotter = r.Form.Get("snapshotter")
 	}
 
 	if img != "" { // pull
@@ -66,11 +88,21 @@ func (ir *imageRouter) postImagesCreate(ctx context.Context, w http.ResponseWrit
 			}
 		}
 
+		var pullOut io.Writer = output
+		if versions.GreaterThanOrEqualTo(version, "1.44") && httputils.BoolValue(r, "quiet") {
+			// Suppress per-layer progress; only status, error and aux
+			// messages are passed through to the client.
+			pullOut = &quietProgressWriter{w: output}
+		}
+
 		// For a pull it is not an error if no auth was given. Ignore invalid
 		// AuthConfig to increase compatibility with the existing API.
 		authConfig, _ := registry.DecodeAuthConfig(r.Header.Get(registry.AuthHeader))
-		progressErr = ir.backend.PullImage(ctx, img, tag, platform, metaHeaders, authConfig, output)
+		progressErr = ir.backend.PullImage(ctx, img, tag, pullOptions, metaHeaders, authConfig, pullOut)
 	} else { // import
+		if len(pullOptions.Platforms) > 1 || pullOptions.AllPlatforms {
+			return errdefs.InvalidParameter(errors.New("multiple platforms are only supported when pulling an image"))
+		}
 		src := r.Form.Get("fromSrc")
 
 		tagRef, err := httputils.RepoTagReference(repo, tag)
@@ -82,6 +114,17 @@ func (ir *imageRouter) postImagesCreate(ctx context.Context, w http.ResponseWrit
 			comment = "Imported from " + src
 		}
 
+		var expectedDigest digest.Digest
+		if versions.GreaterThanOrEqualTo(version, "1.44") {
+			if checksum := r.Form.Get("checksum"); checksum != "" {
+				var err error
+				expectedDigest, err = digest.Parse(checksum)
+				if err != nil {
+					return errdefs.InvalidParameter(errors.Wrapf(err, "invalid checksum %q", checksum))
+				}
+			}
+		}
+
 		var layerReader io.ReadCloser
 		defer r.Body.Close()
 		if src == "-" {
@@ -99,11 +142,27 @@ func (ir *imageRouter) postImagesCreate(ctx context.Context, w http.ResponseWrit
 			if err != nil {
 				return err
 			}
+			body := resp.Body
+			if resp.ContentLength > 0 {
+				// Pick up where we left off with a ranged request when the
+				// connection to the remote server drops mid-download.
+				// Resuming needs the total size up front, so responses
+				// without a Content-Length are read in a single attempt.
+				req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+				if err != nil {
+					resp.Body.Close()
+					return errdefs.InvalidParameter(err)
+				}
+				body = resumable.NewRequestReaderWithInitialResponse(http.DefaultClient, req, 5, resp.ContentLength, resp)
+			}
 			output.Write(streamformatter.FormatStatus("", "Downloading from %s", u))
 			progressOutput := streamformatter.NewJSONProgressOutput(output, true)
-			layerReader = progress.NewProgressReader(resp.Body, progressOutput, resp.ContentLength, "", "Importing")
+			layerReader = progress.NewProgressReader(body, progressOutput, resp.ContentLength, "", "Importing")
 			defer layerReader.Close()
 		}
+		if expectedDigest != "" {
+			layerReader = newVerifiedReadCloser(layerReader, expectedDigest)
+		}
 
 		var id image.ID
 		id, progressErr = ir.backend.ImportImage(ctx, tagRef, platform, comment, layerReader, r.Form["changes"])
@@ -122,6 +181,209 @@ func (ir *imageRouter) postImagesCreate(ctx context.Context, w http.ResponseWrit
 	return nil
 }
 
+// postImagesBundle pulls every image referenced by the posted document, which
+// may be an OCI image index whose manifest descriptors carry the standard
+// ref.name annotation, or a plain `{"references": [...]}` list. All pulls
+// share one progress stream.
+func (ir *imageRouter) postImagesBundle(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	// The body may be posted with the index's own media type, so don't
+	// require an application/json content type here.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errdefs.InvalidParameter(errors.Wrap(err, "failed to read request body"))
+	}
+	var bundle struct {
+		Manifests  []ocispec.Descriptor `json:"manifests"`
+		References []string             `json:"references"`
+	}
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return errdefs.InvalidParameter(errors.Wrap(err, "invalid bundle document"))
+	}
+
+	refs := bundle.References
+	for _, m := range bundle.Manifests {
+		name := m.Annotations[ocispec.AnnotationRefName]
+		if name == "" {
+			return errdefs.InvalidParameter(errors.Errorf("index entry %s has no %s annotation", m.Digest, ocispec.AnnotationRefName))
+		}
+		refs = append(refs, name)
+	}
+	if len(refs) == 0 {
+		return errdefs.InvalidParameter(errors.New("bundle document contains no image references"))
+	}
+
+	metaHeaders := map[string][]string{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(k, "X-Meta-") {
+			metaHeaders[k] = v
+		}
+	}
+	// For a pull it is not an error if no auth was given. Ignore invalid
+	// AuthConfig to increase compatibility with the existing API.
+	authConfig, _ := registry.DecodeAuthConfig(r.Header.Get(registry.AuthHeader))
+
+	var pullOptions opts.PullOptions
+	for _, v := range r.Form["platform"] {
+		for _, p := range strings.Split(v, ",") {
+			if p == "" {
+				continue
+			}
+			sp, err := platforms.Parse(p)
+			if err != nil {
+				return errdefs.InvalidParameter(err)
+			}
+			pullOptions.Platforms = append(pullOptions.Platforms, sp)
+		}
+	}
+
+	output := ioutils.NewWriteFlusher(w)
+	defer output.Close()
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := ir.backend.PullImageBundle(ctx, refs, pullOptions, metaHeaders, authConfig, output); err != nil {
+		if !output.Flushed() {
+			return err
+		}
+		_, _ = output.Write(streamformatter.FormatError(err))
+	}
+	return nil
+}
+
+// defaultBatchPullParallelism is how many images of a batch pull request are
+// pulled at the same time when the request doesn't set its own limit.
+const defaultBatchPullParallelism = 3
+
+// batchPullStream serializes the JSON progress streams of concurrent pulls
+// into a single response, tagging every message with the image it belongs to
+// so clients can demultiplex them.
+type batchPullStream struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *batchPullStream) copyTagged(image string, r io.Reader) {
+	dec := json.NewDecoder(r)
+	for {
+		var msg map[string]interface{}
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		msg["image"] = image
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		s.mu.Lock()
+		_, _ = s.w.Write(append(data, '\r', '\n'))
+		s.mu.Unlock()
+	}
+}
+
+// postImagesPull pulls a batch of images in one request. The pulls run
+// concurrently, bounded by the request's parallelism, and share one progress
+// stream. Unlike the bundle endpoint, every image can select its own
+// platform and registry credentials.
+func (ir *imageRouter) postImagesPull(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	var req opts.BatchPullRequest
+	if err := httputils.ReadJSON(r, &req); err != nil {
+		return err
+	}
+	if len(req.Images) == 0 {
+		return errdefs.InvalidParameter(errors.New("no images provided"))
+	}
+	parallelism := req.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultBatchPullParallelism
+	}
+
+	metaHeaders := map[string][]string{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(k, "X-Meta-") {
+			metaHeaders[k] = v
+		}
+	}
+	// For a pull it is not an error if no auth was given. Ignore invalid
+	// AuthConfig to increase compatibility with the existing API.
+	headerAuth, _ := registry.DecodeAuthConfig(r.Header.Get(registry.AuthHeader))
+
+	type pullItem struct {
+		ref        string
+		image, tag string
+		options    opts.PullOptions
+		auth       *registry.AuthConfig
+	}
+	items := make([]pullItem, 0, len(req.Images))
+	for _, img := range req.Images {
+		ref, err := reference.ParseNormalizedNamed(img.Reference)
+		if err != nil {
+			return errdefs.InvalidParameter(errors.Wrapf(err, "invalid reference %q", img.Reference))
+		}
+		item := pullItem{
+			ref:   reference.FamiliarString(ref),
+			image: reference.FamiliarName(ref),
+			auth:  headerAuth,
+		}
+		if tagged, ok := ref.(reference.Tagged); ok {
+			item.tag = tagged.Tag()
+		} else if digested, ok := ref.(reference.Digested); ok {
+			item.tag = digested.Digest().String()
+		}
+		if img.Platform != "" {
+			p, err := platforms.Parse(img.Platform)
+			if err != nil {
+				return errdefs.InvalidParameter(err)
+			}
+			item.options.Platforms = []ocispec.Platform{p}
+		}
+		if img.Auth != nil {
+			item.auth = img.Auth
+		}
+		items = append(items, item)
+	}
+
+	output := ioutils.NewWriteFlusher(w)
+	defer output.Close()
+	w.Header().Set("Content-Type", "application/json")
+
+	stream := &batchPullStream{w: output}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(parallelism)
+	for _, item := range items {
+		item := item
+		eg.Go(func() error {
+			pr, pw := io.Pipe()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				stream.copyTagged(item.ref, pr)
+			}()
+			err := ir.backend.PullImage(ctx, item.image, item.tag, item.options, metaHeaders, item.auth, pw)
+			_ = pw.Close()
+			<-done
+			if err != nil {
+				return errors.Wrapf(err, "failed to pull %s", item.ref)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		if !output.Flushed() {
+			return err
+		}
+		_, _ = output.Write(streamformatter.FormatError(err))
+	}
+	return nil
+}
+
 func (ir *imageRouter) postImagesPush(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	metaHeaders := map[string][]string{}
 	for k, v := range r.Header {
@@ -172,7 +434,26 @@ func (ir *imageRouter) postImagesPush(ctx context.Context, w http.ResponseWriter
 		ref = r
 	}
 
-	if err := ir.backend.PushImage(ctx, ref, metaHeaders, authConfig, output); err != nil {
+	var withNondistributable bool
+	var pushPlatforms []ocispec.Platform
+	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.44") {
+		withNondistributable = httputils.BoolValue(r, "nondistributable-artifacts")
+		for _, v := range r.Form["platform"] {
+			// Each platform value may itself be a comma-separated list.
+			for _, p := range strings.Split(v, ",") {
+				if p == "" {
+					continue
+				}
+				sp, err := platforms.Parse(p)
+				if err != nil {
+					return errdefs.InvalidParameter(err)
+				}
+				pushPlatforms = append(pushPlatforms, sp)
+			}
+		}
+	}
+
+	if err := ir.backend.PushImage(ctx, ref, pushPlatforms, metaHeaders, authConfig, output, withNondistributable); err != nil {
 		if !output.Flushed() {
 			return err
 		}
@@ -197,7 +478,18 @@ func (ir *imageRouter) getImagesGet(ctx context.Context, w http.ResponseWriter,
 		names = r.Form["names"]
 	}
 
-	if err := ir.backend.ExportImage(ctx, names, output); err != nil {
+	var saveOpts opts.SaveOptions
+	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.44") {
+		saveOpts.NondistributableArtifacts = httputils.BoolValue(r, "nondistributable-artifacts")
+		switch format := r.Form.Get("format"); format {
+		case "", opts.SaveFormatDocker, opts.SaveFormatOCI:
+			saveOpts.Format = format
+		default:
+			return errdefs.InvalidParameter(errors.Errorf("invalid format %q: must be %q or %q", format, opts.SaveFormatDocker, opts.SaveFormatOCI))
+		}
+	}
+
+	if err := ir.backend.ExportImage(ctx, names, output, saveOpts); err != nil {
 		if !output.Flushed() {
 			return err
 		}
@@ -210,13 +502,31 @@ func (ir *imageRouter) postImagesLoad(ctx context.Context, w http.ResponseWriter
 	if err := httputils.ParseForm(r); err != nil {
 		return err
 	}
-	quiet := httputils.BoolValueOrDefault(r, "quiet", true)
+	loadOpts := opts.LoadOptions{
+		Quiet: httputils.BoolValueOrDefault(r, "quiet", true),
+	}
+
+	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.44") {
+		for _, v := range r.Form["platform"] {
+			// Each platform value may itself be a comma-separated list.
+			for _, p := range strings.Split(v, ",") {
+				if p == "" {
+					continue
+				}
+				sp, err := platforms.Parse(p)
+				if err != nil {
+					return errdefs.InvalidParameter(err)
+				}
+				loadOpts.Platforms = append(loadOpts.Platforms, sp)
+			}
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 
 	output := ioutils.NewWriteFlusher(w)
 	defer output.Close()
-	if err := ir.backend.LoadImage(ctx, r.Body, output, quiet); err != nil {
+	if err := ir.backend.LoadImage(ctx, r.Body, output, loadOpts); err != nil {
 		_, _ = output.Write(streamformatter.FormatError(err))
 	}
 	return nil
@@ -253,11 +563,40 @@ func (ir *imageRouter) deleteImages(ctx context.Context, w http.ResponseWriter,
 }
 
 func (ir *imageRouter) getImagesByName(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	img, err := ir.backend.GetImage(ctx, vars["name"], opts.GetImageOpts{Details: true})
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	var platform *ocispec.Platform
+	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.44") {
+		if p := r.Form.Get("platform"); p != "" {
+			sp, err := platforms.Parse(p)
+			if err != nil {
+				return errdefs.InvalidParameter(err)
+			}
+			platform = &sp
+		}
+	}
+
+	img, err := ir.backend.GetImage(ctx, vars["name"], opts.GetImageOpts{Details: true, Platform: platform})
 	if err != nil {
 		return err
 	}
 
+	// The containerd image store falls back to the closest locally present
+	// platform when the requested one is not available, so a fallback has to
+	// be turned into an error here for the parameter to be meaningful.
+	if platform != nil {
+		imgPlat := platforms.Normalize(ocispec.Platform{
+			OS:           img.OS,
+			Architecture: img.Architecture,
+			Variant:      img.Variant,
+		})
+		if !platforms.Only(*platform).Match(imgPlat) {
+			return errdefs.NotFound(errors.Errorf("image %s was found but does not provide platform %s", vars["name"], platforms.Format(*platform)))
+		}
+	}
+
 	imageInspect, err := ir.toImageInspect(img)
 	if err != nil {
 		return err
@@ -324,10 +663,22 @@ func (ir *imageRouter) toImageInspect(img *image.Image) (*types.ImageInspect, er
 		Metadata: types.ImageMetadata{
 			LastTagTime: img.Details.LastUpdated,
 		},
+		ArtifactType:        img.Details.ArtifactType,
+		DistributionSources: img.Details.DistributionSources,
+		LastPulled:          img.Details.LastPulled,
+		LastPushed:          img.Details.LastPushed,
+		Snapshotters:        img.Details.Snapshotters,
+		Usage:               img.Details.Usage,
+		Platforms:           img.Details.Platforms,
+		Pinned:              img.Details.Pinned,
 	}, nil
 }
 
 func rootFSToAPIType(rootfs *image.RootFS) types.RootFS {
+	if rootfs == nil {
+		// OCI artifacts have no rootfs.
+		return types.RootFS{}
+	}
 	var layers []string
 	for _, l := range rootfs.DiffIDs {
 		layers = append(layers, l.String())
@@ -363,10 +714,31 @@ func (ir *imageRouter) getImagesJSON(ctx context.Context, w http.ResponseWriter,
 		sharedSize = httputils.BoolValue(r, "shared-size")
 	}
 
+	var (
+		limit  int
+		cursor string
+	)
+	if versions.GreaterThanOrEqualTo(version, "1.44") {
+		if v := r.Form.Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 0 {
+				return errdefs.InvalidParameter(errors.Errorf("invalid limit: %s", v))
+			}
+		}
+		cursor = r.Form.Get("cursor")
+		if sharedSize && (limit > 0 || cursor != "") {
+			// Shared sizes are relative to the full set of images and
+			// would be wrong when computed over a single page.
+			return errdefs.InvalidParameter(errors.New("shared-size cannot be combined with limit or cursor"))
+		}
+	}
+
 	images, err := ir.backend.Images(ctx, types.ImageListOptions{
 		All:        httputils.BoolValue(r, "all"),
 		Filters:    imageFilters,
 		SharedSize: sharedSize,
+		Limit:      limit,
+		Cursor:     cursor,
 	})
 	if err != nil {
 		return err
@@ -397,7 +769,22 @@ func (ir *imageRouter) getImagesJSON(ctx context.Context, w http.ResponseWriter,
 }
 
 func (ir *imageRouter) getImagesHistory(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	history, err := ir.backend.ImageHistory(ctx, vars["name"])
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	var platform *ocispec.Platform
+	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.44") {
+		if p := r.Form.Get("platform"); p != "" {
+			sp, err := platforms.Parse(p)
+			if err != nil {
+				return errdefs.InvalidParameter(err)
+			}
+			platform = &sp
+		}
+	}
+
+	history, err := ir.backend.ImageHistory(ctx, vars["name"], platform)
 	if err != nil {
 		return err
 	}
@@ -405,6 +792,15 @@ func (ir *imageRouter) getImagesHistory(ctx context.Context, w http.ResponseWrit
 	return httputils.WriteJSON(w, http.StatusOK, history)
 }
 
+func (ir *imageRouter) getImagesTags(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	tags, err := ir.backend.ImageTags(ctx, vars["name"])
+	if err != nil {
+		return err
+	}
+
+	return httputils.WriteJSON(w, http.StatusOK, tags)
+}
+
 func (ir *imageRouter) postImagesTag(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := httputils.ParseForm(r); err != nil {
 		return err
@@ -427,6 +823,220 @@ func (ir *imageRouter) postImagesTag(ctx context.Context, w http.ResponseWriter,
 	return nil
 }
 
+func (ir *imageRouter) postImagesMount(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	var platform *ocispec.Platform
+	if p := r.Form.Get("platform"); p != "" {
+		sp, err := platforms.Parse(p)
+		if err != nil {
+			return errdefs.InvalidParameter(err)
+		}
+		platform = &sp
+	}
+
+	path, err := ir.backend.MountImage(ctx, vars["name"], platform)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, &opts.MountResponse{Path: path})
+}
+
+func (ir *imageRouter) postImagesUnmount(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	if err := ir.backend.UnmountImage(ctx, vars["name"]); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (ir *imageRouter) postImagesPin(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	if err := ir.backend.PinImage(ctx, vars["name"]); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (ir *imageRouter) postImagesUnpin(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	if err := ir.backend.UnpinImage(ctx, vars["name"]); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (ir *imageRouter) postImagesConvert(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	targetRef, err := httputils.RepoTagReference(r.Form.Get("repo"), r.Form.Get("tag"))
+	if targetRef == nil || err != nil {
+		return errdefs.InvalidParameter(err)
+	}
+
+	convertOptions := opts.ConvertOptions{
+		Compression: r.Form.Get("compression"),
+		EStargz:     httputils.BoolValue(r, "estargz"),
+	}
+	for _, v := range r.Form["platform"] {
+		for _, p := range strings.Split(v, ",") {
+			if p == "" {
+				continue
+			}
+			sp, err := platforms.Parse(p)
+			if err != nil {
+				return errdefs.InvalidParameter(err)
+			}
+			convertOptions.Platforms = append(convertOptions.Platforms, sp)
+		}
+	}
+
+	if err := ir.backend.ConvertImage(ctx, vars["name"], targetRef, convertOptions); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (ir *imageRouter) postImagesFlatten(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	targetRef, err := httputils.RepoTagReference(r.Form.Get("repo"), r.Form.Get("tag"))
+	if targetRef == nil || err != nil {
+		return errdefs.InvalidParameter(err)
+	}
+
+	output := ioutils.NewWriteFlusher(w)
+	defer output.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := ir.backend.FlattenImage(ctx, vars["name"], targetRef, output); err != nil {
+		if !output.Flushed() {
+			return err
+		}
+		_, _ = output.Write(streamformatter.FormatError(err))
+	}
+	return nil
+}
+
+func (ir *imageRouter) postImagesVerify(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	report, err := ir.backend.VerifyImages(ctx, opts.VerifyOptions{
+		Repair: httputils.BoolValue(r, "repair"),
+		Delete: httputils.BoolValue(r, "delete"),
+	})
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (ir *imageRouter) getImagesReferences(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	refs, err := ir.backend.ExportReferences(ctx)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, opts.ReferencesDump{References: refs})
+}
+
+func (ir *imageRouter) postImagesReferences(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var dump opts.ReferencesDump
+	if err := httputils.ReadJSON(r, &dump); err != nil {
+		return err
+	}
+
+	report, err := ir.backend.ImportReferences(ctx, dump.References)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (ir *imageRouter) postImagesLabels(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var update opts.LabelsUpdate
+	if err := httputils.ReadJSON(r, &update); err != nil {
+		return err
+	}
+
+	id, err := ir.backend.UpdateImageLabels(ctx, vars["name"], update)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, types.IDResponse{ID: id.String()})
+}
+
+func (ir *imageRouter) postImagesSBOM(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	var sbomOpts opts.SBOMOptions
+	sbomOpts.Format = r.Form.Get("format")
+	if p := r.Form.Get("platform"); p != "" {
+		sp, err := platforms.Parse(p)
+		if err != nil {
+			return errdefs.InvalidParameter(err)
+		}
+		sbomOpts.Platform = &sp
+	}
+
+	report, err := ir.backend.GenerateImageSBOM(ctx, vars["name"], sbomOpts)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (ir *imageRouter) postImagesScan(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	report, err := ir.backend.ScanImage(ctx, vars["name"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (ir *imageRouter) getImagesScan(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	report, err := ir.backend.ImageScanStatus(ctx, vars["name"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (ir *imageRouter) getImagesReferrers(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	referrers, err := ir.backend.ImageReferrers(ctx, vars["name"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, referrers)
+}
+
 func (ir *imageRouter) getImagesSearch(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := httputils.ParseForm(r); err != nil {
 		return err
@@ -474,9 +1084,88 @@ func (ir *imageRouter) postImagesPrune(ctx context.Context, w http.ResponseWrite
 		return err
 	}
 
-	pruneReport, err := ir.backend.ImagesPrune(ctx, pruneFilters)
+	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.44") && httputils.BoolValue(r, "stream") {
+		output := ioutils.NewWriteFlusher(w)
+		defer output.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		pruneReport, err := ir.backend.ImagesPrune(ctx, pruneFilters, output)
+		if err != nil {
+			if !output.Flushed() {
+				return err
+			}
+			_, _ = output.Write(streamformatter.FormatError(err))
+			return nil
+		}
+		// End the stream with the same report the non-streaming response
+		// returns, as an aux record.
+		return (&streamformatter.AuxFormatter{Writer: output}).Emit("", pruneReport)
+	}
+
+	pruneReport, err := ir.backend.ImagesPrune(ctx, pruneFilters, nil)
 	if err != nil {
 		return err
 	}
 	return httputils.WriteJSON(w, http.StatusOK, pruneReport)
 }
+
+// quietProgressWriter filters a JSON progress stream, dropping per-layer
+// progress messages while passing status, error and aux messages through
+// unchanged. It is used to implement quiet pulls server-side.
+type quietProgressWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (q *quietProgressWriter) Write(p []byte) (int, error) {
+	q.buf.Write(p)
+	for {
+		line, err := q.buf.ReadBytes('\n')
+		if err != nil {
+			// Incomplete message, keep it buffered until more data arrives.
+			q.buf.Write(line)
+			return len(p), nil
+		}
+
+		var msg jsonmessage.JSONMessage
+		if err := json.Unmarshal(line, &msg); err == nil {
+			if msg.ID != "" || msg.Progress != nil || msg.ProgressMessage != "" {
+				continue
+			}
+		}
+		if _, err := q.w.Write(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// verifiedReadCloser checks the bytes read from the underlying reader
+// against an expected digest. When the reader is exhausted and the digests
+// don't match, the final read returns an error instead of io.EOF, so
+// consumers fail before committing the data they read.
+type verifiedReadCloser struct {
+	io.ReadCloser
+	expected digest.Digest
+	digester digest.Digester
+}
+
+func newVerifiedReadCloser(rc io.ReadCloser, expected digest.Digest) io.ReadCloser {
+	return &verifiedReadCloser{
+		ReadCloser: rc,
+		expected:   expected,
+		digester:   expected.Algorithm().Digester(),
+	}
+}
+
+func (v *verifiedReadCloser) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	if n > 0 {
+		v.digester.Hash().Write(p[:n])
+	}
+	if err == io.EOF {
+		if actual := v.digester.Digest(); actual != v.expected {
+			return n, errdefs.InvalidParameter(errors.Errorf("checksum mismatch: expected %s, got %s", v.expected, actual))
+		}
+	}
+	return n, err
+}
@@ -60,7 +60,7 @@ func (s *systemRouter) swarmStatus() string {
 func (s *systemRouter) getInfo(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	version := httputils.VersionFromContext(ctx)
 	info, _, _ := s.collectSystemInfo.Do(ctx, version, func(ctx context.Context) (*system.Info, error) {
-		info := s.backend.SystemInfo()
+		info := s.backend.SystemInfo(ctx)
 
 		if s.cluster != nil {
 			info.Swarm = s.cluster.Info()
@@ -91,6 +91,18 @@ func (s *systemRouter) getInfo(ctx context.Context, w http.ResponseWriter, r *ht
 		if versions.GreaterThanOrEqualTo(version, "1.42") {
 			info.KernelMemory = false
 		}
+		if versions.LessThan(version, "1.44") {
+			info.Capabilities = nil
+			info.PreloadImages = nil
+		}
+		if s.builder != nil && versions.GreaterThanOrEqualTo(version, "1.44") {
+			builderInfo, err := s.builder.Inspect(ctx)
+			if err != nil {
+				info.Warnings = append(info.Warnings, "failed to inspect the builder: "+err.Error())
+			} else {
+				info.Builder = builderInfo
+			}
+		}
 		return info, nil
 	})
 	return httputils.WriteJSON(w, http.StatusOK, info)
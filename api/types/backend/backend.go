@@ -121,4 +121,9 @@ type CommitConfig struct {
 	ContainerMountLabel string
 	ContainerOS         string
 	ParentImageID       string
+	// SnapshotCaptured, when set, is called once the container's filesystem
+	// has been fully read. A caller that paused the container so the commit
+	// sees a consistent filesystem can use it to resume the container before
+	// the rest of the commit completes.
+	SnapshotCaptured func()
 }
@@ -247,6 +247,15 @@ type ImageListOptions struct {
 
 	// ContainerCount indicates whether container count should be computed.
 	ContainerCount bool
+
+	// Limit caps the number of images listed; zero means no limit. It
+	// cannot be combined with SharedSize.
+	Limit int
+
+	// Cursor resumes a paginated listing after the image with the given ID,
+	// as returned by the previous page. Pages are stable as long as the
+	// image store doesn't change between requests.
+	Cursor string
 }
 
 // ImageLoadResponse returns information to the client about a load process.
@@ -3,6 +3,7 @@ package types // import "github.com/docker/docker/api/types"
 import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -18,6 +19,15 @@ type ContainerCreateConfig struct {
 	NetworkingConfig *network.NetworkingConfig
 	Platform         *ocispec.Platform
 	AdjustCPUShares  bool
+
+	// Pull is the image pull policy applied before creating the container.
+	// An empty policy preserves the historic behavior: the image is looked
+	// up locally and never pulled.
+	Pull container.PullPolicy
+
+	// AuthConfig holds registry credentials for when Pull requires
+	// contacting the registry.
+	AuthConfig *registry.AuthConfig
 }
 
 // ContainerRmConfig holds arguments for the container remove
@@ -0,0 +1,21 @@
+package container // import "github.com/docker/docker/api/types/container"
+
+// PullPolicy describes when the image for a container should be pulled as
+// part of creating the container, as requested through the "pull" query
+// parameter of POST /containers/create. An empty policy preserves the
+// historic behavior: the image is looked up locally and never pulled.
+type PullPolicy string
+
+const (
+	// PullPolicyMissing pulls the image when the requested platform is not
+	// present in the local image store.
+	PullPolicyMissing PullPolicy = "missing"
+
+	// PullPolicyAlways re-resolves the image reference at the registry and
+	// pulls it when the locally stored image is out of date.
+	PullPolicyAlways PullPolicy = "always"
+
+	// PullPolicyNever never pulls the image; creating the container fails
+	// when the requested platform is not available locally.
+	PullPolicyNever PullPolicy = "never"
+)
@@ -0,0 +1,26 @@
+package image
+
+// Label update modes, selecting how labels are attached to an image by
+// POST "/images/{name}/labels".
+const (
+	// LabelsModeConfig derives a new image config with the labels added,
+	// producing a new image ID. References to the image are updated to
+	// point at the derived image.
+	LabelsModeConfig = "config"
+	// LabelsModeRecord attaches the labels to the image record only,
+	// leaving the image content and ID unchanged. Record labels are local
+	// to the daemon and are not pushed with the image.
+	LabelsModeRecord = "record"
+)
+
+// LabelsUpdate holds labels to add to an existing image for POST
+// "/images/{name}/labels".
+type LabelsUpdate struct {
+	// Labels are added to the image, replacing existing labels with the
+	// same key.
+	Labels map[string]string
+
+	// Mode selects how the labels are attached; one of LabelsModeConfig
+	// (the default) or LabelsModeRecord.
+	Mode string
+}
@@ -0,0 +1,10 @@
+package image
+
+// MountResponse contains the response of the API endpoint that mounts an
+// image on the host:
+// POST "/images/{name}/mount"
+type MountResponse struct {
+	// Path is the host path where the image's filesystem is mounted
+	// read-only.
+	Path string
+}
@@ -1,9 +1,177 @@
 package image
 
-import ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+import (
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
 
 // GetImageOpts holds parameters to inspect an image.
 type GetImageOpts struct {
 	Platform *ocispec.Platform
 	Details  bool
 }
+
+// Compression values accepted by the image convert endpoint.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// ConvertOptions holds parameters to convert an image.
+type ConvertOptions struct {
+	// Compression is the target layer compression, either CompressionGzip
+	// or CompressionZstd. It may be empty when EStargz is set.
+	Compression string
+
+	// EStargz converts the layers to the eStargz format. The resulting
+	// layers are gzip-compressed regardless of Compression.
+	EStargz bool
+
+	// Platforms restricts the conversion to the listed platforms. An empty
+	// list converts every platform present in the source image.
+	Platforms []ocispec.Platform
+}
+
+// Formats accepted by the image export (save) endpoints.
+const (
+	// SaveFormatDocker is the historic Docker archive format: an OCI layout
+	// with an additional Docker-style manifest.json file.
+	SaveFormatDocker = "docker"
+	// SaveFormatOCI is a plain OCI layout. Every exported reference is
+	// recorded in the top-level index with ref.name annotations and no
+	// manifest.json is written.
+	SaveFormatOCI = "oci"
+)
+
+// SaveOptions holds parameters to export images to a tar archive.
+type SaveOptions struct {
+	// NondistributableArtifacts includes non-distributable (foreign) layers
+	// in the archive.
+	NondistributableArtifacts bool
+
+	// Format selects the archive layout, either SaveFormatDocker or
+	// SaveFormatOCI. An empty format defaults to SaveFormatDocker. Blobs
+	// shared between the exported images are stored once in the archive in
+	// either format. SaveFormatOCI is only supported with the containerd
+	// image store.
+	Format string
+}
+
+// LoadOptions holds parameters to load images from a tar archive.
+type LoadOptions struct {
+	// Quiet suppresses per-image progress messages.
+	Quiet bool
+
+	// Platforms restricts the load to the listed platforms. An empty list
+	// loads everything present in the archive. Loaded images keep the digest
+	// they have in the archive's index; only the blobs of the selected
+	// platforms are imported. Only supported with the containerd image
+	// store.
+	Platforms []ocispec.Platform
+}
+
+// PullOptions holds parameters to pull an image.
+type PullOptions struct {
+	// Platforms is the list of platforms to pull. An empty list pulls the
+	// host's default platform.
+	Platforms []ocispec.Platform
+
+	// AllPlatforms pulls every platform available in the remote index,
+	// regardless of Platforms.
+	AllPlatforms bool
+
+	// ContentOnly pulls the image content without unpacking it into a
+	// snapshot, which is sufficient for images that will only be re-pushed
+	// or saved. The image is unpacked on demand when a container is
+	// created from it.
+	ContentOnly bool
+
+	// Snapshotter selects the snapshotter the image is unpacked into,
+	// overriding the daemon's default and any per-registry policy. Only
+	// supported with the containerd image store.
+	Snapshotter string
+
+	// Internal marks the pulled image as an internal helper image (a
+	// buildkit frontend, an emulation helper, ...). Internal images are
+	// hidden from image listings unless explicitly requested with the
+	// "internal" filter. Only supported with the containerd image store.
+	Internal bool
+}
+
+// SBOMFormatSPDXJSON is the SBOM document format produced by the embedded
+// scanner: an SPDX 2.3 document in JSON encoding.
+const SBOMFormatSPDXJSON = "spdx-json"
+
+// SBOMOptions holds parameters to generate an SBOM for an image.
+type SBOMOptions struct {
+	// Format selects the SBOM document format. An empty format defaults to
+	// SBOMFormatSPDXJSON, the only format produced by the embedded scanner.
+	Format string
+
+	// Platform selects the platform whose filesystem is scanned. A nil
+	// platform scans the host's default platform.
+	Platform *ocispec.Platform
+}
+
+// SBOMReport describes the SBOM artifact generated for an image.
+type SBOMReport struct {
+	// Digest is the digest of the artifact manifest that holds the SBOM
+	// document. The manifest names the scanned image as its subject and is
+	// listed by the referrers endpoint.
+	Digest string
+
+	// Format is the format of the stored document.
+	Format string
+
+	// Size is the size in bytes of the SBOM document blob.
+	Size int64
+
+	// Files is the number of files inventoried by the scan.
+	Files int
+}
+
+// ScanReport describes a vulnerability scan of an image.
+type ScanReport struct {
+	// Status is the outcome reported by the scanner plugin, for example
+	// "clean" or "vulnerable".
+	Status string
+
+	// Summary is a short human-readable description of the outcome.
+	Summary string `json:",omitempty"`
+
+	// ScannedAt is when the scan finished.
+	ScannedAt time.Time
+
+	// ResultsDigest is the digest of the artifact manifest holding the
+	// full scan report, when the scanner produced one. The artifact is
+	// listed by the referrers endpoint.
+	ResultsDigest string `json:",omitempty"`
+}
+
+// BatchPullImage describes one image of a batch pull request.
+type BatchPullImage struct {
+	// Reference is the image reference to pull.
+	Reference string
+
+	// Platform selects the platform to pull, in os[/arch[/variant]]
+	// format. An empty platform pulls the host's default platform.
+	Platform string `json:",omitempty"`
+
+	// Auth are the registry credentials to pull this image with. When nil,
+	// the credentials of the X-Registry-Auth request header are used.
+	Auth *registry.AuthConfig `json:",omitempty"`
+}
+
+// BatchPullRequest holds parameters to pull several images in one request.
+type BatchPullRequest struct {
+	// Images lists the images to pull.
+	Images []BatchPullImage
+
+	// Parallelism caps how many of the images are pulled at the same time.
+	// Zero applies the daemon's default. Layer downloads additionally stay
+	// bounded by the daemon's max-concurrent-downloads setting, which is
+	// shared between all pulls of the request.
+	Parallelism int `json:",omitempty"`
+}
@@ -0,0 +1,23 @@
+package image
+
+// ReferencesDump is the name-to-digest mapping of the image store, as
+// returned by the references export endpoint and accepted by the import
+// endpoint. It plays the same role as repositories.json does for the legacy
+// image store: tags can be backed up separately from content and
+// re-associated after a content-level restore.
+type ReferencesDump struct {
+	// References maps each image reference to the digest it points at.
+	References map[string]string
+}
+
+// ReferencesRestoreReport summarizes a restore of the reference mapping.
+type ReferencesRestoreReport struct {
+	// Restored lists the references that were re-associated with their
+	// target.
+	Restored []string `json:",omitempty"`
+
+	// Skipped lists references whose target content is not present
+	// locally; restore the content first and import again to re-associate
+	// them.
+	Skipped []string `json:",omitempty"`
+}
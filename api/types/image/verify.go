@@ -0,0 +1,49 @@
+package image
+
+// VerifyOptions holds parameters to verify the content of local images.
+type VerifyOptions struct {
+	// Repair re-fetches missing or corrupt blobs from the registries the
+	// image was pulled from, using the recorded distribution sources.
+	Repair bool
+
+	// Delete removes images whose content is missing or corrupt and could
+	// not be repaired.
+	Delete bool
+}
+
+// VerifyResult describes the outcome of verifying a single image:
+// POST "/images/verify"
+type VerifyResult struct {
+	// ID is the content-addressable ID of the image.
+	ID string
+
+	// RepoTags is the list of references tagged on the image.
+	RepoTags []string `json:",omitempty"`
+
+	// Missing is the list of digests referenced by the image which are not
+	// present in the content store.
+	Missing []string `json:",omitempty"`
+
+	// Corrupt is the list of digests whose content no longer matches the
+	// digest it is stored under.
+	Corrupt []string `json:",omitempty"`
+
+	// Repaired is the list of digests that were successfully re-fetched
+	// from the image's registry sources.
+	Repaired []string `json:",omitempty"`
+
+	// Deleted reports whether the broken image was deleted.
+	Deleted bool `json:",omitempty"`
+}
+
+// VerifyReport contains the response of the API endpoint that verifies the
+// content of local images:
+// POST "/images/verify"
+type VerifyReport struct {
+	// ImagesVerified is the total number of images checked.
+	ImagesVerified int
+
+	// Broken lists the images with missing or corrupt content. It is empty
+	// when all image content verified successfully.
+	Broken []VerifyResult `json:",omitempty"`
+}
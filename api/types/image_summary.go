@@ -7,6 +7,12 @@ package types
 // swagger:model ImageSummary
 type ImageSummary struct {
 
+	// Media type of the artifact when the image is an OCI artifact (for
+	// example a WASM module or a Helm chart) instead of a runnable image.
+	// Empty for regular images.
+	//
+	ArtifactType string `json:"ArtifactType,omitempty"`
+
 	// Number of containers using this image. Includes both stopped and running
 	// containers.
 	//
@@ -38,6 +44,19 @@ type ImageSummary struct {
 	// Required: true
 	Labels map[string]string `json:"Labels"`
 
+	// Date and time at which the image was last pulled by this daemon, or
+	// last validated at the registry by the "always" pull policy, as a Unix
+	// timestamp (number of seconds sinds EPOCH). Omitted for images that
+	// were built or imported locally.
+	//
+	LastPulled int64 `json:"LastPulled,omitempty"`
+
+	// Date and time at which the image was last pushed to a registry by
+	// this daemon, as a Unix timestamp (number of seconds sinds EPOCH).
+	// Omitted when the image was never pushed.
+	//
+	LastPushed int64 `json:"LastPushed,omitempty"`
+
 	// ID of the parent image.
 	//
 	// Depending on how the image was created, this field may be empty and
@@ -47,6 +66,11 @@ type ImageSummary struct {
 	// Required: true
 	ParentID string `json:"ParentId"`
 
+	// Pinned indicates that the image was pinned and is protected from
+	// image prune and from automatic image garbage collection.
+	//
+	Pinned bool `json:"Pinned,omitempty"`
+
 	// List of content-addressable digests of locally available image manifests
 	// that the image is referenced from. Multiple manifests can refer to the
 	// same image.
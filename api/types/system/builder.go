@@ -0,0 +1,48 @@
+package system
+
+// BuilderInfo describes the daemon's embedded BuildKit builder, as returned
+// by GET "/build/inspect" and in the "Builder" field of GET "/info".
+type BuilderInfo struct {
+	// Name is the ID of the BuildKit worker backing the builder.
+	Name string
+
+	// Platforms are the platforms the worker can build for, in
+	// "os/arch[/variant]" notation. Platforms reachable through binfmt
+	// emulation are included.
+	Platforms []string
+
+	// Emulators are the non-native platforms from Platforms, i.e. those
+	// only executable through an emulator such as qemu registered with
+	// binfmt_misc.
+	Emulators []string `json:",omitempty"`
+
+	// Snapshotter is the containerd snapshotter the worker stores its
+	// layers in. Empty when the worker is backed by a graph driver.
+	Snapshotter string `json:",omitempty"`
+
+	// Labels are the BuildKit worker labels.
+	Labels map[string]string `json:",omitempty"`
+
+	// GCPolicy is the garbage-collection policy the worker applies to its
+	// build cache.
+	GCPolicy []BuilderGCRule `json:",omitempty"`
+}
+
+// BuilderGCRule is one rule of a BuildKit worker's garbage-collection
+// policy.
+type BuilderGCRule struct {
+	// All indicates that the rule applies to all cache records, not only
+	// the reclaimable ones.
+	All bool `json:",omitempty"`
+
+	// Filter restricts the cache records the rule applies to.
+	Filter []string `json:",omitempty"`
+
+	// KeepDuration is the age (in nanoseconds) after which cache records
+	// are collected by this rule. Zero means no age limit.
+	KeepDuration int64 `json:",omitempty"`
+
+	// KeepBytes is the amount of cache this rule keeps. Zero means no
+	// size limit.
+	KeepBytes int64 `json:",omitempty"`
+}
@@ -0,0 +1,45 @@
+package system
+
+// Image-store capability names advertised in the ImageStore field of
+// [Capabilities].
+const (
+	// CapImageMultiPlatform indicates that the image store can hold all
+	// platforms of a multi-platform image.
+	CapImageMultiPlatform = "multi-platform"
+	// CapImageSnapshotterSelect indicates that pulls can select the
+	// snapshotter to unpack into, per request or per registry.
+	CapImageSnapshotterSelect = "snapshotter-select"
+	// CapImageMount indicates support for mounting images as read-only
+	// filesystems (POST "/images/{name}/mount").
+	CapImageMount = "mount"
+	// CapImageConvert indicates support for converting images between
+	// manifest formats (POST "/images/{name}/convert").
+	CapImageConvert = "convert"
+	// CapImageFlatten indicates support for flattening images to a single
+	// layer (POST "/images/{name}/flatten").
+	CapImageFlatten = "flatten"
+	// CapImageVerify indicates support for verifying the integrity of
+	// image content (POST "/images/verify").
+	CapImageVerify = "verify"
+	// CapImageReferences indicates support for dumping and restoring the
+	// reference mapping of the image store ("/images/references").
+	CapImageReferences = "references"
+	// CapImageRecordLabels indicates support for attaching labels to the
+	// image record without changing the image ID.
+	CapImageRecordLabels = "record-labels"
+	// CapImageRegistryMirror indicates that the daemon can serve its
+	// image store through the Registry API.
+	CapImageRegistryMirror = "registry-mirror"
+	// CapImageSquash indicates support for squashing image layers after a
+	// build ("--squash").
+	CapImageSquash = "squash"
+)
+
+// Capabilities advertises which optional features are implemented by the
+// active backends, so clients can adjust their UX instead of probing
+// endpoints for "not implemented" errors.
+type Capabilities struct {
+	// ImageStore lists the capabilities of the active image store; see
+	// the CapImage constants for known values.
+	ImageStore []string
+}
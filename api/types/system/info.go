@@ -75,6 +75,19 @@ type Info struct {
 	DefaultAddressPools []NetworkAddressPool `json:",omitempty"`
 	CDISpecDirs         []string
 
+	// Builder describes the embedded BuildKit builder. Only present on
+	// API 1.44 and up.
+	Builder *BuilderInfo `json:",omitempty"`
+
+	// Capabilities advertises which optional features are implemented by
+	// the active backends. Only present on API 1.44 and up.
+	Capabilities *Capabilities `json:",omitempty"`
+
+	// PreloadImages reports the status of the images listed in the
+	// daemon's preload-images configuration. Only present when image
+	// preloading is configured, on API 1.44 and up.
+	PreloadImages []ImagePreloadStatus `json:",omitempty"`
+
 	// Legacy API fields for older API versions.
 	legacyFields
 
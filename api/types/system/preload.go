@@ -0,0 +1,26 @@
+package system
+
+// Statuses reported for images listed in the daemon's preload-images
+// configuration.
+const (
+	// PreloadStatusPending means the image has not been checked yet.
+	PreloadStatusPending = "pending"
+	// PreloadStatusPresent means the image was already present at startup.
+	PreloadStatusPresent = "present"
+	// PreloadStatusPulled means the image was missing and has been pulled.
+	PreloadStatusPulled = "pulled"
+	// PreloadStatusFailed means the image could not be pulled; Error holds
+	// the reason.
+	PreloadStatusFailed = "failed"
+)
+
+// ImagePreloadStatus reports the state of one image listed in the daemon's
+// preload-images configuration.
+type ImagePreloadStatus struct {
+	// Image is the reference as listed in the configuration.
+	Image string
+	// Status is one of the PreloadStatus constants.
+	Status string
+	// Error is the failure reason when Status is "failed".
+	Error string `json:",omitempty"`
+}
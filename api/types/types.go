@@ -129,6 +129,62 @@ type ImageInspect struct {
 	//
 	// This information is local to the daemon, and not part of the image itself.
 	Metadata ImageMetadata
+
+	// ArtifactType is the media type of the artifact when the manifest
+	// describes an OCI artifact (for example a WASM module or a Helm chart)
+	// instead of a runnable image. Empty for regular images.
+	ArtifactType string `json:",omitempty"`
+
+	// DistributionSources lists the registry repositories the image is
+	// known to have been pulled from, as "host/repository" strings.
+	//
+	// This information is local to the daemon, and not part of the image
+	// itself. It is only set when the containerd image store is enabled.
+	DistributionSources []string `json:",omitempty"`
+
+	// LastPulled is when the image was last pulled, or last validated at
+	// the registry by the "always" pull policy. Zero for images that were
+	// built or imported locally.
+	//
+	// This information is local to the daemon. It is only set when the
+	// containerd image store is enabled.
+	LastPulled time.Time `json:",omitempty"`
+
+	// LastPushed is when the image was last pushed to a registry by this
+	// daemon. Zero when it never was.
+	//
+	// This information is local to the daemon. It is only set when the
+	// containerd image store is enabled.
+	LastPushed time.Time `json:",omitempty"`
+
+	// Snapshotters lists the snapshotters that hold an unpacked copy of the
+	// image for the host platform.
+	//
+	// This information is local to the daemon. It is only set when the
+	// containerd image store is enabled.
+	Snapshotters []string `json:",omitempty"`
+
+	// Usage counts the resources on this daemon that reference the image,
+	// such as containers using it.
+	//
+	// This information is local to the daemon, and not part of the image
+	// itself.
+	Usage *ImageUsage `json:",omitempty"`
+
+	// Platforms describes the local state of every platform present in the
+	// image's index: whether its content is complete and which snapshotters
+	// hold an unpacked copy of it.
+	//
+	// This information is local to the daemon. It is only set when the
+	// containerd image store is enabled.
+	Platforms []ImagePlatformStatus `json:",omitempty"`
+
+	// Pinned indicates that the image was pinned and is protected from
+	// image prune and from automatic image garbage collection.
+	//
+	// This information is local to the daemon, and not part of the image
+	// itself.
+	Pinned bool `json:",omitempty"`
 }
 
 // ImageMetadata contains engine-local data about the image
@@ -545,6 +601,60 @@ type VolumesPruneReport struct {
 type ImagesPruneReport struct {
 	ImagesDeleted  []ImageDeleteResponseItem
 	SpaceReclaimed uint64
+
+	// ImagesSkipped counts the images that were considered but not
+	// deleted, keyed by the reason they were kept (for example "in use",
+	// "filter mismatch" or "lease held").
+	ImagesSkipped map[string]uint64 `json:",omitempty"`
+
+	// Failures lists the images the prune tried but failed to delete,
+	// each with a machine-readable reason, so a partial prune doesn't
+	// collapse into a single flattened error string.
+	Failures []ImagePruneFailure `json:",omitempty"`
+}
+
+// ImageUsage counts the resources that reference an image, so clients can
+// explain why a deletion would be blocked without attempting it.
+type ImageUsage struct {
+	// Containers is the total number of containers using the image,
+	// running or not.
+	Containers int64
+	// RunningContainers and StoppedContainers split Containers by state.
+	RunningContainers int64
+	StoppedContainers int64
+	// Services is the number of swarm services using the image, or -1
+	// when the daemon doesn't track services (not part of a swarm).
+	Services int64
+	// BuildCache is the number of build-cache records derived from the
+	// image, or -1 when the daemon cannot determine it.
+	BuildCache int64
+}
+
+// ImagePlatformStatus describes the local state of one platform present in
+// an image's index.
+type ImagePlatformStatus struct {
+	// Platform in the format os[/arch[/variant]].
+	Platform string
+	// ContentAvailable is true when every blob of this platform's manifest
+	// is present in the content store. Pulling the image downloads only
+	// the missing blobs.
+	ContentAvailable bool
+	// Unpacked lists the snapshotters that hold an unpacked copy of this
+	// platform. Running a container requires the platform to be unpacked
+	// into the active snapshotter; available content is unpacked locally
+	// without downloading anything.
+	Unpacked []string `json:",omitempty"`
+}
+
+// ImagePruneFailure describes a single image that a prune could not delete.
+type ImagePruneFailure struct {
+	// Image is the image name or digest the failure applies to.
+	Image string
+	// Reason is a machine-readable category, for example "in use" or
+	// "content store I/O error".
+	Reason string
+	// Error is the underlying error message.
+	Error string
 }
 
 // BuildCachePruneReport contains the response for Engine API:
@@ -554,6 +664,39 @@ type BuildCachePruneReport struct {
 	SpaceReclaimed uint64
 }
 
+// BuildCacheMount describes a `RUN --mount=type=cache` volume held by the
+// builder.
+type BuildCacheMount struct {
+	// ID is the ID of the build cache record backing the cache mount.
+	ID string
+	// CacheID is the cache ID the Dockerfile declared with the `id` option
+	// of `RUN --mount=type=cache`, defaulting to the mount target path.
+	// Mounts populated from a named stage carry the ID of the source
+	// snapshot after a colon.
+	CacheID string
+	// Description is a description of the build-step that created the
+	// cache mount.
+	Description string
+	// InUse indicates if the cache mount is used by a running build.
+	InUse bool
+	// Shared indicates if the cache mount is shared between build steps.
+	Shared bool
+	// Size is the amount of disk space used by the cache mount (in bytes).
+	Size int64
+	// CreatedAt is the date and time at which the cache mount was created.
+	CreatedAt time.Time
+	// LastUsedAt is the date and time at which the cache mount was last used.
+	LastUsedAt *time.Time
+	UsageCount int
+}
+
+// BuildCacheMountPruneReport contains the response for Engine API:
+// POST "/build/cachemounts/prune"
+type BuildCacheMountPruneReport struct {
+	RecordsDeleted []string
+	SpaceReclaimed uint64
+}
+
 // NetworksPruneReport contains the response for Engine API:
 // POST "/networks/prune"
 type NetworksPruneReport struct {
@@ -593,6 +736,29 @@ type PushResult struct {
 	Size   int
 }
 
+// PullResult summarizes what a completed pull produced. It is sent as an
+// aux progress message at the end of the pull stream so automation can
+// capture exactly what the pull did without parsing the human-readable
+// progress messages.
+type PullResult struct {
+	// Digest is the digest of the manifest or index the pulled reference
+	// resolved to.
+	Digest string
+
+	// Platforms lists the platforms whose manifests were fetched, in
+	// "os[/arch[/variant]]" format.
+	Platforms []string
+
+	// BytesTransferred is the total size of the content downloaded from
+	// the registry. Content that was already present locally does not
+	// count.
+	BytesTransferred int64
+
+	// Snapshotter is the snapshotter the image was unpacked into. Empty
+	// for content-only pulls.
+	Snapshotter string
+}
+
 // BuildResult contains the image id of a successful build
 type BuildResult struct {
 	ID string
@@ -629,5 +795,8 @@ type BuildCache struct {
 type BuildCachePruneOptions struct {
 	All         bool
 	KeepStorage int64
-	Filters     filters.Args
+	// KeepDuration is the minimum age of cache records to remove; it's
+	// equivalent to, and conflicts with, the "until" filter.
+	KeepDuration time.Duration
+	Filters      filters.Args
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,6 +15,8 @@ import (
 	"github.com/containerd/containerd/remotes/docker"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/system"
 	timetypes "github.com/docker/docker/api/types/time"
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/builder-next/exporter"
@@ -21,18 +24,22 @@ import (
 	"github.com/docker/docker/builder/builder-next/exporter/overrides"
 	"github.com/docker/docker/daemon/config"
 	"github.com/docker/docker/daemon/images"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/libnetwork"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/docker/docker/registry"
 	"github.com/docker/go-units"
 	controlapi "github.com/moby/buildkit/api/services/control"
 	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/control"
 	"github.com/moby/buildkit/identity"
 	"github.com/moby/buildkit/session"
+	spb "github.com/moby/buildkit/sourcepolicy/pb"
 	"github.com/moby/buildkit/util/entitlements"
 	"github.com/moby/buildkit/util/tracing"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
@@ -93,6 +100,11 @@ type Opt struct {
 	Snapshotter         string
 	ContainerdAddress   string
 	ContainerdNamespace string
+
+	// CredentialHelpers resolves daemon-configured registry credentials
+	// for base image pulls when the build session provides no auth; see
+	// registryauth.go.
+	CredentialHelpers *registry.CredentialHelpers
 }
 
 // Builder can build using BuildKit backend
@@ -104,13 +116,30 @@ type Builder struct {
 	mu             sync.Mutex
 	jobs           map[string]*buildJob
 	useSnapshotter bool
+	snapshotter    string
+	cacheMounts    *cacheMounts
+
+	// sourcePolicy is the daemon-configured source policy applied to the
+	// sources of every build; see sourcepolicy.go.
+	sourcePolicy *spb.Policy
 }
 
 // New creates a new builder
 func New(ctx context.Context, opt Opt) (*Builder, error) {
 	reqHandler := newReqBodyHandler(tracing.DefaultTransport)
 
-	c, err := newController(ctx, reqHandler, opt)
+	if opt.CredentialHelpers != nil {
+		opt.RegistryHosts = withCredentialHelpers(opt.RegistryHosts, opt.CredentialHelpers)
+	}
+	c, cm, err := newController(ctx, reqHandler, opt)
+	if err != nil {
+		return nil, err
+	}
+	cm.sizeCaps, err = parseCacheMountSizeCaps(opt.BuilderConfig)
+	if err != nil {
+		return nil, err
+	}
+	sourcePolicy, err := toBuildkitSourcePolicy(opt.BuilderConfig.SourcePolicy)
 	if err != nil {
 		return nil, err
 	}
@@ -120,10 +149,64 @@ func New(ctx context.Context, opt Opt) (*Builder, error) {
 		reqBodyHandler: reqHandler,
 		jobs:           map[string]*buildJob{},
 		useSnapshotter: opt.UseSnapshotter,
+		cacheMounts:    cm,
+		sourcePolicy:   sourcePolicy,
 	}
+	if opt.UseSnapshotter {
+		b.snapshotter = opt.Snapshotter
+	}
+	// Apply the configured size caps to cache mounts left over from
+	// previous daemon runs.
+	go b.enforceCacheMountCaps(context.Background())
 	return b, nil
 }
 
+// Inspect describes the BuildKit worker backing the builder: the platforms
+// it can build for (including detected emulators), the snapshotter it stores
+// layers in and its cache garbage-collection policy.
+func (b *Builder) Inspect(ctx context.Context) (*system.BuilderInfo, error) {
+	resp, err := b.controller.ListWorkers(ctx, &controlapi.ListWorkersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Record) == 0 {
+		return nil, errors.New("no worker found in the builder")
+	}
+
+	// The embedded builder always has exactly one worker.
+	w := resp.Record[0]
+	info := &system.BuilderInfo{
+		Name:        w.ID,
+		Labels:      w.Labels,
+		Snapshotter: b.snapshotter,
+	}
+
+	native := platforms.Normalize(platforms.DefaultSpec())
+	for _, p := range w.Platforms {
+		spec := ocispecs.Platform{
+			OS:           p.OS,
+			Architecture: p.Architecture,
+			Variant:      p.Variant,
+		}
+		formatted := platforms.Format(spec)
+		info.Platforms = append(info.Platforms, formatted)
+		if spec.OS != native.OS || spec.Architecture != native.Architecture {
+			info.Emulators = append(info.Emulators, formatted)
+		}
+	}
+
+	for _, rule := range w.GCPolicy {
+		info.GCPolicy = append(info.GCPolicy, system.BuilderGCRule{
+			All:          rule.All,
+			Filter:       rule.Filters,
+			KeepDuration: rule.KeepDuration,
+			KeepBytes:    rule.KeepBytes,
+		})
+	}
+
+	return info, nil
+}
+
 // RegisterGRPC registers controller to the grpc server.
 func (b *Builder) RegisterGRPC(s *grpc.Server) {
 	b.controller.Register(s)
@@ -218,6 +301,56 @@ func (b *Builder) Prune(ctx context.Context, opts types.BuildCachePruneOptions)
 	return size, cacheIDs, nil
 }
 
+// RemoveBuildHistory deletes the history record of the build with the given
+// ref, releasing the log and trace blobs it stores and unpinning the cache
+// refs it keeps alive. The build's reclaimable cache records can then be
+// removed with RemoveCacheRecord or a regular prune.
+func (b *Builder) RemoveBuildHistory(ctx context.Context, ref string) error {
+	_, err := b.controller.UpdateBuildHistory(ctx, &controlapi.UpdateBuildHistoryRequest{
+		Ref:    ref,
+		Delete: true,
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return errdefs.NotFound(errors.Errorf("no build found with ref %s", ref))
+	}
+	return err
+}
+
+// RemoveCacheRecord deletes the single build-cache record with the given ID
+// and returns the bytes reclaimed. Unlike Prune it fails when the record
+// does not exist or is in use, instead of silently skipping it.
+func (b *Builder) RemoveCacheRecord(ctx context.Context, id string) (int64, error) {
+	records, err := b.DiskUsage(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var record *types.BuildCache
+	for _, r := range records {
+		if r.ID == id {
+			record = r
+			break
+		}
+	}
+	if record == nil {
+		return 0, errdefs.NotFound(errors.Errorf("no build cache record with ID %s", id))
+	}
+	if record.InUse {
+		return 0, errdefs.Conflict(errors.Errorf("build cache record %s is in use and cannot be removed", id))
+	}
+
+	size, ids, err := b.Prune(ctx, types.BuildCachePruneOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("id", id)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, errdefs.Conflict(errors.Errorf("build cache record %s could not be removed", id))
+	}
+	return size, nil
+}
+
 // Build executes a build request
 func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.Result, error) {
 	if len(opt.Options.Outputs) > 1 {
@@ -313,6 +446,13 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 
 	if opt.Options.PullParent {
 		frontendAttrs["image-resolve-mode"] = "pull"
+	} else if b.useSnapshotter {
+		// The containerd worker resolves the "default" mode against the
+		// registry first; "local" makes FROM references resolve against
+		// locally built and tagged images before going to the network,
+		// matching the graph driver worker, whose source treats
+		// "default" as prefer-local already.
+		frontendAttrs["image-resolve-mode"] = "local"
 	} else {
 		frontendAttrs["image-resolve-mode"] = "default"
 	}
@@ -392,6 +532,7 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 		FrontendAttrs: frontendAttrs,
 		Session:       opt.Options.SessionID,
 		Cache:         cache,
+		SourcePolicy:  b.sourcePolicy,
 	}
 
 	if opt.Options.NetworkMode == "host" {
@@ -445,6 +586,10 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 		return nil, err
 	}
 
+	// The build may have grown a cache mount past its configured size cap;
+	// check in the background so the build response isn't delayed.
+	go b.enforceCacheMountCaps(context.Background())
+
 	return &out, nil
 }
 
@@ -653,6 +798,13 @@ func toBuildkitPruneInfo(opts types.BuildCachePruneOptions) (client.PruneInfo, e
 		return client.PruneInfo{}, errMultipleFilterValues{}
 	}
 
+	if opts.KeepDuration != 0 {
+		if until != 0 {
+			return client.PruneInfo{}, errConflictFilter{"keep-duration", filterKey}
+		}
+		until = opts.KeepDuration
+	}
+
 	bkFilter := make([]string, 0, opts.Filters.Len())
 	for cacheField := range cacheFields {
 		if opts.Filters.Contains(cacheField) {
@@ -0,0 +1,167 @@
+package buildkit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/daemon/config"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-units"
+	"github.com/moby/buildkit/cache/metadata"
+	"github.com/moby/buildkit/client"
+	bkworker "github.com/moby/buildkit/worker"
+	"github.com/pkg/errors"
+)
+
+// keyCacheDir is the metadata key under which buildkit's mounts package
+// records the cache ID of a cache mount record; see mounts.SearchCacheDir.
+const keyCacheDir = "cache-dir"
+
+// cacheMounts gives the builder access to the `RUN --mount=type=cache`
+// records of its worker: the worker prunes them, the metadata store maps
+// each cache record back to the cache ID declared in the Dockerfile.
+type cacheMounts struct {
+	worker bkworker.Worker
+	md     *metadata.Store
+
+	// mu serializes size-cap enforcement runs triggered by concurrent
+	// builds.
+	mu       sync.Mutex
+	sizeCaps map[string]int64
+}
+
+// parseCacheMountSizeCaps converts the configured human-readable size caps
+// into bytes, failing daemon startup on values that cannot be parsed.
+func parseCacheMountSizeCaps(conf config.BuilderConfig) (map[string]int64, error) {
+	if len(conf.CacheMounts.SizeCaps) == 0 {
+		return nil, nil
+	}
+	caps := make(map[string]int64, len(conf.CacheMounts.SizeCaps))
+	for id, v := range conf.CacheMounts.SizeCaps {
+		b, err := units.RAMInBytes(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse %q as Builder.CacheMounts.SizeCaps[%s] config", v, id)
+		}
+		if b <= 0 {
+			return nil, errors.Errorf("Builder.CacheMounts.SizeCaps[%s] config must be positive, got %q", id, v)
+		}
+		caps[id] = b
+	}
+	return caps, nil
+}
+
+// cacheID resolves the cache ID a cache mount record was created for, or ""
+// when the record carries no cache-dir metadata.
+func (c *cacheMounts) cacheID(recordID string) string {
+	si, ok := c.md.Get(recordID)
+	if !ok {
+		return ""
+	}
+	v := si.Get(keyCacheDir)
+	if v == nil {
+		return ""
+	}
+	var id string
+	if err := v.Unmarshal(&id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// CacheMounts lists the `RUN --mount=type=cache` volumes held by the
+// builder's worker, with their cache IDs and disk usage.
+func (b *Builder) CacheMounts(ctx context.Context) ([]*types.BuildCacheMount, error) {
+	du, err := b.cacheMounts.worker.DiskUsage(ctx, client.DiskUsageInfo{
+		Filter: []string{"type==" + string(client.UsageRecordTypeCacheMount)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*types.BuildCacheMount, 0, len(du))
+	for _, r := range du {
+		items = append(items, &types.BuildCacheMount{
+			ID:          r.ID,
+			CacheID:     b.cacheMounts.cacheID(r.ID),
+			Description: r.Description,
+			InUse:       r.InUse,
+			Shared:      r.Shared,
+			Size:        r.Size,
+			CreatedAt:   r.CreatedAt,
+			LastUsedAt:  r.LastUsedAt,
+			UsageCount:  r.UsageCount,
+		})
+	}
+	return items, nil
+}
+
+// PruneCacheMounts resets the cache mounts with the given cache IDs. The
+// reported size is the disk usage of the records that were detached;
+// records still used by a running build are released when that build
+// finishes.
+func (b *Builder) PruneCacheMounts(ctx context.Context, ids []string) (*types.BuildCacheMountPruneReport, error) {
+	if len(ids) == 0 {
+		return nil, errdefs.InvalidParameter(errors.New("no cache mount IDs provided"))
+	}
+
+	mounts, err := b.CacheMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	report := &types.BuildCacheMountPruneReport{}
+	for _, m := range mounts {
+		if _, ok := wanted[m.CacheID]; !ok {
+			continue
+		}
+		report.RecordsDeleted = append(report.RecordsDeleted, m.ID)
+		report.SpaceReclaimed += uint64(m.Size)
+	}
+
+	if err := b.cacheMounts.worker.PruneCacheMounts(ctx, ids); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// enforceCacheMountCaps resets every cache mount whose total size exceeds
+// its configured cap. It runs in the background after each build, so a
+// build is never failed or delayed by a cache that grew past its cap.
+func (b *Builder) enforceCacheMountCaps(ctx context.Context) {
+	c := b.cacheMounts
+	if c == nil || len(c.sizeCaps) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mounts, err := b.CacheMounts(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list cache mounts for size-cap enforcement")
+		return
+	}
+	sizes := map[string]int64{}
+	for _, m := range mounts {
+		sizes[m.CacheID] += m.Size
+	}
+
+	for id, limit := range c.sizeCaps {
+		if sizes[id] <= limit {
+			continue
+		}
+		log.G(ctx).WithFields(log.Fields{
+			"cacheID": id,
+			"size":    sizes[id],
+			"cap":     limit,
+		}).Info("build cache mount exceeds its size cap, resetting")
+		if err := c.worker.PruneCacheMounts(ctx, []string{id}); err != nil {
+			log.G(ctx).WithError(err).WithField("cacheID", id).Warn("failed to reset cache mount exceeding its size cap")
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	ctd "github.com/containerd/containerd"
@@ -54,26 +55,33 @@ import (
 	"github.com/moby/buildkit/util/apicaps"
 )
 
-func newController(ctx context.Context, rt http.RoundTripper, opt Opt) (*control.Controller, error) {
+func newController(ctx context.Context, rt http.RoundTripper, opt Opt) (*control.Controller, *cacheMounts, error) {
 	if opt.UseSnapshotter {
 		return newSnapshotterController(ctx, rt, opt)
 	}
 	return newGraphDriverController(ctx, rt, opt)
 }
 
-func newSnapshotterController(ctx context.Context, rt http.RoundTripper, opt Opt) (*control.Controller, error) {
+func newSnapshotterController(ctx context.Context, rt http.RoundTripper, opt Opt) (*control.Controller, *cacheMounts, error) {
+	if strings.HasPrefix(opt.ContainerdAddress, "tcp://") {
+		// The vendored worker constructor dials the address itself and
+		// only understands local sockets; handing it a tcp:// address
+		// would make it dial a nonsense socket path. Fail up front with
+		// an actionable error instead of breaking at the first build.
+		return nil, nil, errors.Errorf("the BuildKit containerd worker requires a local containerd socket and cannot use the remote containerd address %s; builds need a containerd instance on this host", opt.ContainerdAddress)
+	}
 	if err := os.MkdirAll(opt.Root, 0o711); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	historyDB, historyConf, err := openHistoryDB(opt.Root, opt.BuilderConfig.History)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	cacheStorage, err := bboltcachestorage.NewStore(filepath.Join(opt.Root, "cache.db"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	nc := netproviders.Opt{
@@ -86,12 +94,12 @@ func newSnapshotterController(ctx context.Context, rt http.RoundTripper, opt Opt
 			label.Snapshotter: opt.Snapshotter,
 		}, dns, nc, opt.ApparmorProfile, false, nil, "", ctd.WithTimeout(60*time.Second))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	policy, err := getGCPolicy(opt.BuilderConfig, opt.Root)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	wo.GCPolicy = policy
@@ -100,27 +108,27 @@ func newSnapshotterController(ctx context.Context, rt http.RoundTripper, opt Opt
 
 	exec, err := newExecutor(opt.Root, opt.DefaultCgroupParent, opt.NetworkController, dns, opt.Rootless, opt.IdentityMapping, opt.ApparmorProfile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	wo.Executor = exec
 
 	w, err := mobyworker.NewContainerdWorker(ctx, wo)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	wc := &worker.Controller{}
 
 	err = wc.Add(w)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	frontends := map[string]frontend.Frontend{
 		"dockerfile.v0": forwarder.NewGatewayForwarder(wc, dockerfile.Build),
 		"gateway.v0":    gateway.NewGatewayFrontend(wc),
 	}
 
-	return control.NewController(control.Opt{
+	c, err := control.NewController(control.Opt{
 		SessionManager:   opt.SessionManager,
 		WorkerController: wc,
 		Frontends:        frontends,
@@ -142,6 +150,10 @@ func newSnapshotterController(ctx context.Context, rt http.RoundTripper, opt Opt
 		LeaseManager:  wo.LeaseManager,
 		ContentStore:  wo.ContentStore,
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, &cacheMounts{worker: w, md: wo.MetadataStore}, nil
 }
 
 func openHistoryDB(root string, cfg *config.BuilderHistoryConfig) (*bolt.DB, *bkconfig.HistoryConfig, error) {
@@ -161,9 +173,9 @@ func openHistoryDB(root string, cfg *config.BuilderHistoryConfig) (*bolt.DB, *bk
 	return db, conf, nil
 }
 
-func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt) (*control.Controller, error) {
+func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt) (*control.Controller, *cacheMounts, error) {
 	if err := os.MkdirAll(opt.Root, 0o711); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	dist := opt.Dist
@@ -187,17 +199,17 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 	}); ok {
 		driver = ls.Driver()
 	} else {
-		return nil, errors.Errorf("could not access graphdriver")
+		return nil, nil, errors.Errorf("could not access graphdriver")
 	}
 
 	store, err := local.NewStore(filepath.Join(root, "content"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	db, err := bolt.Open(filepath.Join(root, "containerdmeta.db"), 0o644, nil)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, nil, errors.WithStack(err)
 	}
 
 	mdb := ctdmetadata.NewDB(db, store, map[string]snapshots.Snapshotter{})
@@ -213,21 +225,21 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 		IdentityMapping: opt.IdentityMapping,
 	}, lm)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := cache.MigrateV2(context.Background(), filepath.Join(root, "metadata.db"), filepath.Join(root, "metadata_v2.db"), store, snapshotter, lm); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	md, err := metadata.NewStore(filepath.Join(root, "metadata_v2.db"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	layerGetter, ok := snapshotter.(imagerefchecker.LayerGetter)
 	if !ok {
-		return nil, errors.Errorf("snapshotter does not implement layergetter")
+		return nil, nil, errors.Errorf("snapshotter does not implement layergetter")
 	}
 
 	refChecker := imagerefchecker.New(imagerefchecker.Opt{
@@ -244,7 +256,7 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 		GarbageCollect:  mdb.GarbageCollect,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	src, err := containerimage.NewSource(containerimage.SourceOpt{
@@ -260,19 +272,19 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 		GarbageCollect:  mdb.GarbageCollect,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	dns := getDNSConfig(opt.DNSConfig)
 
 	exec, err := newExecutor(root, opt.DefaultCgroupParent, opt.NetworkController, dns, opt.Rootless, opt.IdentityMapping, opt.ApparmorProfile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	differ, ok := snapshotter.(mobyexporter.Differ)
 	if !ok {
-		return nil, errors.Errorf("snapshotter doesn't support differ")
+		return nil, nil, errors.Errorf("snapshotter doesn't support differ")
 	}
 
 	exp, err := mobyexporter.New(mobyexporter.Opt{
@@ -281,32 +293,32 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 		ImageTagger: opt.ImageTagger,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	cacheStorage, err := bboltcachestorage.NewStore(filepath.Join(opt.Root, "cache.db"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	historyDB, historyConf, err := openHistoryDB(opt.Root, opt.BuilderConfig.History)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	gcPolicy, err := getGCPolicy(opt.BuilderConfig, root)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not get builder GC policy")
+		return nil, nil, errors.Wrap(err, "could not get builder GC policy")
 	}
 
 	layers, ok := snapshotter.(mobyworker.LayerAccess)
 	if !ok {
-		return nil, errors.Errorf("snapshotter doesn't support differ")
+		return nil, nil, errors.Errorf("snapshotter doesn't support differ")
 	}
 
 	leases, err := lm.List(ctx, `labels."buildkit/lease.temporary"`)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, l := range leases {
 		lm.Delete(ctx, l)
@@ -333,7 +345,7 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 	wc := &worker.Controller{}
 	w, err := mobyworker.NewWorker(wopt)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	wc.Add(w)
 
@@ -342,7 +354,7 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 		"gateway.v0":    gateway.NewGatewayFrontend(wc),
 	}
 
-	return control.NewController(control.Opt{
+	c, err := control.NewController(control.Opt{
 		SessionManager:   opt.SessionManager,
 		WorkerController: wc,
 		Frontends:        frontends,
@@ -360,6 +372,10 @@ func newGraphDriverController(ctx context.Context, rt http.RoundTripper, opt Opt
 		HistoryDB:     historyDB,
 		HistoryConfig: historyConf,
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, &cacheMounts{worker: w, md: md}, nil
 }
 
 func getGCPolicy(conf config.BuilderConfig, root string) ([]client.PruneInfo, error) {
@@ -0,0 +1,89 @@
+package buildkit
+
+import (
+	"net/http"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/docker/registry"
+)
+
+// withCredentialHelpers wraps a registry-hosts callback so that requests to
+// hosts with a configured credential helper are retried with the daemon's
+// credentials when the registry rejects them as unauthorized. BuildKit
+// replaces every host's authorizer with one backed by the build session, so
+// the fallback lives in the host's HTTP client, below the authorizer: it
+// only takes effect when the session provides no working credentials.
+func withCredentialHelpers(hosts docker.RegistryHosts, credHelpers *registry.CredentialHelpers) docker.RegistryHosts {
+	return func(host string) ([]docker.RegistryHost, error) {
+		res, err := hosts(host)
+		if err != nil {
+			return nil, err
+		}
+		for i := range res {
+			if !credHelpers.Configured(res[i].Host) {
+				continue
+			}
+			// Copy the client rather than mutating it; the hosts
+			// callback may hand out the same client on every call.
+			client := &http.Client{}
+			if res[i].Client != nil {
+				c := *res[i].Client
+				client = &c
+			}
+			client.Transport = newAuthFallbackTransport(client.Transport, credHelpers)
+			res[i].Client = client
+		}
+		return res, nil
+	}
+}
+
+// authFallbackTransport retries an unauthorized request once, authorized
+// with credentials resolved from the daemon's credential helpers. The
+// containerd authorizer takes care of token negotiation against the
+// challenge carried by the rejected response.
+type authFallbackTransport struct {
+	base       http.RoundTripper
+	authorizer docker.Authorizer
+}
+
+func newAuthFallbackTransport(base http.RoundTripper, credHelpers *registry.CredentialHelpers) *authFallbackTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &authFallbackTransport{
+		base:       base,
+		authorizer: docker.NewDockerAuthorizer(docker.WithAuthCreds(credHelpers.Get)),
+	}
+}
+
+func (t *authFallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// Only requests whose body can be replayed are retried.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	ctx := req.Context()
+	if err := t.authorizer.AddResponses(ctx, []*http.Response{resp}); err != nil {
+		return resp, nil
+	}
+	retry := req.Clone(ctx)
+	retry.Header.Del("Authorization")
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	if err := t.authorizer.Authorize(ctx, retry); err != nil || retry.Header.Get("Authorization") == "" {
+		// No credentials for this host after all; hand the original
+		// rejection back to the caller.
+		return resp, nil
+	}
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
@@ -0,0 +1,61 @@
+package buildkit
+
+import (
+	"github.com/docker/docker/daemon/config"
+	spb "github.com/moby/buildkit/sourcepolicy/pb"
+	"github.com/pkg/errors"
+)
+
+// toBuildkitSourcePolicy converts the daemon's source-policy configuration
+// into a BuildKit source policy. An empty rule list yields a nil policy.
+func toBuildkitSourcePolicy(rules []config.BuilderSourcePolicyRule) (*spb.Policy, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	pol := &spb.Policy{Version: 1}
+	for _, r := range rules {
+		rule := &spb.Rule{}
+
+		switch r.Action {
+		case "allow":
+			rule.Action = spb.PolicyAction_ALLOW
+		case "deny":
+			rule.Action = spb.PolicyAction_DENY
+		case "convert":
+			rule.Action = spb.PolicyAction_CONVERT
+		default:
+			return nil, errors.Errorf(`invalid source policy action %q: must be "allow", "deny" or "convert"`, r.Action)
+		}
+
+		if r.Selector.Identifier == "" {
+			return nil, errors.New("source policy rules require a selector identifier")
+		}
+		rule.Selector = &spb.Selector{Identifier: r.Selector.Identifier}
+		switch r.Selector.MatchType {
+		case "", "wildcard":
+			rule.Selector.MatchType = spb.MatchType_WILDCARD
+		case "exact":
+			rule.Selector.MatchType = spb.MatchType_EXACT
+		case "regex":
+			rule.Selector.MatchType = spb.MatchType_REGEX
+		default:
+			return nil, errors.Errorf(`invalid source policy match type %q: must be "wildcard", "exact" or "regex"`, r.Selector.MatchType)
+		}
+
+		if rule.Action == spb.PolicyAction_CONVERT {
+			if r.Updates == nil || (r.Updates.Identifier == "" && len(r.Updates.Attrs) == 0) {
+				return nil, errors.Errorf(`source policy rule for %s with the "convert" action requires updates`, r.Selector.Identifier)
+			}
+			rule.Updates = &spb.Update{
+				Identifier: r.Updates.Identifier,
+				Attrs:      r.Updates.Attrs,
+			}
+		} else if r.Updates != nil {
+			return nil, errors.Errorf(`source policy rule for %s has updates, which are only valid with the "convert" action`, r.Selector.Identifier)
+		}
+
+		pol.Rules = append(pol.Rules, rule)
+	}
+	return pol, nil
+}
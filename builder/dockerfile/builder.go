@@ -316,10 +316,10 @@ func (b *Builder) dispatchDockerfileWithCancellation(ctx context.Context, parseR
 // - Call parse.Parse() to get an AST root for the concatenated Dockerfile entries.
 // - Do build by calling builder.dispatch() to call all entries' handling routines
 //
-// BuildFromConfig is used by the /commit endpoint, with the changes
-// coming from the query parameter of the same name.
-//
-// TODO: Remove?
+// BuildFromConfig is the single engine behind the `changes` query parameter:
+// both the /commit endpoint and the import flavour of /images/create apply
+// their change instructions through it. Only instructions that modify the
+// image configuration are accepted; see validCommitCommands.
 func BuildFromConfig(ctx context.Context, config *container.Config, changes []string, os string) (*container.Config, error) {
 	if len(changes) == 0 {
 		return config, nil
@@ -330,6 +330,13 @@ func BuildFromConfig(ctx context.Context, config *container.Config, changes []st
 		return nil, errdefs.InvalidParameter(err)
 	}
 
+	// ensure that the commands are valid
+	for _, n := range dockerfile.AST.Children {
+		if !validCommitCommands[strings.ToLower(n.Value)] {
+			return nil, errdefs.InvalidParameter(errors.Errorf("%s is not a valid change command; valid commands are: %s", n.Value, strings.Join(sortedCommitCommands(), ", ")))
+		}
+	}
+
 	b, err := newBuilder(ctx, builderOptions{
 		Options: &types.ImageBuildOptions{NoCache: true},
 	})
@@ -337,13 +344,6 @@ func BuildFromConfig(ctx context.Context, config *container.Config, changes []st
 		return nil, err
 	}
 
-	// ensure that the commands are valid
-	for _, n := range dockerfile.AST.Children {
-		if !validCommitCommands[strings.ToLower(n.Value)] {
-			return nil, errdefs.InvalidParameter(errors.Errorf("%s is not a valid change command", n.Value))
-		}
-	}
-
 	b.Stdout = io.Discard
 	b.Stderr = io.Discard
 	b.disableCommit = true
@@ -373,6 +373,17 @@ func BuildFromConfig(ctx context.Context, config *container.Config, changes []st
 	return dispatchRequest.state.runConfig, nil
 }
 
+// sortedCommitCommands returns the instructions accepted in change commands,
+// upper-cased and sorted for use in error messages.
+func sortedCommitCommands() []string {
+	commands := make([]string, 0, len(validCommitCommands))
+	for command := range validCommitCommands {
+		commands = append(commands, strings.ToUpper(command))
+	}
+	sort.Strings(commands)
+	return commands
+}
+
 func convertMapToEnvList(m map[string]string) []string {
 	result := []string{}
 	for k, v := range m {
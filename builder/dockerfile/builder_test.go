@@ -0,0 +1,53 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestBuildFromConfig(t *testing.T) {
+	base := &container.Config{Image: "busybox"}
+	changes := []string{
+		`CMD ["sleep", "100"]`,
+		"ENV FOO=bar",
+		"EXPOSE 80/tcp",
+		"LABEL com.example.version=1",
+		"USER nobody",
+	}
+
+	newConfig, err := BuildFromConfig(context.Background(), base, changes, runtime.GOOS)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(newConfig.Cmd, strslice.StrSlice{"sleep", "100"}))
+	assert.Check(t, is.Contains(newConfig.Env, "FOO=bar"))
+	assert.Check(t, is.Contains(newConfig.ExposedPorts, nat.Port("80/tcp")))
+	assert.Check(t, is.Equal(newConfig.Labels["com.example.version"], "1"))
+	assert.Check(t, is.Equal(newConfig.User, "nobody"))
+}
+
+func TestBuildFromConfigNoChanges(t *testing.T) {
+	base := &container.Config{Image: "busybox"}
+	newConfig, err := BuildFromConfig(context.Background(), base, nil, runtime.GOOS)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(newConfig, base))
+}
+
+func TestBuildFromConfigInvalidChange(t *testing.T) {
+	for _, change := range []string{
+		"RUN apt-get update",
+		"COPY foo /foo",
+		"FROM alpine",
+	} {
+		_, err := BuildFromConfig(context.Background(), &container.Config{}, []string{change}, runtime.GOOS)
+		assert.Check(t, errdefs.IsInvalidParameter(err), change)
+		assert.Check(t, is.ErrorContains(err, "is not a valid change command"), change)
+		assert.Check(t, is.ErrorContains(err, "valid commands are: CMD, ENTRYPOINT, ENV, EXPOSE, HEALTHCHECK, LABEL, ONBUILD, STOPSIGNAL, USER, VOLUME, WORKDIR"), change)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	buildbackend "github.com/docker/docker/api/server/backend/build"
 	"github.com/docker/docker/api/server/middleware"
 	"github.com/docker/docker/api/server/router"
+	blobsrouter "github.com/docker/docker/api/server/router/blobs"
 	"github.com/docker/docker/api/server/router/build"
 	checkpointrouter "github.com/docker/docker/api/server/router/checkpoint"
 	"github.com/docker/docker/api/server/router/container"
@@ -53,6 +54,7 @@ import (
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/plugin"
+	"github.com/docker/docker/registry"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/go-connections/tlsconfig"
 	"github.com/moby/buildkit/session"
@@ -101,6 +103,7 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 	}
 
 	configureProxyEnv(cli.Config)
+	configureTracingEnv(cli.Config)
 	configureDaemonLogs(cli.Config)
 
 	log.G(ctx).Info("Starting up")
@@ -379,6 +382,7 @@ func newRouterOptions(ctx context.Context, config *config.Config, d *daemon.Daem
 		Snapshotter:         d.ImageService().StorageDriver(),
 		ContainerdAddress:   config.ContainerdAddr,
 		ContainerdNamespace: config.ContainerdNamespace,
+		CredentialHelpers:   registry.NewCredentialHelpers(config.RegistryCredentialHelpers),
 	})
 	if err != nil {
 		return opts, err
@@ -617,6 +621,7 @@ func (opts routerOptions) Build() []router.Router {
 		swarmrouter.NewRouter(opts.cluster),
 		pluginrouter.NewRouter(opts.daemon.PluginManager()),
 		distributionrouter.NewRouter(opts.daemon.ImageBackend()),
+		blobsrouter.NewRouter(opts.daemon.ImageService()),
 	}
 
 	if opts.buildBackend != nil {
@@ -654,6 +659,11 @@ func initMiddlewares(s *apiserver.Server, cfg *config.Config, pluginStore plugin
 		s.UseMiddleware(c)
 	}
 
+	if cfg.Experimental && len(cfg.ImageNamespaces) > 0 {
+		nm := middleware.NewNamespaceMiddleware(cfg.ImageNamespaces)
+		s.UseMiddleware(nm)
+	}
+
 	authzMiddleware := authorization.NewMiddleware(cfg.AuthorizationPlugins, pluginStore)
 	s.UseMiddleware(authzMiddleware)
 	return authzMiddleware
@@ -912,6 +922,22 @@ func configureProxyEnv(conf *config.Config) {
 	}
 }
 
+// configureTracingEnv seeds the standard OTEL_* environment variables from
+// the daemon's tracing configuration. The trace exporter is detected from
+// those variables on first use, so this must run before any span is started;
+// variables already present in the environment win over daemon.json.
+func configureTracingEnv(conf *config.Config) {
+	if conf.Tracing.Endpoint == "" {
+		return
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		_ = os.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", conf.Tracing.Endpoint)
+	}
+	if conf.Tracing.Protocol != "" && os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL") == "" {
+		_ = os.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", conf.Tracing.Protocol)
+	}
+}
+
 func overrideProxyEnv(name, val string) {
 	if oldVal := os.Getenv(name); oldVal != "" && oldVal != val {
 		log.G(context.TODO()).WithFields(log.Fields{
@@ -23,7 +23,7 @@ func (daemon *Daemon) containerStatPath(container *container.Container, path str
 	container.Lock()
 	defer container.Unlock()
 
-	cfs, err := daemon.openContainerFS(container)
+	cfs, err := daemon.openContainerFSReadOnly(container)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +47,7 @@ func (daemon *Daemon) containerArchivePath(container *container.Container, path
 		}
 	}()
 
-	cfs, err := daemon.openContainerFS(container)
+	cfs, err := daemon.openContainerFSReadOnly(container)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -172,7 +172,7 @@ func (daemon *Daemon) containerCopy(container *container.Container, resource str
 		}
 	}()
 
-	cfs, err := daemon.openContainerFS(container)
+	cfs, err := daemon.openContainerFSReadOnly(container)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/distribution/reference"
@@ -142,9 +143,18 @@ func (daemon *Daemon) CreateImageFromContainer(ctx context.Context, name string,
 		return "", errdefs.Conflict(fmt.Errorf("You cannot commit container %s which is being removed", container.ID))
 	}
 
+	// Pause the container so the commit sees a consistent filesystem. The
+	// image service resumes the container through the SnapshotCaptured
+	// callback as soon as the filesystem has been read, so busy containers
+	// are only frozen for the capture and not for the whole commit.
+	unpause := func() {}
 	if c.Pause && !container.IsPaused() {
 		daemon.containerPause(container)
-		defer daemon.containerUnpause(container)
+		var once sync.Once
+		unpause = func() {
+			once.Do(func() { daemon.containerUnpause(container) })
+		}
+		defer unpause()
 	}
 
 	if c.Config == nil {
@@ -167,6 +177,7 @@ func (daemon *Daemon) CreateImageFromContainer(ctx context.Context, name string,
 		ContainerMountLabel: container.MountLabel,
 		ContainerOS:         container.OS,
 		ParentImageID:       string(container.ImageID),
+		SnapshotCaptured:    unpause,
 	})
 	if err != nil {
 		return "", err
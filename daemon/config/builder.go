@@ -60,6 +60,17 @@ type BuilderGCConfig struct {
 	DefaultKeepStorage string          `json:",omitempty"`
 }
 
+// BuilderCacheMountsConfig configures the `RUN --mount=type=cache` volumes
+// held by a buildkit builder.
+type BuilderCacheMountsConfig struct {
+	// SizeCaps maps a cache mount ID (declared with the `id` option of
+	// `RUN --mount=type=cache`, defaulting to the mount target path) to
+	// the maximum size the cache may grow to, as a human-readable size
+	// string (for example "2GB"). A cache that exceeds its cap is reset
+	// after the build that grew it.
+	SizeCaps map[string]string `json:",omitempty"`
+}
+
 // BuilderHistoryConfig contains history config for a buildkit builder
 type BuilderHistoryConfig struct {
 	MaxAge     int64 `json:",omitempty"`
@@ -72,9 +83,53 @@ type BuilderEntitlements struct {
 	SecurityInsecure *bool `json:"security-insecure,omitempty"`
 }
 
+// BuilderSourcePolicyRule is one rule of the builder's source policy; see
+// BuilderConfig.SourcePolicy.
+type BuilderSourcePolicyRule struct {
+	// Action is what happens to sources matched by Selector: "allow",
+	// "deny", or "convert" (rewrite into Updates).
+	Action string `json:",omitempty"`
+
+	// Selector matches the sources the rule applies to.
+	Selector BuilderSourcePolicySelector `json:",omitempty"`
+
+	// Updates describes what matched sources are rewritten into. Only
+	// used with the "convert" action.
+	Updates *BuilderSourcePolicyUpdate `json:",omitempty"`
+}
+
+// BuilderSourcePolicySelector matches build source identifiers.
+type BuilderSourcePolicySelector struct {
+	// Identifier of the source, including its scheme, for example
+	// "docker-image://docker.io/library/alpine:*" or "https://*".
+	Identifier string `json:",omitempty"`
+
+	// MatchType is how Identifier is matched against sources:
+	// "wildcard" (the default), "exact" or "regex".
+	MatchType string `json:",omitempty"`
+}
+
+// BuilderSourcePolicyUpdate describes what a source matched by a "convert"
+// rule is rewritten into.
+type BuilderSourcePolicyUpdate struct {
+	// Identifier the matched source is rewritten to, for example an
+	// internal registry mirror of the matched image.
+	Identifier string `json:",omitempty"`
+
+	// Attrs are source attributes to set on the rewritten source.
+	Attrs map[string]string `json:",omitempty"`
+}
+
 // BuilderConfig contains config for the builder
 type BuilderConfig struct {
-	GC           BuilderGCConfig       `json:",omitempty"`
-	Entitlements BuilderEntitlements   `json:",omitempty"`
-	History      *BuilderHistoryConfig `json:",omitempty"`
+	GC           BuilderGCConfig          `json:",omitempty"`
+	Entitlements BuilderEntitlements      `json:",omitempty"`
+	History      *BuilderHistoryConfig    `json:",omitempty"`
+	CacheMounts  BuilderCacheMountsConfig `json:",omitempty"`
+
+	// SourcePolicy lists source-policy rules that are applied, in order,
+	// to the sources of every build, whether or not the client provides
+	// a policy of its own. Rules can deny sources outright or convert
+	// them, for example rewrite docker.io images to an internal mirror.
+	SourcePolicy []BuilderSourcePolicyRule `json:",omitempty"`
 }
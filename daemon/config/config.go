@@ -8,15 +8,21 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
 
+	"github.com/containerd/containerd/identifiers"
 	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/registry"
+	units "github.com/docker/go-units"
 	"github.com/imdario/mergo"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -32,6 +38,10 @@ const (
 	// maximum number of uploads that
 	// may take place at a time.
 	DefaultMaxConcurrentUploads = 5
+	// DefaultMaxConcurrentUnpacks is the default value for
+	// maximum number of platforms that may be unpacked in
+	// parallel after a multi-platform pull.
+	DefaultMaxConcurrentUnpacks = 3
 	// DefaultDownloadAttempts is the default value for
 	// maximum number of attempts that
 	// may take place at a time for each pull when the connection is lost.
@@ -121,6 +131,27 @@ type NetworkConfig struct {
 	DefaultNetworkOpts map[string]map[string]string `json:"default-network-opts,omitempty"`
 }
 
+// SnapshotterOpts configures snapshotter-specific behavior for unpacking
+// image layers with the containerd image store.
+type SnapshotterOpts struct {
+	// OverlayVolatile unpacks layers into volatile overlayfs snapshots,
+	// skipping sync on apply. This speeds up pulls at the cost of having
+	// to re-pull images that were being unpacked during an unclean
+	// shutdown.
+	OverlayVolatile bool `json:"overlay-volatile,omitempty"`
+
+	// IDMappedMounts remaps layer ownership with idmapped mounts when
+	// user-namespace remapping is enabled, instead of chowning a copy of
+	// each layer per mapping.
+	IDMappedMounts bool `json:"idmapped-mounts,omitempty"`
+
+	// Labels are applied to every snapshot created during unpack, for
+	// snapshotter-specific tuning that has no dedicated option. Only
+	// "containerd.io/snapshot/"-prefixed labels are passed through to the
+	// snapshotter.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
 // TLSOptions defines TLS configuration for the daemon server.
 // It includes json tags to deserialize configuration from a file
 // using the same names that the flags in the command line use.
@@ -144,6 +175,9 @@ type DNSConfig struct {
 // using the same names that the flags in the command line use.
 type CommonConfig struct {
 	AuthorizationPlugins  []string `json:"authorization-plugins,omitempty"` // AuthorizationPlugins holds list of authorization plugins
+	ImagePolicyPlugins    []string `json:"image-policy-plugins,omitempty"`  // ImagePolicyPlugins holds list of plugins consulted before a pull is admitted
+	ImageScanPlugin       string   `json:"image-scan-plugin,omitempty"`     // ImageScanPlugin names the plugin that scans images for vulnerabilities
+	ScanOnPull            bool     `json:"scan-on-pull,omitempty"`          // ScanOnPull scans every pulled image with the image scan plugin
 	AutoRestart           bool     `json:"-"`
 	DisableBridge         bool     `json:"-"`
 	ExecOptions           []string `json:"exec-opts,omitempty"`
@@ -177,6 +211,99 @@ type CommonConfig struct {
 	// may take place at a time for each push.
 	MaxDownloadAttempts int `json:"max-download-attempts,omitempty"`
 
+	// MaxConcurrentUnpacks is the maximum number of platforms that may be
+	// unpacked in parallel after a multi-platform pull. It has no effect
+	// on single-platform pulls, whose layers unpack in chain order while
+	// the pull downloads, with decompression overlapping apply. Only used
+	// with the containerd image store.
+	MaxConcurrentUnpacks int `json:"max-concurrent-unpacks,omitempty"`
+
+	// MaxImagePullSize rejects pulls whose manifests declare a total
+	// compressed size larger than this value (e.g. "10GB"). Empty means
+	// no limit. Only enforced with the containerd image store enabled.
+	MaxImagePullSize string `json:"max-image-pull-size,omitempty"`
+
+	// RegistryMaxPullSize overrides MaxImagePullSize for specific registry
+	// hosts, keyed by hostname (e.g. "registry.example.com").
+	RegistryMaxPullSize map[string]string `json:"registry-max-pull-size,omitempty"`
+
+	// RegistrySnapshotter selects the snapshotter that images pulled from
+	// specific registry hosts are unpacked into, keyed by hostname. Hosts
+	// not listed use the default snapshotter. Only used with the containerd
+	// image store.
+	RegistrySnapshotter map[string]string `json:"registry-snapshotter,omitempty"`
+
+	// PushChunkSize makes pushes upload layer blobs of at least this size
+	// (e.g. "100MB") in resumable chunks, so an interrupted upload continues
+	// from the last acknowledged chunk instead of restarting. Empty disables
+	// chunked uploads. Only used with the containerd image store.
+	PushChunkSize string `json:"push-chunk-size,omitempty"`
+
+	// PushSigningKey is the path of a PEM-encoded private key. When set,
+	// every pushed manifest is signed with it and the signature is uploaded
+	// to the target repository in the cosign format. Only used with the
+	// containerd image store.
+	PushSigningKey string `json:"push-signing-key,omitempty"`
+
+	// SnapshotterOpts configures snapshotter-specific unpack behavior.
+	// Only used with the containerd image store.
+	SnapshotterOpts SnapshotterOpts `json:"snapshotter-opts,omitempty"`
+
+	// DistributionAccelerators are URLs of peer-to-peer blob sources (e.g.
+	// a Dragonfly or Spegel peer) that pulls are redirected through before
+	// falling back to the origin registry. Only used with the containerd
+	// image store.
+	DistributionAccelerators []string `json:"distribution-accelerators,omitempty"`
+
+	// ProtectedTags are shell-glob patterns of image tags that cannot be
+	// retagged, and can only be deleted with force. A pattern containing a
+	// "/" or ":" is matched against the whole reference, any other pattern
+	// against the tag alone (e.g. "prod-*").
+	ProtectedTags []string `json:"protected-tags,omitempty"`
+
+	// RegistryMirrorAddr, when set, is the address the daemon listens on to
+	// serve a read-only Registry API backed by its image store, so
+	// co-located hosts can use this daemon as a mirror of the images it
+	// already holds. Only used with the containerd image store.
+	RegistryMirrorAddr string `json:"registry-mirror-addr,omitempty"`
+
+	// ImageStoreBackend selects the image-store backend by its registered
+	// name (e.g. "graphdriver" or "containerd"). When empty, the backend
+	// is derived from the "containerd-snapshotter" feature flag.
+	ImageStoreBackend string `json:"image-store-backend,omitempty"`
+
+	// DanglingImagesPruneAge, when set to a non-zero duration (e.g. "72h"),
+	// makes the daemon periodically remove dangling images that are older
+	// than the given age and not used by any container. Only used with the
+	// containerd image store.
+	DanglingImagesPruneAge string `json:"dangling-images-prune-age,omitempty"`
+
+	// MaxImageAge, when set to a non-zero duration (e.g. "720h"), makes
+	// the daemon check at container create how long ago the image was
+	// pulled or last validated at the registry. Containers from images
+	// older than the given age are warned about or, with MaxImageAgeAction
+	// "refuse", not created. Only used with the containerd image store.
+	MaxImageAge string `json:"max-image-age,omitempty"`
+
+	// MaxImageAgeAction decides what happens when an image is older than
+	// MaxImageAge: "warn" (the default) logs a warning, "refuse" fails
+	// container creation.
+	MaxImageAgeAction string `json:"max-image-age-action,omitempty"`
+
+	// SharedContentStore is the path to a read-only containerd content
+	// store directory (the one containing "blobs"), typically the host's
+	// store mounted into a nested daemon. Blobs found there are copied
+	// locally instead of being downloaded during a pull. Only used with
+	// the containerd image store.
+	SharedContentStore string `json:"shared-content-store,omitempty"`
+
+	// RegistryCredentialHelpers maps registry hosts to the name of a
+	// docker credential helper ("docker-credential-<name>" must be on the
+	// daemon's PATH) that supplies credentials for that host. The helpers
+	// are consulted for pulls and for build base images when the client
+	// provides no credentials of its own.
+	RegistryCredentialHelpers map[string]string `json:"registry-credential-helpers,omitempty"`
+
 	// ShutdownTimeout is the timeout value (in seconds) the daemon will wait for the container
 	// to stop when daemon is being shutdown
 	ShutdownTimeout int `json:"shutdown-timeout,omitempty"`
@@ -241,15 +368,57 @@ type CommonConfig struct {
 
 	Builder BuilderConfig `json:"builder,omitempty"`
 
+	ImageGC ImageGCConfig `json:"image-gc,omitempty"`
+
+	// PreloadImages lists image references that must be present on the
+	// daemon. Missing images are pulled at startup; references that
+	// include a digest are verified against it. The status of each image
+	// is reported in the info endpoint.
+	PreloadImages []string `json:"preload-images,omitempty"`
+
 	ContainerdNamespace       string `json:"containerd-namespace,omitempty"`
 	ContainerdPluginNamespace string `json:"containerd-plugin-namespace,omitempty"`
 
+	// ImageNamespaces maps TLS client identities (the Common Name of the
+	// client certificate) to dedicated containerd namespaces. API requests
+	// authenticated with a mapped identity are scoped to that namespace
+	// and only see its images; content blobs are still shared between
+	// namespaces. Requires experimental features and the containerd image
+	// store.
+	ImageNamespaces map[string]string `json:"image-namespaces,omitempty"`
+
+	// ContainerdTLS configures mutual TLS for the gRPC connection to
+	// containerd when ContainerdAddr is a tcp:// address. It is only
+	// valid for remote (tcp) addresses; local socket connections are
+	// never encrypted. The BuildKit worker of the containerd image store
+	// cannot use a remote containerd instance; builds require a local
+	// containerd socket.
+	ContainerdTLS *TLSOptions `json:"containerd-tls,omitempty"`
+
+	// Tracing configures where the daemon exports its OpenTelemetry trace
+	// spans (image operations, builds and the containerd gRPC client).
+	// The standard OTEL_* environment variables take precedence when set.
+	Tracing TracingConfig `json:"tracing,omitempty"`
+
 	DefaultRuntime string `json:"default-runtime,omitempty"`
 
 	// CDISpecDirs is a list of directories in which CDI specifications can be found.
 	CDISpecDirs []string `json:"cdi-spec-dirs,omitempty"`
 }
 
+// TracingConfig configures the OTLP exporter for the daemon's OpenTelemetry
+// trace spans.
+type TracingConfig struct {
+	// Endpoint is the OTLP endpoint trace spans are exported to, e.g.
+	// "https://collector:4317". When empty, no exporter is configured
+	// unless the OTEL_* environment variables are set.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Protocol selects the OTLP transport: "grpc" (the default) or
+	// "http/protobuf".
+	Protocol string `json:"protocol,omitempty"`
+}
+
 // Proxies holds the proxies that are configured for the daemon.
 type Proxies struct {
 	HTTPProxy  string `json:"http-proxy,omitempty"`
@@ -279,6 +448,7 @@ func New() (*Config, error) {
 			MaxConcurrentDownloads: DefaultMaxConcurrentDownloads,
 			MaxConcurrentUploads:   DefaultMaxConcurrentUploads,
 			MaxDownloadAttempts:    DefaultDownloadAttempts,
+			MaxConcurrentUnpacks:   DefaultMaxConcurrentUnpacks,
 			BridgeConfig:           BridgeConfig{MTU: DefaultNetworkMtu},
 			NetworkConfig: NetworkConfig{
 				NetworkControlPlaneMTU: DefaultNetworkMtu,
@@ -635,11 +805,137 @@ func Validate(config *Config) error {
 	if config.MaxDownloadAttempts < 0 {
 		return errors.Errorf("invalid max download attempts: %d", config.MaxDownloadAttempts)
 	}
+	if config.MaxConcurrentUnpacks < 0 {
+		return errors.Errorf("invalid max concurrent unpacks: %d", config.MaxConcurrentUnpacks)
+	}
+	for k := range config.SnapshotterOpts.Labels {
+		if !strings.HasPrefix(k, "containerd.io/snapshot/") {
+			return errors.Errorf(`invalid snapshotter label %q: only "containerd.io/snapshot/"-prefixed labels are passed to the snapshotter`, k)
+		}
+	}
+	for _, accelerator := range config.DistributionAccelerators {
+		if u, err := url.Parse(accelerator); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.Errorf("invalid distribution accelerator %q: must be an http(s) URL", accelerator)
+		}
+	}
+	if config.RegistryMirrorAddr != "" {
+		if _, _, err := net.SplitHostPort(config.RegistryMirrorAddr); err != nil {
+			return errors.Wrapf(err, "invalid registry mirror address %s", config.RegistryMirrorAddr)
+		}
+	}
+	for _, pattern := range config.ProtectedTags {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Errorf("invalid protected tag pattern %q", pattern)
+		}
+	}
+	if config.ContainerdTLS != nil {
+		if !strings.HasPrefix(config.ContainerdAddr, "tcp://") {
+			return errors.New(`option "containerd-tls" is only valid with a tcp:// containerd address`)
+		}
+		if (config.ContainerdTLS.CertFile == "") != (config.ContainerdTLS.KeyFile == "") {
+			return errors.New(`option "containerd-tls" requires both a certificate and a key for mutual TLS, or neither`)
+		}
+	}
+	switch config.Tracing.Protocol {
+	case "", "grpc", "http/protobuf":
+	default:
+		return errors.Errorf(`invalid tracing protocol %q: must be "grpc" or "http/protobuf"`, config.Tracing.Protocol)
+	}
+	if config.Tracing.Protocol != "" && config.Tracing.Endpoint == "" {
+		return errors.New(`option "tracing" requires an endpoint when a protocol is set`)
+	}
+	if config.ScanOnPull && config.ImageScanPlugin == "" {
+		return errors.New(`option "scan-on-pull" requires an "image-scan-plugin" to be configured`)
+	}
+	if len(config.ImageNamespaces) > 0 && !config.Experimental {
+		return errors.New(`option "image-namespaces" is only supported with experimental features enabled`)
+	}
+	for identity, ns := range config.ImageNamespaces {
+		if identity == "" {
+			return errors.New(`option "image-namespaces" requires a non-empty client identity`)
+		}
+		if err := identifiers.Validate(ns); err != nil {
+			return errors.Wrapf(err, "invalid image namespace %q for client %s", ns, identity)
+		}
+	}
+	if config.DanglingImagesPruneAge != "" {
+		age, err := time.ParseDuration(config.DanglingImagesPruneAge)
+		if err != nil {
+			return errors.Wrapf(err, "invalid dangling images prune age %s", config.DanglingImagesPruneAge)
+		}
+		if age <= 0 {
+			return errors.Errorf("invalid dangling images prune age %s: must be positive", config.DanglingImagesPruneAge)
+		}
+	}
+	if config.MaxImageAge != "" {
+		age, err := time.ParseDuration(config.MaxImageAge)
+		if err != nil {
+			return errors.Wrapf(err, "invalid max image age %s", config.MaxImageAge)
+		}
+		if age <= 0 {
+			return errors.Errorf("invalid max image age %s: must be positive", config.MaxImageAge)
+		}
+	}
+	switch config.MaxImageAgeAction {
+	case "", "warn", "refuse":
+	default:
+		return errors.Errorf(`invalid max image age action %q: must be "warn" or "refuse"`, config.MaxImageAgeAction)
+	}
+	if config.MaxImageAgeAction != "" && config.MaxImageAge == "" {
+		return errors.New(`option "max-image-age-action" requires "max-image-age" to be set`)
+	}
+	if config.SharedContentStore != "" && !filepath.IsAbs(config.SharedContentStore) {
+		return errors.Errorf("invalid shared content store path %s: must be an absolute path", config.SharedContentStore)
+	}
+	for host, helper := range config.RegistryCredentialHelpers {
+		if host == "" {
+			return errors.New(`option "registry-credential-helpers" requires a non-empty registry host`)
+		}
+		if helper == "" || strings.ContainsAny(helper, `/\`) {
+			return errors.Errorf("invalid credential helper %q for registry %s: must be the name suffix of a docker-credential-<name> program", helper, host)
+		}
+	}
 
 	if _, err := ParseGenericResources(config.NodeGenericResources); err != nil {
 		return err
 	}
 
+	if config.ImageGC.Interval != "" {
+		if _, err := time.ParseDuration(config.ImageGC.Interval); err != nil {
+			return errors.Wrapf(err, "invalid image-gc interval: %s", config.ImageGC.Interval)
+		}
+	}
+	if config.ImageGC.DiskUsageThreshold != "" {
+		if _, err := units.RAMInBytes(config.ImageGC.DiskUsageThreshold); err != nil {
+			return errors.Wrapf(err, "invalid image-gc disk-usage threshold: %s", config.ImageGC.DiskUsageThreshold)
+		}
+	}
+	if config.ImageGC.KeepStorage != "" {
+		if _, err := units.RAMInBytes(config.ImageGC.KeepStorage); err != nil {
+			return errors.Wrapf(err, "invalid image-gc keep-storage: %s", config.ImageGC.KeepStorage)
+		}
+	}
+	for _, img := range config.PreloadImages {
+		if _, err := reference.ParseNormalizedNamed(img); err != nil {
+			return errors.Wrapf(err, "invalid preload-images reference %s", img)
+		}
+	}
+	if config.MaxImagePullSize != "" {
+		if _, err := units.RAMInBytes(config.MaxImagePullSize); err != nil {
+			return errors.Wrapf(err, "invalid max-image-pull-size: %s", config.MaxImagePullSize)
+		}
+	}
+	for host, size := range config.RegistryMaxPullSize {
+		if _, err := units.RAMInBytes(size); err != nil {
+			return errors.Wrapf(err, "invalid registry-max-pull-size for %s: %s", host, size)
+		}
+	}
+	if config.PushChunkSize != "" {
+		if _, err := units.RAMInBytes(config.PushChunkSize); err != nil {
+			return errors.Wrapf(err, "invalid push-chunk-size: %s", config.PushChunkSize)
+		}
+	}
+
 	for _, h := range config.Hosts {
 		if _, err := opts.ValidateHost(h); err != nil {
 			return err
@@ -0,0 +1,29 @@
+package config
+
+// ImageGCConfig contains automatic garbage-collection configuration for the
+// image store, similar to the builder's GC policies. It only takes effect
+// when the containerd image store is enabled.
+type ImageGCConfig struct {
+	// Enabled turns the periodic image garbage collector on.
+	Enabled bool `json:",omitempty"`
+
+	// Interval between collection runs, in time.Duration syntax (e.g.
+	// "1h"). Defaults to one hour when empty.
+	Interval string `json:",omitempty"`
+
+	// Filters restrict which images are collected, using the same syntax
+	// as "docker image prune --filter" (e.g. "until=168h", "label!=keep").
+	// When no "dangling" filter is given only dangling images are removed.
+	Filters []string `json:",omitempty"`
+
+	// DiskUsageThreshold skips collection while the combined layer usage
+	// is below this size (e.g. "50GB"). When empty, collection runs on
+	// every interval.
+	DiskUsageThreshold string `json:",omitempty"`
+
+	// KeepStorage is the usage target for evictions (e.g. "40GB"). When
+	// collection was triggered by DiskUsageThreshold, unreferenced images
+	// are evicted oldest-first until usage falls below this size. When
+	// empty, no evictions beyond the regular prune are performed.
+	KeepStorage string `json:",omitempty"`
+}
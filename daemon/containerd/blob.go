@@ -0,0 +1,29 @@
+package containerd
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ReadBlob opens the blob with the given digest in the content store for
+// reading and returns its size. The caller is responsible for closing the
+// returned reader.
+func (i *ImageService) ReadBlob(ctx context.Context, dgst digest.Digest) (io.ReadCloser, int64, error) {
+	ra, err := i.client.ContentStore().ReaderAt(ctx, ocispec.Descriptor{Digest: dgst})
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, 0, errdefs.NotFound(errors.Wrapf(err, "blob %s not found", dgst))
+		}
+		return nil, 0, errdefs.System(errors.Wrapf(err, "failed to open blob %s", dgst))
+	}
+
+	return ioutils.NewReadCloserWrapper(content.NewReader(ra), ra.Close), ra.Size(), nil
+}
@@ -0,0 +1,98 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/docker/docker/errdefs"
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	// configCacheSize is the number of image config blobs kept in memory.
+	configCacheSize = 256
+
+	// configCacheMaxBlobSize bounds the size of blobs the cache will hold.
+	// Image configs are typically a few kilobytes; anything bigger is not
+	// worth keeping in memory.
+	configCacheMaxBlobSize = 1 << 20 // 1 MiB
+)
+
+// configCache is an LRU of raw image config blobs keyed by digest. Listing
+// and inspecting images read the same few configs over and over; keeping
+// them in memory saves a containerd round trip per image per call. Content
+// is addressed by digest so entries can never go stale, but they are still
+// dropped when the matching content is deleted (see watchContainerdEvents)
+// so the cache only holds live content.
+type configCache struct {
+	mu  sync.Mutex
+	lru *simplelru.LRU
+}
+
+func newConfigCache() *configCache {
+	// NewLRU only fails for non-positive sizes.
+	lru, _ := simplelru.NewLRU(configCacheSize, nil)
+	return &configCache{lru: lru}
+}
+
+// get is safe to call on a nil cache, so the zero ImageService used in tests
+// behaves like one with caching disabled.
+func (c *configCache) get(dgst digest.Digest) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.lru.Get(dgst); ok {
+		return v.([]byte), true
+	}
+	return nil, false
+}
+
+func (c *configCache) add(dgst digest.Digest, data []byte) {
+	if c == nil || int64(len(data)) > configCacheMaxBlobSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Add(dgst, data)
+}
+
+func (c *configCache) remove(dgst digest.Digest) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Remove(dgst)
+}
+
+// readConfig reads content pointed by the descriptor and unmarshals it into a
+// specified output, serving repeated reads of the same digest from the
+// in-memory config cache.
+func (i *ImageService) readConfig(ctx context.Context, store content.Provider, desc ocispec.Descriptor, out interface{}) error {
+	data, ok := i.configCache.get(desc.Digest)
+	if !ok {
+		var err error
+		data, err = content.ReadBlob(ctx, store, desc)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to read config content")
+			if cerrdefs.IsNotFound(err) {
+				return errdefs.NotFound(err)
+			}
+			return err
+		}
+		i.configCache.add(desc.Digest, data)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return errdefs.System(errors.Wrapf(err, "could not deserialize image config"))
+	}
+	return nil
+}
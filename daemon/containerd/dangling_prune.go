@@ -0,0 +1,89 @@
+package containerd
+
+import (
+	"context"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/container"
+)
+
+const (
+	danglingPruneMinInterval = time.Minute
+	danglingPruneMaxInterval = time.Hour
+)
+
+// pruneDanglingLoop periodically removes dangling images that are older than
+// the configured danglingPruneAge and not used by any container. Dangling
+// images pile up after repeated builds and retags; the loop keeps them from
+// accumulating without an operator having to run prune by hand.
+func (i *ImageService) pruneDanglingLoop(ctx context.Context) {
+	// Check a few times per configured age so images don't overstay it by
+	// much, but don't hammer containerd for very short ages.
+	interval := i.danglingPruneAge / 4
+	if interval < danglingPruneMinInterval {
+		interval = danglingPruneMinInterval
+	}
+	if interval > danglingPruneMaxInterval {
+		interval = danglingPruneMaxInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if err := i.pruneExpiredDanglingImages(ctx); err != nil && ctx.Err() == nil {
+			log.G(ctx).WithError(err).Warn("failed to prune expired dangling images")
+		}
+	}
+}
+
+// pruneExpiredDanglingImages deletes dangling images older than the
+// configured age, skipping those still used by a container. Deletions are
+// logged to the image event stream like a manual delete would be.
+func (i *ImageService) pruneExpiredDanglingImages(ctx context.Context) error {
+	// Don't race a user-initiated prune; the next tick will catch up.
+	if !i.pruneRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer i.pruneRunning.Store(false)
+
+	imgs, err := i.client.ImageService().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-i.danglingPruneAge)
+	for _, img := range imgs {
+		if !isDanglingImage(img) || img.CreatedAt.After(cutoff) {
+			continue
+		}
+
+		target := img.Target.Digest
+		if ctr := i.containers.First(func(c *container.Container) bool {
+			return c.ImageID.Digest() == target
+		}); ctr != nil {
+			continue
+		}
+
+		if err := i.client.ImageService().Delete(ctx, img.Name, containerdimages.SynchronousDelete()); err != nil {
+			if !cerrdefs.IsNotFound(err) {
+				log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to delete expired dangling image")
+			}
+			continue
+		}
+
+		log.G(ctx).WithFields(log.Fields{
+			"image": img.Name,
+			"age":   time.Since(img.CreatedAt).Round(time.Second),
+		}).Info("removed expired dangling image")
+		i.LogImageEvent(target.String(), target.String(), "delete")
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/daemon/containerd/testutil"
+	daemonevents "github.com/docker/docker/daemon/events"
+	"github.com/docker/docker/image"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+// fakeImageService returns an ImageService backed by the in-memory containerd
+// client from the testutil package.
+func fakeImageService(t *testing.T) *ImageService {
+	client, err := testutil.NewClient("native")
+	assert.NilError(t, err)
+
+	return &ImageService{
+		client:        client,
+		containers:    container.NewMemoryStore(),
+		snapshotter:   "native",
+		eventsService: daemonevents.New(),
+		imageMounts:   map[string]string{},
+	}
+}
+
+func TestPruneExpiredDanglingImages(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+	i.danglingPruneAge = time.Hour
+
+	is8 := i.client.ImageService()
+	newImage := func(name, seed string, createdAt time.Time) containerdimages.Image {
+		img, err := is8.Create(ctx, containerdimages.Image{
+			Name: name,
+			Target: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromString(seed),
+				Size:      int64(len(seed)),
+			},
+			CreatedAt: createdAt,
+		})
+		assert.NilError(t, err)
+		return img
+	}
+
+	old := time.Now().Add(-24 * time.Hour)
+	expired := newImage(danglingImageName(digest.FromString("expired")), "expired", old)
+	fresh := newImage(danglingImageName(digest.FromString("fresh")), "fresh", time.Now())
+	tagged := newImage("docker.io/library/busybox:latest", "tagged", old)
+	used := newImage(danglingImageName(digest.FromString("used")), "used", old)
+
+	ctr := &container.Container{ID: "something"}
+	ctr.ImageID = image.ID(used.Target.Digest)
+	i.containers.Add(ctr.ID, ctr)
+
+	assert.NilError(t, i.pruneExpiredDanglingImages(ctx))
+
+	_, err := is8.Get(ctx, expired.Name)
+	assert.Check(t, cerrdefs.IsNotFound(err), "expired dangling image should have been removed")
+
+	for _, name := range []string{fresh.Name, tagged.Name, used.Name} {
+		_, err := is8.Get(ctx, name)
+		assert.Check(t, err, "image %s should have been kept", name)
+	}
+}
+
+func TestPruneExpiredDanglingImagesSkipsWhenPruneRunning(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+	i.danglingPruneAge = time.Hour
+
+	expired, err := i.client.ImageService().Create(ctx, containerdimages.Image{
+		Name: danglingImageName(digest.FromString("expired")),
+		Target: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromString("expired"),
+			Size:      7,
+		},
+		CreatedAt: time.Now().Add(-24 * time.Hour),
+	})
+	assert.NilError(t, err)
+
+	i.pruneRunning.Store(true)
+	assert.NilError(t, i.pruneExpiredDanglingImages(ctx))
+	i.pruneRunning.Store(false)
+
+	img, err := i.client.ImageService().Get(ctx, expired.Name)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(img.Name, expired.Name))
+}
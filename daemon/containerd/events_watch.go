@@ -0,0 +1,99 @@
+package containerd
+
+import (
+	"context"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/typeurl"
+	"github.com/docker/distribution/reference"
+)
+
+// selfEventWindow is how long an image name or ID logged by the daemon's own
+// operations suppresses the matching containerd event. It only needs to span
+// the delivery delay of the containerd event stream.
+const selfEventWindow = 5 * time.Second
+
+// watchContainerdEvents subscribes to containerd image and content events
+// and keeps daemon state in sync with changes made by other clients of the
+// shared store (ctr, nerdctl, CRI): cached configs are dropped when content
+// is deleted, and image records created, updated or removed externally are
+// surfaced as docker image events so listeners and listings stay consistent.
+func (i *ImageService) watchContainerdEvents(ctx context.Context) {
+	eventsCh, errCh := i.client.Subscribe(ctx,
+		`topic~="/images/"`,
+		`topic=="/content/delete"`,
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-eventsCh:
+			if ev.Event == nil {
+				continue
+			}
+			v, err := typeurl.UnmarshalAny(ev.Event)
+			if err != nil {
+				log.G(ctx).WithError(err).WithField("topic", ev.Topic).Warn("failed to unmarshal containerd event")
+				continue
+			}
+			switch e := v.(type) {
+			case *apievents.ContentDelete:
+				i.configCache.remove(e.Digest)
+			case *apievents.ImageCreate:
+				i.invalidateImageCount()
+				go i.logNonDaemonImageEvent(ctx, e.Name, "create")
+			case *apievents.ImageUpdate:
+				go i.logNonDaemonImageEvent(ctx, e.Name, "update")
+			case *apievents.ImageDelete:
+				i.invalidateImageCount()
+				go i.logNonDaemonImageEvent(ctx, e.Name, "delete")
+			}
+		case err := <-errCh:
+			if err != nil && ctx.Err() == nil {
+				log.G(ctx).WithError(err).Warn("containerd event stream failed; external image changes will not be reflected until restart")
+			}
+			i.imageCountEventsLost.Store(true)
+			i.invalidateImageCount()
+			return
+		}
+	}
+}
+
+// logNonDaemonImageEvent emits a docker image event for a change made by
+// another containerd client. Operations performed by the daemon itself
+// already produced a docker event, so events matching a recently logged
+// image name or ID are dropped. The containerd event can overtake the
+// daemon's own event log, so wait out half the suppression window before
+// deciding.
+func (i *ImageService) logNonDaemonImageEvent(ctx context.Context, name, action string) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(selfEventWindow / 2):
+	}
+
+	candidates := []string{name}
+	if ref, err := reference.ParseNormalizedNamed(name); err == nil {
+		candidates = append(candidates, reference.FamiliarString(ref))
+	}
+	for _, c := range candidates {
+		if i.isRecentSelfEvent(c) {
+			return
+		}
+	}
+
+	imageID := name
+	if img, err := i.client.ImageService().Get(ctx, name); err == nil {
+		imageID = img.Target.Digest.String()
+		if i.isRecentSelfEvent(imageID) {
+			return
+		}
+	} else if !cerrdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("image", name).Debug("failed to resolve image from containerd event")
+	}
+
+	i.LogImageEvent(imageID, name, action)
+}
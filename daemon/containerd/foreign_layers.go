@@ -0,0 +1,112 @@
+package containerd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes"
+	"github.com/docker/docker/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// withForeignURLsFetcher wraps resolver so that layers carrying external
+// URLs in their descriptor (foreign layers, e.g. Windows base images) are
+// downloaded from those URLs with the daemon's proxy and TLS settings for
+// the URL's host. containerd's own fetcher tries descriptor URLs too, but
+// does so with the default HTTP client, ignoring the daemon's registry
+// configuration; it stays in place as the fallback when none of the URLs
+// work. The fetched content is still verified against the descriptor's
+// digest when it is committed to the content store.
+func (i *ImageService) withForeignURLsFetcher(resolver remotes.Resolver) remotes.Resolver {
+	return &foreignURLsResolver{Resolver: resolver, registryService: i.registryService}
+}
+
+type foreignURLsResolver struct {
+	remotes.Resolver
+	registryService RegistryConfigProvider
+}
+
+func (r *foreignURLsResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	fetcher, err := r.Resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &foreignURLsFetcher{inner: fetcher, registryService: r.registryService}, nil
+}
+
+type foreignURLsFetcher struct {
+	inner           remotes.Fetcher
+	registryService RegistryConfigProvider
+}
+
+func (f *foreignURLsFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	for _, us := range desc.URLs {
+		rc, err := f.fetchURL(ctx, us)
+		if err != nil {
+			log.G(ctx).WithError(err).WithFields(log.Fields{
+				"digest": desc.Digest,
+				"url":    us,
+			}).Debug("failed to fetch layer from foreign URL")
+			continue
+		}
+		return rc, nil
+	}
+	// No URLs, or none of them worked; the registry path, which retries the
+	// URLs itself as a last resort, may still succeed.
+	return f.inner.Fetch(ctx, desc)
+}
+
+func (f *foreignURLsFetcher) fetchURL(ctx context.Context, us string) (io.ReadCloser, error) {
+	u, err := url.Parse(us)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q in foreign layer URL", u.Scheme)
+	}
+
+	client, err := f.clientForHost(u.Host)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// clientForHost builds an HTTP client for the given host, applying the
+// daemon's proxy and insecure-registry configuration the same way the
+// registry hosts themselves get it.
+func (f *foreignURLsFetcher) clientForHost(host string) (*http.Client, error) {
+	insecure := f.registryService.IsInsecureRegistry(host)
+	if proxy, ok := f.registryService.ProxyFor(host); ok {
+		transport, err := registry.NewProxiedTransport(host, proxy, !insecure)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Client{Transport: transport}, nil
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if insecure {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &http.Client{Transport: transport}, nil
+}
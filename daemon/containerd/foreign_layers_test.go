@@ -0,0 +1,98 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type fakeRegistryConfig struct {
+	insecure map[string]bool
+	proxies  map[string]string
+}
+
+func (f fakeRegistryConfig) IsInsecureRegistry(host string) bool { return f.insecure[host] }
+
+func (f fakeRegistryConfig) AllowsNondistributableArtifacts(host string) bool { return false }
+
+func (f fakeRegistryConfig) ResolveRepository(name reference.Named) (*registry.RepositoryInfo, error) {
+	return nil, nil
+}
+
+func (f fakeRegistryConfig) CheckRegistryIsAllowed(host string) error { return nil }
+
+func (f fakeRegistryConfig) ProxyFor(host string) (string, bool) {
+	proxy, ok := f.proxies[host]
+	return proxy, ok
+}
+
+type fetcherFunc func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	return f(ctx, desc)
+}
+
+func TestForeignURLsFetcher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/layer.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "foreign layer data")
+	}))
+	defer srv.Close()
+
+	innerCalled := false
+	fetcher := &foreignURLsFetcher{
+		inner: fetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+			innerCalled = true
+			return io.NopCloser(strings.NewReader("from the registry")), nil
+		}),
+		registryService: fakeRegistryConfig{},
+	}
+
+	read := func(rc io.ReadCloser) string {
+		t.Helper()
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		assert.NilError(t, err)
+		return string(data)
+	}
+
+	t.Run("fetches from the first working url", func(t *testing.T) {
+		innerCalled = false
+		rc, err := fetcher.Fetch(context.Background(), ocispec.Descriptor{
+			URLs: []string{srv.URL + "/missing", srv.URL + "/layer.tar.gz"},
+		})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(read(rc), "foreign layer data"))
+		assert.Check(t, !innerCalled)
+	})
+
+	t.Run("falls back to the registry when no url works", func(t *testing.T) {
+		innerCalled = false
+		rc, err := fetcher.Fetch(context.Background(), ocispec.Descriptor{
+			URLs: []string{srv.URL + "/missing", "ftp://example.com/layer"},
+		})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(read(rc), "from the registry"))
+		assert.Check(t, innerCalled)
+	})
+
+	t.Run("regular layers go straight to the registry", func(t *testing.T) {
+		innerCalled = false
+		rc, err := fetcher.Fetch(context.Background(), ocispec.Descriptor{})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(read(rc), "from the registry"))
+		assert.Check(t, innerCalled)
+	})
+}
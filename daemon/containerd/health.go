@@ -0,0 +1,86 @@
+package containerd
+
+import (
+	"context"
+	"time"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errImageServiceUnavailable is returned for image operations attempted while
+// the connection to containerd is down. The gRPC channel reconnects
+// automatically once containerd is serving again.
+var errImageServiceUnavailable = errdefs.Unavailable(errors.New("image service is unavailable: cannot connect to containerd"))
+
+const (
+	// healthCheckInterval is how often the connection to containerd is
+	// checked while it is healthy.
+	healthCheckInterval = 30 * time.Second
+	// healthCheckRetryInterval is how often a reconnect is attempted after
+	// the connection was detected as down.
+	healthCheckRetryInterval = time.Second
+	// healthCheckTimeout bounds a single health-check request.
+	healthCheckTimeout = 10 * time.Second
+)
+
+// monitorConnectionHealth periodically checks that containerd is serving and
+// records the result. A dropped connection (for example when containerd is
+// restarted) is retried more aggressively until it comes back, so operations
+// fail fast with a typed error instead of opaque transport errors in the
+// meantime.
+func (i *ImageService) monitorConnectionHealth(ctx context.Context) {
+	interval := healthCheckInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		serving, err := i.client.IsServing(checkCtx)
+		cancel()
+
+		healthy := err == nil && serving
+		if wasHealthy := i.connectionHealthy.Swap(healthy); wasHealthy != healthy {
+			if healthy {
+				log.G(ctx).Info("connection to containerd restored")
+			} else {
+				log.G(ctx).WithError(err).Error("connection to containerd lost, image operations will fail until it is restored")
+			}
+		}
+
+		if healthy {
+			interval = healthCheckInterval
+		} else {
+			interval = healthCheckRetryInterval
+		}
+	}
+}
+
+// ensureConnection fails fast with errImageServiceUnavailable when the
+// containerd connection is known to be down. It doesn't perform a
+// health-check request itself, so it is cheap to call at the start of every
+// operation.
+func (i *ImageService) ensureConnection() error {
+	if !i.connectionHealthy.Load() {
+		return errImageServiceUnavailable
+	}
+	return nil
+}
+
+// translateConnectionError converts gRPC transport errors caused by a downed
+// containerd into errImageServiceUnavailable, leaving other errors untouched.
+func translateConnectionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) == codes.Unavailable {
+		return errors.Wrap(errImageServiceUnavailable, err.Error())
+	}
+	return err
+}
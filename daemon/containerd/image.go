@@ -2,6 +2,7 @@ package containerd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
@@ -9,20 +10,24 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/containerd/containerd/content"
 	cerrdefs "github.com/containerd/containerd/errdefs"
 	containerdimages "github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/log"
 	cplatforms "github.com/containerd/containerd/platforms"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
 	imagetype "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/daemon/images"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	imagespec "github.com/docker/docker/image/spec/specs-go/v1"
 	"github.com/docker/docker/pkg/platforms"
+	"github.com/moby/buildkit/util/attestation"
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -35,9 +40,20 @@ func (i *ImageService) GetImage(ctx context.Context, refOrID string, options ima
 		return nil, err
 	}
 
+	if artifactType, err := i.imageArtifactType(ctx, desc); err == nil && artifactType != "" {
+		if !options.Details {
+			return nil, errdefs.InvalidParameter(errors.Errorf("%s is an OCI artifact of type %q, not an image, and cannot be used to create a container", refOrID, artifactType))
+		}
+		// Artifacts have no runnable image configuration; surface what is
+		// known about them so "docker image inspect" still works.
+		img := image.NewImage(image.ID(desc.Target.Digest))
+		img.Details = &image.Details{ArtifactType: artifactType}
+		return img, nil
+	}
+
 	platform := platforms.AllPlatformsWithPreference(cplatforms.Default())
 	if options.Platform != nil {
-		platform = cplatforms.OnlyStrict(*options.Platform)
+		platform = platforms.OnlyStrict(*options.Platform)
 	}
 
 	cs := i.client.ContentStore()
@@ -56,7 +72,7 @@ func (i *ImageService) GetImage(ctx context.Context, refOrID string, options ima
 		}
 
 		var ociimage imagespec.DockerOCIImage
-		if err := readConfig(ctx, cs, conf, &ociimage); err != nil {
+		if err := i.readConfig(ctx, cs, conf, &ociimage); err != nil {
 			if cerrdefs.IsNotFound(err) {
 				log.G(ctx).WithFields(log.Fields{
 					"manifestDescriptor": img.Target(),
@@ -82,6 +98,17 @@ func (i *ImageService) GetImage(ctx context.Context, refOrID string, options ima
 	})
 	ociimage := presentImages[0]
 
+	if options.Platform != nil && !platform.Match(ociimage.Platform) {
+		// The closest present image is handed back for compatibility with
+		// the graphdriver image store; the create path turns this into a
+		// warning on the API response.
+		log.G(ctx).WithFields(log.Fields{
+			"image":     refOrID,
+			"requested": cplatforms.Format(*options.Platform),
+			"actual":    cplatforms.Format(ociimage.Platform),
+		}).Warn("requested image platform does not match any locally present platform")
+	}
+
 	img := dockerOciImageToDockerImagePartial(image.ID(desc.Target.Digest), ociimage)
 	if options.Details {
 		lastUpdated := time.Unix(0, 0)
@@ -97,10 +124,21 @@ func (i *ImageService) GetImage(ctx context.Context, refOrID string, options ima
 
 		// Usually each image will result in 2 references (named and digested).
 		refs := make([]reference.Named, 0, len(tagged)*2)
+		var pinned bool
+		var lastPulled, lastPushed time.Time
 		for _, i := range tagged {
 			if i.UpdatedAt.After(lastUpdated) {
 				lastUpdated = i.UpdatedAt
 			}
+			if isPinnedImage(i) {
+				pinned = true
+			}
+			if t, err := time.Parse(time.RFC3339, i.Labels[imageLabelPulledAt]); err == nil && t.After(lastPulled) {
+				lastPulled = t
+			}
+			if t, err := time.Parse(time.RFC3339, i.Labels[imageLabelPushedAt]); err == nil && t.After(lastPushed) {
+				lastPushed = t
+			}
 			if isDanglingImage(i) {
 				if len(tagged) > 1 {
 					// This is unexpected - dangling image should be deleted
@@ -137,17 +175,124 @@ func (i *ImageService) GetImage(ctx context.Context, refOrID string, options ima
 		}
 
 		img.Details = &image.Details{
-			References:  refs,
-			Size:        size,
-			Metadata:    nil,
-			Driver:      i.snapshotter,
-			LastUpdated: lastUpdated,
+			References:          refs,
+			Size:                size,
+			Metadata:            nil,
+			Driver:              i.snapshotter,
+			LastUpdated:         lastUpdated,
+			DistributionSources: i.imageDistributionSources(ctx, desc),
+			Snapshotters:        i.imageSnapshotters(ctx, desc),
+			Usage:               i.imageUsage(desc.Target.Digest),
+			Platforms:           i.imagePlatformStatuses(ctx, desc),
+			Pinned:              pinned,
+			LastPulled:          lastPulled,
+			LastPushed:          lastPushed,
 		}
 	}
 
 	return img, nil
 }
 
+// imageUsage counts the containers on this daemon that use the image with
+// the given target digest. Swarm services and build-cache records aren't
+// tracked by the image service, so those counts are reported as unknown.
+func (i *ImageService) imageUsage(target digest.Digest) *types.ImageUsage {
+	usage := &types.ImageUsage{Services: -1, BuildCache: -1}
+	for _, ctr := range i.containers.List() {
+		if ctr.ImageID.Digest() != target {
+			continue
+		}
+		usage.Containers++
+		if ctr.IsRunning() {
+			usage.RunningContainers++
+		} else {
+			usage.StoppedContainers++
+		}
+	}
+	return usage
+}
+
+// imagePlatformStatuses describes, for every platform manifest listed in the
+// image's index, whether its content is locally complete and which
+// snapshotters hold an unpacked copy of it. Unlike walkImageManifests it
+// also reports platforms whose manifest has not been fetched at all, so the
+// result tells what a pull would download and why running a platform fails.
+func (i *ImageService) imagePlatformStatuses(ctx context.Context, img containerdimages.Image) []types.ImagePlatformStatus {
+	store := i.client.ContentStore()
+	target := img.Target
+
+	var descs []ocispec.Descriptor
+	switch {
+	case containerdimages.IsIndexType(target.MediaType):
+		data, err := content.ReadBlob(ctx, store, target)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("digest", target.Digest).Debug("failed to read image index")
+			return nil
+		}
+		var index ocispec.Index
+		if err := json.Unmarshal(data, &index); err != nil {
+			log.G(ctx).WithError(err).WithField("digest", target.Digest).Debug("failed to unmarshal image index")
+			return nil
+		}
+		descs = index.Manifests
+	case containerdimages.IsManifestType(target.MediaType):
+		descs = []ocispec.Descriptor{target}
+	default:
+		return nil
+	}
+
+	snapshotters := i.snapshotterPlugins(ctx)
+
+	var statuses []types.ImagePlatformStatus
+	for _, desc := range descs {
+		if !containerdimages.IsManifestType(desc.MediaType) {
+			continue
+		}
+		// Attestation manifests are not runnable platforms.
+		if _, ok := desc.Annotations[attestation.DockerAnnotationReferenceType]; ok {
+			continue
+		}
+		if desc.Platform != nil && desc.Platform.OS == "unknown" {
+			continue
+		}
+
+		status := types.ImagePlatformStatus{}
+		if desc.Platform != nil {
+			status.Platform = platforms.Format(*desc.Platform)
+		}
+
+		available, _, _, missing, err := containerdimages.Check(ctx, store, desc, cplatforms.All)
+		status.ContentAvailable = err == nil && available && len(missing) == 0
+
+		if status.ContentAvailable {
+			im, err := i.NewImageManifest(ctx, img, desc)
+			if err == nil {
+				if status.Platform == "" {
+					// Single-manifest image: the platform only lives in
+					// the image configuration.
+					if cfgDesc, err := im.Image.Config(ctx); err == nil {
+						var cfg ocispec.Image
+						if err := i.readConfig(ctx, store, cfgDesc, &cfg); err == nil {
+							status.Platform = platforms.Format(ocispec.Platform{
+								OS:           cfg.OS,
+								Architecture: cfg.Architecture,
+								Variant:      cfg.Variant,
+							})
+						}
+					}
+				}
+				for _, sn := range snapshotters {
+					if ok, err := im.IsUnpacked(ctx, sn); err == nil && ok {
+						status.Unpacked = append(status.Unpacked, sn)
+					}
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
 func (i *ImageService) GetImageManifest(ctx context.Context, refOrID string, options imagetype.GetImageOpts) (*ocispec.Descriptor, error) {
 	cs := i.client.ContentStore()
 
@@ -163,7 +308,7 @@ func (i *ImageService) GetImageManifest(ctx context.Context, refOrID string, opt
 	if containerdimages.IsIndexType(desc.MediaType) {
 		platform := platforms.AllPlatformsWithPreference(cplatforms.Default())
 		if options.Platform != nil {
-			platform = cplatforms.Only(*options.Platform)
+			platform = platforms.Only(*options.Platform)
 		}
 
 		childManifests, err := containerdimages.LimitManifests(containerdimages.ChildrenHandler(cs), platform, 1)(ctx, desc)
@@ -226,7 +371,10 @@ func (i *ImageService) resolveDescriptor(ctx context.Context, refOrID string) (o
 	return img.Target, nil
 }
 
-func (i *ImageService) resolveImage(ctx context.Context, refOrID string) (containerdimages.Image, error) {
+func (i *ImageService) resolveImage(ctx context.Context, refOrID string) (_ containerdimages.Image, retErr error) {
+	ctx, span := startSpan(ctx, "ImageService.resolveImage", attribute.String("image", refOrID))
+	defer completeSpan(span, &retErr)
+
 	parsed, err := reference.ParseAnyReference(refOrID)
 	if err != nil {
 		return containerdimages.Image{}, errdefs.InvalidParameter(err)
@@ -0,0 +1,68 @@
+package containerd
+
+import (
+	"context"
+
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// imageLabelArtifactType is set on image records whose manifest describes an
+// OCI artifact (wasm module, helm chart, ...) rather than a runnable image.
+// It holds the artifact's media type.
+const imageLabelArtifactType = "org.mobyproject.image.artifact-type"
+
+// ArtifactType returns the artifact media type of the manifest when it
+// describes an OCI artifact rather than a runnable image, or an empty string
+// for regular images.
+func (im *ImageManifest) ArtifactType(ctx context.Context) (string, error) {
+	mfst, err := im.Manifest(ctx)
+	if err != nil {
+		return "", err
+	}
+	if mfst.ArtifactType != "" {
+		return mfst.ArtifactType, nil
+	}
+	switch mfst.Config.MediaType {
+	case ocispec.MediaTypeImageConfig, containerdimages.MediaTypeDockerSchema2Config:
+		return "", nil
+	}
+	return mfst.Config.MediaType, nil
+}
+
+// imageArtifactType inspects the locally present manifests of the image and
+// returns the artifact media type when the image is an OCI artifact, or an
+// empty string when it is a regular image.
+func (i *ImageService) imageArtifactType(ctx context.Context, img containerdimages.Image) (string, error) {
+	var artifactType string
+	err := i.walkImageManifests(ctx, img, func(im *ImageManifest) error {
+		if artifactType != "" {
+			return nil
+		}
+		at, err := im.ArtifactType(ctx)
+		if err != nil {
+			return nil
+		}
+		artifactType = at
+		return nil
+	})
+	return artifactType, err
+}
+
+// labelImageArtifactType stores the artifact media type as a label on the
+// image record, so artifacts can be recognized without reading their
+// manifests back.
+func (i *ImageService) labelImageArtifactType(ctx context.Context, img containerdimages.Image) {
+	artifactType, err := i.imageArtifactType(ctx, img)
+	if err != nil || artifactType == "" {
+		return
+	}
+	if img.Labels == nil {
+		img.Labels = map[string]string{}
+	}
+	img.Labels[imageLabelArtifactType] = artifactType
+	if _, err := i.client.ImageService().Update(ctx, img, "labels."+imageLabelArtifactType); err != nil {
+		log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to store artifact type label")
+	}
+}
@@ -132,7 +132,11 @@ func (i *ImageService) pullForBuilder(ctx context.Context, name string, authConf
 		pullRegistryAuth = &resolvedConfig
 	}
 
-	if err := i.PullImage(ctx, ref.Name(), taggedRef.(reference.NamedTagged).Tag(), platform, nil, pullRegistryAuth, output); err != nil {
+	var pullOptions imagetypes.PullOptions
+	if platform != nil {
+		pullOptions.Platforms = []ocispec.Platform{*platform}
+	}
+	if err := i.PullImage(ctx, ref.Name(), taggedRef.(reference.NamedTagged).Tag(), pullOptions, nil, pullRegistryAuth, output); err != nil {
 		return nil, err
 	}
 
@@ -457,7 +461,7 @@ func (i *ImageService) CreateImage(ctx context.Context, config []byte, parent st
 		}
 	}
 
-	if err := i.unpackImage(ctx, createdImage, platforms.DefaultSpec()); err != nil {
+	if err := i.unpackImage(ctx, createdImage, platforms.DefaultSpec(), i.snapshotter); err != nil {
 		return nil, err
 	}
 
@@ -83,6 +83,12 @@ func (i *ImageService) CommitImage(ctx context.Context, cc backend.CommitConfig)
 	if err != nil {
 		return "", fmt.Errorf("failed to export layer: %w", err)
 	}
+	// The active snapshot has been fully read into the content store; the
+	// container no longer needs to stay frozen for the remainder of the
+	// commit.
+	if cc.SnapshotCaptured != nil {
+		cc.SnapshotCaptured()
+	}
 	imageConfig := generateCommitImageConfig(parentImage, diffID, cc)
 
 	layers := parentManifest.Layers
@@ -127,7 +133,10 @@ func generateCommitImageConfig(baseConfig imagespec.DockerOCIImage, diffID diges
 		opts.Author = baseConfig.Author
 	}
 
-	createdTime := time.Now()
+	// The new history entry is generated the same way as in the graphdriver
+	// image store, so `docker history` output doesn't depend on the backend.
+	imgHistory := image.NewHistory(opts.Author, opts.Comment, strings.Join(opts.ContainerConfig.Cmd, " "), diffID == "")
+
 	arch := baseConfig.Architecture
 	if arch == "" {
 		arch = runtime.GOARCH
@@ -151,19 +160,13 @@ func generateCommitImageConfig(baseConfig imagespec.DockerOCIImage, diffID diges
 				Architecture: arch,
 				OS:           os,
 			},
-			Created: &createdTime,
+			Created: imgHistory.Created,
 			Author:  opts.Author,
 			RootFS: ocispec.RootFS{
 				Type:    "layers",
 				DiffIDs: diffIds,
 			},
-			History: append(baseConfig.History, ocispec.History{
-				Created:    &createdTime,
-				CreatedBy:  strings.Join(opts.ContainerConfig.Cmd, " "),
-				Author:     opts.Author,
-				Comment:    opts.Comment,
-				EmptyLayer: diffID == "",
-			}),
+			History: append(baseConfig.History, imgHistory),
 		},
 		Config: containerConfigToDockerOCIImageConfig(opts.Config),
 	}
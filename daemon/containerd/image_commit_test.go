@@ -0,0 +1,65 @@
+package containerd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/container"
+	imagespec "github.com/docker/docker/image/spec/specs-go/v1"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestGenerateCommitImageConfig(t *testing.T) {
+	baseCreated := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	baseDiffID := digest.FromString("base layer")
+	base := imagespec.DockerOCIImage{
+		Image: ocispec.Image{
+			Platform: ocispec.Platform{Architecture: "amd64", OS: "linux"},
+			Author:   "base author",
+			RootFS: ocispec.RootFS{
+				Type:    "layers",
+				DiffIDs: []digest.Digest{baseDiffID},
+			},
+			History: []ocispec.History{
+				{Created: &baseCreated, CreatedBy: "/bin/sh -c #(nop) ADD file:deadbeef in / "},
+			},
+		},
+	}
+
+	t.Run("with diff", func(t *testing.T) {
+		diffID := digest.FromString("new layer")
+		img := generateCommitImageConfig(base, diffID, backend.CommitConfig{
+			Comment:         "a comment",
+			Config:          &container.Config{},
+			ContainerConfig: &container.Config{Cmd: []string{"/bin/sh", "-c", "touch /foo"}},
+		})
+
+		assert.Assert(t, is.Len(img.History, 2))
+		entry := img.History[1]
+		assert.Check(t, is.Equal(entry.CreatedBy, "/bin/sh -c touch /foo"))
+		assert.Check(t, is.Equal(entry.Comment, "a comment"))
+		assert.Check(t, is.Equal(entry.Author, "base author"), "author should fall back to the parent image's")
+		assert.Check(t, !entry.EmptyLayer)
+		assert.Assert(t, entry.Created != nil)
+		assert.Check(t, is.Equal(img.Created, entry.Created))
+		assert.Check(t, is.DeepEqual(img.RootFS.DiffIDs, []digest.Digest{baseDiffID, diffID}))
+	})
+
+	t.Run("empty diff", func(t *testing.T) {
+		img := generateCommitImageConfig(base, "", backend.CommitConfig{
+			Author:          "an author",
+			Config:          &container.Config{},
+			ContainerConfig: &container.Config{},
+		})
+
+		assert.Assert(t, is.Len(img.History, 2))
+		entry := img.History[1]
+		assert.Check(t, entry.EmptyLayer, "commit without filesystem changes should be an empty layer")
+		assert.Check(t, is.Equal(entry.Author, "an author"))
+		assert.Check(t, is.DeepEqual(img.RootFS.DiffIDs, []digest.Digest{baseDiffID}))
+	})
+}
@@ -0,0 +1,212 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/containerd/containerd/archive/compression"
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/images/converter"
+	"github.com/containerd/containerd/log"
+	cplatforms "github.com/containerd/containerd/platforms"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ConvertImage converts the image to a different layer compression (gzip or
+// zstd) and optionally to the eStargz format, and stores the result under
+// targetRef. The source image is left untouched.
+func (i *ImageService) ConvertImage(ctx context.Context, refOrID string, targetRef reference.Named, options imagetypes.ConvertOptions) (retErr error) {
+	ctx, span := startSpan(ctx, "ImageService.ConvertImage",
+		attribute.String("image", refOrID),
+		attribute.String("target", targetRef.String()))
+	defer completeSpan(span, &retErr)
+
+	if err := i.ensureConnection(); err != nil {
+		return err
+	}
+
+	src, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return err
+	}
+
+	var layerConvert converter.ConvertFunc
+	switch {
+	case options.EStargz:
+		layerConvert = estargzLayerConvertFunc()
+	case options.Compression == imagetypes.CompressionGzip:
+		layerConvert = recompressLayerConvertFunc(compression.Gzip, ocispec.MediaTypeImageLayerGzip)
+	case options.Compression == imagetypes.CompressionZstd:
+		layerConvert = recompressLayerConvertFunc(compression.Zstd, ocispec.MediaTypeImageLayerZstd)
+	case options.Compression == "":
+		return errdefs.InvalidParameter(errors.New("no conversion requested: a target compression or estargz is required"))
+	default:
+		return errdefs.InvalidParameter(errors.Errorf("unsupported compression %q: supported values are %q and %q", options.Compression, imagetypes.CompressionGzip, imagetypes.CompressionZstd))
+	}
+
+	convertOpts := []converter.Opt{
+		// The OCI media types are required for zstd and eStargz layers.
+		converter.WithDockerToOCI(true),
+		converter.WithLayerConvertFunc(layerConvert),
+	}
+	if len(options.Platforms) > 0 {
+		convertOpts = append(convertOpts, converter.WithPlatform(cplatforms.Any(options.Platforms...)))
+	}
+
+	dst, err := converter.Convert(ctx, i.client, targetRef.String(), src.Name, convertOpts...)
+	if err != nil {
+		return translateConnectionError(err)
+	}
+
+	log.G(ctx).WithFields(log.Fields{
+		"source": src.Name,
+		"target": dst.Name,
+		"digest": dst.Target.Digest,
+	}).Info("image converted")
+	i.LogImageEvent(dst.Target.Digest.String(), reference.FamiliarString(targetRef), "convert")
+
+	return nil
+}
+
+// recompressLayerConvertFunc returns a layer convert function that rewrites
+// every layer with the given compression, leaving non-layer content alone.
+func recompressLayerConvertFunc(comp compression.Compression, mediaType string) converter.ConvertFunc {
+	return func(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+		if !containerdimages.IsLayerType(desc.MediaType) {
+			return nil, nil
+		}
+		if desc.MediaType == mediaType {
+			// Already in the requested format.
+			return nil, nil
+		}
+
+		decompressed, cleanup, err := decompressedLayer(ctx, cs, desc)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		pr, pw := io.Pipe()
+		go func() {
+			zw, err := compression.CompressStream(pw, comp)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(zw, decompressed); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			pw.CloseWithError(zw.Close())
+		}()
+
+		newDesc := desc
+		newDesc.MediaType = mediaType
+		newDesc.Annotations = nil
+		return writeBlob(ctx, cs, newDesc, pr)
+	}
+}
+
+// estargzLayerConvertFunc returns a layer convert function that rewrites
+// every layer into the eStargz format, annotated with the TOC digest so
+// lazy-pulling snapshotters can use it.
+func estargzLayerConvertFunc() converter.ConvertFunc {
+	return func(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+		if !containerdimages.IsLayerType(desc.MediaType) {
+			return nil, nil
+		}
+
+		decompressed, cleanup, err := decompressedLayer(ctx, cs, desc)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		// estargz.Build needs random access to the uncompressed tar, so
+		// spill it to a temporary file first.
+		tmp, err := os.CreateTemp("", "docker-estargz-convert")
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}()
+		size, err := io.Copy(tmp, decompressed)
+		if err != nil {
+			return nil, err
+		}
+
+		blob, err := estargz.Build(io.NewSectionReader(tmp, 0, size))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to build eStargz layer")
+		}
+		defer blob.Close()
+
+		newDesc := desc
+		newDesc.MediaType = ocispec.MediaTypeImageLayerGzip
+		newDesc.Annotations = nil
+		converted, err := writeBlob(ctx, cs, newDesc, blob)
+		if err != nil {
+			return nil, err
+		}
+		converted.Annotations = map[string]string{
+			estargz.TOCJSONDigestAnnotation:         blob.TOCDigest().String(),
+			estargz.StoreUncompressedSizeAnnotation: strconv.FormatInt(size, 10),
+		}
+		return converted, nil
+	}
+}
+
+// decompressedLayer returns a reader of the layer's uncompressed tar stream
+// and a cleanup function releasing the underlying content reader.
+func decompressedLayer(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (io.Reader, func(), error) {
+	ra, err := cs.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, nil, err
+	}
+	decompressed, err := compression.DecompressStream(content.NewReader(ra))
+	if err != nil {
+		ra.Close()
+		return nil, nil, err
+	}
+	return decompressed, func() {
+		decompressed.Close()
+		ra.Close()
+	}, nil
+}
+
+// writeBlob streams the converted layer into the content store and returns
+// its descriptor with the digest and size filled in.
+func writeBlob(ctx context.Context, cs content.Store, desc ocispec.Descriptor, r io.Reader) (*ocispec.Descriptor, error) {
+	ref := "convert-" + desc.Digest.Encoded()
+	w, err := content.OpenWriter(ctx, cs, content.WithRef(ref))
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	digester := digest.Canonical.Digester()
+	size, err := io.Copy(io.MultiWriter(w, digester.Hash()), r)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Commit(ctx, size, digester.Digest()); err != nil && !cerrdefs.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	desc.Digest = digester.Digest()
+	desc.Size = size
+	return &desc, nil
+}
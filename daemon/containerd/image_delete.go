@@ -11,10 +11,12 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
 )
 
 // ImageDelete deletes the image referenced by the given imageRef from this
@@ -63,6 +65,12 @@ func (i *ImageService) ImageDelete(ctx context.Context, imageRef string, force,
 
 	imgID := image.ID(img.Target.Digest)
 
+	if !force {
+		if err := i.checkProtectedTags(ctx, img, parsedRef, isImageIDPrefix(imgID.String(), imageRef)); err != nil {
+			return nil, err
+		}
+	}
+
 	if isImageIDPrefix(imgID.String(), imageRef) {
 		return i.deleteAll(ctx, img, force, prune)
 	}
@@ -115,6 +123,40 @@ func (i *ImageService) ImageDelete(ctx context.Context, imageRef string, force,
 	return i.deleteAll(ctx, img, force, prune)
 }
 
+// checkProtectedTags returns a Forbidden error when the delete would remove
+// a reference matching a protected tag pattern. Deleting by ID removes every
+// reference of the image, so all of them are checked in that case.
+func (i *ImageService) checkProtectedTags(ctx context.Context, img images.Image, parsedRef reference.Named, byID bool) error {
+	if len(i.protectedTags) == 0 {
+		return nil
+	}
+
+	var refs []reference.Named
+	if byID {
+		imgs, err := i.client.ImageService().List(ctx, "target.digest=="+img.Target.Digest.String())
+		if err != nil {
+			return err
+		}
+		for _, im := range imgs {
+			if isDanglingImage(im) {
+				continue
+			}
+			if ref, err := reference.ParseNormalizedNamed(im.Name); err == nil {
+				refs = append(refs, ref)
+			}
+		}
+	} else {
+		refs = append(refs, reference.TagNameOnly(parsedRef))
+	}
+
+	for _, ref := range refs {
+		if image.IsProtectedTag(i.protectedTags, ref) {
+			return errdefs.Forbidden(errors.Errorf("tag %s is protected and can only be deleted with force", reference.FamiliarString(ref)))
+		}
+	}
+	return nil
+}
+
 // deleteAll deletes the image from the daemon, and if prune is true,
 // also deletes dangling parents if there is no conflict in doing so.
 // Parent images are removed quietly, and if there is any issue/conflict
@@ -0,0 +1,197 @@
+package containerd
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/log"
+	cplatforms "github.com/containerd/containerd/platforms"
+	"github.com/docker/distribution/reference"
+	containertypes "github.com/docker/docker/api/types/container"
+	imagetypes "github.com/docker/docker/api/types/image"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/image"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// EnsureImage applies the given pull policy before a container is created
+// and returns the image the container will run. An empty policy keeps the
+// historic create behavior: the image is resolved locally and never pulled.
+//
+// "never" additionally requires the image to be unpacked into the default
+// snapshotter for the platform the container would run, so create fails fast
+// instead of discovering missing content at start. "missing" and "always"
+// decide per platform: a name that is present locally for another platform
+// only does not satisfy them. "always" re-resolves tags at the registry and
+// reuses local content when the digest is unchanged; image IDs and digested
+// references are content-addressed and cannot become out of date, so they are
+// never re-resolved.
+func (i *ImageService) EnsureImage(ctx context.Context, refOrID string, policy containertypes.PullPolicy, platform *ocispec.Platform, authConfig *registrytypes.AuthConfig) (*image.Image, error) {
+	opts := imagetypes.GetImageOpts{Platform: platform}
+	if policy == "" {
+		img, err := i.GetImage(ctx, refOrID, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.enforceImageFreshness(ctx, refOrID); err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
+
+	switch policy {
+	case containertypes.PullPolicyNever:
+		img, err := i.getImageForPlatform(ctx, refOrID, platform)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.checkImageUnpacked(ctx, refOrID, platform, img); err != nil {
+			return nil, err
+		}
+		if err := i.enforceImageFreshness(ctx, refOrID); err != nil {
+			return nil, err
+		}
+		return img, nil
+	case containertypes.PullPolicyMissing, containertypes.PullPolicyAlways:
+	default:
+		return nil, errdefs.InvalidParameter(errors.Errorf("invalid pull policy: %q", policy))
+	}
+
+	pullRef, pinned := createPullReference(refOrID)
+
+	img, err := i.getImageForPlatform(ctx, refOrID, platform)
+	if err == nil {
+		if policy == containertypes.PullPolicyMissing || pinned {
+			if err := i.enforceImageFreshness(ctx, refOrID); err != nil {
+				return nil, err
+			}
+			return img, nil
+		}
+		upToDate, err := i.tagUpToDate(ctx, pullRef, img, authConfig)
+		if err != nil {
+			return nil, err
+		}
+		if upToDate {
+			// The tag still matches the registry, so the image counts
+			// as freshly validated for the max-image-age policy.
+			i.refreshImagePullTime(ctx, digest.Digest(img.ID().String()))
+			return img, nil
+		}
+	} else if !errdefs.IsNotFound(err) || pullRef == nil {
+		return nil, err
+	}
+
+	log.G(ctx).WithFields(log.Fields{
+		"image":  reference.FamiliarString(pullRef),
+		"policy": policy,
+	}).Debug("pulling image for container create")
+
+	var pullOpts imagetypes.PullOptions
+	if platform != nil {
+		pullOpts.Platforms = []ocispec.Platform{*platform}
+	}
+	tagOrDigest := ""
+	if tagged, ok := pullRef.(reference.Tagged); ok {
+		tagOrDigest = tagged.Tag()
+	} else if digested, ok := pullRef.(reference.Digested); ok {
+		tagOrDigest = digested.Digest().String()
+	}
+	if err := i.PullImage(ctx, pullRef.Name(), tagOrDigest, pullOpts, nil, authConfig, io.Discard); err != nil {
+		return nil, err
+	}
+
+	return i.GetImage(ctx, refOrID, opts)
+}
+
+// getImageForPlatform resolves refOrID locally and, unlike GetImage, returns
+// a NotFound error instead of the closest present image when the requested
+// platform is not available. The pull policies decide per platform, so a
+// fuzzy match must count as missing.
+func (i *ImageService) getImageForPlatform(ctx context.Context, refOrID string, platform *ocispec.Platform) (*image.Image, error) {
+	img, err := i.GetImage(ctx, refOrID, imagetypes.GetImageOpts{Platform: platform})
+	if err != nil {
+		return nil, err
+	}
+	if platform != nil {
+		imgPlat := ocispec.Platform{
+			OS:           img.OS,
+			Architecture: img.Architecture,
+			Variant:      img.Variant,
+		}
+		if !cplatforms.Only(*platform).Match(imgPlat) {
+			return nil, errdefs.NotFound(errors.Errorf("image %s was found but does not provide platform %s", refOrID, cplatforms.Format(*platform)))
+		}
+	}
+	return img, nil
+}
+
+// checkImageUnpacked returns a NotFound error when the image is not unpacked
+// into the default snapshotter for the given platform, or for the platform of
+// the resolved image when none was requested.
+func (i *ImageService) checkImageUnpacked(ctx context.Context, refOrID string, platform *ocispec.Platform, img *image.Image) error {
+	c8dImg, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return err
+	}
+
+	p := ocispec.Platform{
+		OS:           img.OS,
+		Architecture: img.Architecture,
+		Variant:      img.Variant,
+	}
+	if platform != nil {
+		p = *platform
+	}
+
+	platformImg := containerd.NewImageWithPlatform(i.client, c8dImg, cplatforms.Only(p))
+	unpacked, err := platformImg.IsUnpacked(ctx, i.snapshotter)
+	if err != nil {
+		return err
+	}
+	if !unpacked {
+		return errdefs.NotFound(errors.Errorf("image %s is not unpacked for platform %s, and the pull policy is %q", refOrID, cplatforms.Format(p), containertypes.PullPolicyNever))
+	}
+	return nil
+}
+
+// tagUpToDate reports whether the tag still resolves to the digest of the
+// locally stored image. Only the manifest descriptor is fetched from the
+// registry; no content is pulled.
+func (i *ImageService) tagUpToDate(ctx context.Context, ref reference.Named, img *image.Image, authConfig *registrytypes.AuthConfig) (bool, error) {
+	if err := i.registryService.CheckRegistryIsAllowed(reference.Domain(ref)); err != nil {
+		return false, err
+	}
+
+	resolver, _ := i.newResolverFromAuthConfig(ctx, authConfig)
+	_, desc, err := resolver.Resolve(ctx, ref.String())
+	if err != nil {
+		return false, translatePullError(ctx, ref, err)
+	}
+	return desc.Digest == img.ID().Digest(), nil
+}
+
+// createPullReference derives the reference to pull when a pull policy
+// requires fetching refOrID. Image IDs and bare digests cannot be pulled, so
+// ref is nil for those. pinned is true when the reference is
+// content-addressed (an ID or a digested reference) and so cannot become out
+// of date.
+func createPullReference(refOrID string) (ref reference.Named, pinned bool) {
+	if truncatedID.MatchString(refOrID) {
+		return nil, true
+	}
+	if _, err := digest.Parse(refOrID); err == nil {
+		return nil, true
+	}
+	parsed, err := reference.ParseNormalizedNamed(refOrID)
+	if err != nil {
+		return nil, true
+	}
+	if _, ok := parsed.(reference.Digested); ok {
+		return parsed, true
+	}
+	return reference.TagNameOnly(parsed), false
+}
@@ -0,0 +1,41 @@
+package containerd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestCreatePullReference(t *testing.T) {
+	for _, tc := range []struct {
+		refOrID string
+		ref     string
+		pinned  bool
+	}{
+		{refOrID: "busybox", ref: "docker.io/library/busybox:latest", pinned: false},
+		{refOrID: "busybox:1.36", ref: "docker.io/library/busybox:1.36", pinned: false},
+		{refOrID: "registry.example.com/img", ref: "registry.example.com/img:latest", pinned: false},
+		{
+			refOrID: "busybox@sha256:538721340ded10875f4710cad688c70e5d0ecb4dcd5e7d0c161f301f36f79414",
+			ref:     "docker.io/library/busybox@sha256:538721340ded10875f4710cad688c70e5d0ecb4dcd5e7d0c161f301f36f79414",
+			pinned:  true,
+		},
+		// Image IDs and bare digests cannot be pulled.
+		{refOrID: "538721340ded", pinned: true},
+		{refOrID: "538721340ded10875f4710cad688c70e5d0ecb4dcd5e7d0c161f301f36f79414", pinned: true},
+		{refOrID: "sha256:538721340ded10875f4710cad688c70e5d0ecb4dcd5e7d0c161f301f36f79414", pinned: true},
+	} {
+		tc := tc
+		t.Run(tc.refOrID, func(t *testing.T) {
+			ref, pinned := createPullReference(tc.refOrID)
+			if tc.ref == "" {
+				assert.Check(t, is.Nil(ref))
+			} else {
+				assert.Assert(t, ref != nil)
+				assert.Check(t, is.Equal(ref.String(), tc.ref))
+			}
+			assert.Check(t, is.Equal(pinned, tc.pinned))
+		})
+	}
+}
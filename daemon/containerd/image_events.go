@@ -2,6 +2,7 @@ package containerd
 
 import (
 	"context"
+	"time"
 
 	"github.com/docker/docker/api/types/events"
 	imagetypes "github.com/docker/docker/api/types/image"
@@ -10,6 +11,8 @@ import (
 // LogImageEvent generates an event related to an image with only the default attributes.
 func (i *ImageService) LogImageEvent(imageID, refName, action string) {
 	ctx := context.TODO()
+	i.markSelfEvent(imageID)
+	i.markSelfEvent(refName)
 	attributes := map[string]string{}
 
 	img, err := i.GetImage(ctx, imageID, imagetypes.GetImageOpts{})
@@ -29,6 +32,36 @@ func (i *ImageService) LogImageEvent(imageID, refName, action string) {
 	i.eventsService.Log(action, events.ImageEventType, actor)
 }
 
+// markSelfEvent records that the daemon itself just logged an event for the
+// given image name or ID, so the matching containerd event is not reported a
+// second time by watchContainerdEvents.
+func (i *ImageService) markSelfEvent(name string) {
+	if name == "" {
+		return
+	}
+	i.selfEventsMu.Lock()
+	defer i.selfEventsMu.Unlock()
+	if i.selfEvents == nil {
+		i.selfEvents = map[string]time.Time{}
+	}
+	now := time.Now()
+	for n, t := range i.selfEvents {
+		if now.Sub(t) > selfEventWindow {
+			delete(i.selfEvents, n)
+		}
+	}
+	i.selfEvents[name] = now
+}
+
+// isRecentSelfEvent reports whether the daemon logged an event for the given
+// image name or ID within the suppression window.
+func (i *ImageService) isRecentSelfEvent(name string) bool {
+	i.selfEventsMu.Lock()
+	defer i.selfEventsMu.Unlock()
+	t, ok := i.selfEvents[name]
+	return ok && time.Since(t) <= selfEventWindow
+}
+
 // copyAttributes guarantees that labels are not mutated by event triggers.
 func copyAttributes(attributes, labels map[string]string) {
 	if labels == nil {
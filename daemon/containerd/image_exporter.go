@@ -14,6 +14,7 @@ import (
 	"github.com/containerd/containerd/log"
 	cplatforms "github.com/containerd/containerd/platforms"
 	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/platforms"
@@ -42,14 +43,20 @@ func (i *ImageService) PerformWithBaseFS(ctx context.Context, c *container.Conta
 // exported images are archived into a tar when written to the output
 // stream. All images with the given tag and all versions containing
 // the same tag are exported. names is the set of tags to export, and
-// outStream is the writer which the images are written to.
+// outStream is the writer which the images are written to. Non-distributable
+// (foreign) layers, such as Windows base layers, are omitted from the archive
+// unless options.NondistributableArtifacts is set.
+//
+// The archive is always an OCI layout whose top-level index records every
+// exported reference with ref.name annotations, and blobs shared between the
+// images are stored once. In the default Docker format a manifest.json file
+// is written next to it for backwards compatibility; the OCI format leaves
+// it out.
 //
 // TODO(thaJeztah): produce JSON stream progress response and image events; see https://github.com/moby/moby/issues/43910
-func (i *ImageService) ExportImage(ctx context.Context, names []string, outStream io.Writer) error {
+func (i *ImageService) ExportImage(ctx context.Context, names []string, outStream io.Writer, options imagetypes.SaveOptions) error {
 	platform := platforms.AllPlatformsWithPreference(cplatforms.Default())
 	opts := []archive.ExportOpt{
-		archive.WithSkipNonDistributableBlobs(),
-
 		// This makes the exported archive also include `manifest.json`
 		// when the image is a manifest list. It is needed for backwards
 		// compatibility with Docker image format.
@@ -63,6 +70,12 @@ func (i *ImageService) ExportImage(ctx context.Context, names []string, outStrea
 		// Importing the same archive into containerd, will not restrict the platforms.
 		archive.WithPlatform(platform),
 	}
+	if !options.NondistributableArtifacts {
+		opts = append(opts, archive.WithSkipNonDistributableBlobs())
+	}
+	if options.Format == imagetypes.SaveFormatOCI {
+		opts = append(opts, archive.WithSkipDockerManifest())
+	}
 
 	contentStore := i.client.ContentStore()
 	leasesManager := i.client.LeasesService()
@@ -144,10 +157,18 @@ func leaseContent(ctx context.Context, store content.Store, leasesManager leases
 // LoadImage uploads a set of images into the repository. This is the
 // complement of ExportImage.  The input stream is an uncompressed tar
 // ball containing images and metadata.
-func (i *ImageService) LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, quiet bool) error {
+//
+// When platforms are requested, only the blobs of the matching manifests are
+// imported from the archive; the loaded images still point at the digest
+// recorded in the archive's index.
+func (i *ImageService) LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, options imagetypes.LoadOptions) error {
+	platformMatcher := cplatforms.All
+	if len(options.Platforms) > 0 {
+		platformMatcher = platforms.Any(options.Platforms...)
+	}
+
 	opts := []containerd.ImportOpt{
-		// TODO(vvoland): Allow user to pass platform
-		containerd.WithImportPlatform(cplatforms.All),
+		containerd.WithImportPlatform(platformMatcher),
 
 		// Create an additional image with dangling name for imported images...
 		containerd.WithDigestRef(danglingImageName),
@@ -186,6 +207,11 @@ func (i *ImageService) LoadImage(ctx context.Context, inTar io.ReadCloser, outSt
 				"manifest": platformImg.Target().Digest,
 			})
 
+			if p := platformImg.Target().Platform; p != nil && !platformMatcher.Match(*p) {
+				logger.Debug("don't unpack manifest of platform that wasn't requested")
+				return nil
+			}
+
 			if isPseudo, err := platformImg.IsPseudoImage(ctx); isPseudo || err != nil {
 				if err != nil {
 					logger.WithError(err).Warn("failed to read manifest")
@@ -202,7 +228,7 @@ func (i *ImageService) LoadImage(ctx context.Context, inTar io.ReadCloser, outSt
 			}
 
 			if !unpacked {
-				err = platformImg.Unpack(ctx, i.snapshotter)
+				err = platformImg.Unpack(ctx, i.snapshotter, i.unpackOpts()...)
 
 				if err != nil {
 					return errdefs.System(err)
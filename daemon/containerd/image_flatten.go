@@ -0,0 +1,150 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/diff"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/log"
+	cplatforms "github.com/containerd/containerd/platforms"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/errdefs"
+	imagespec "github.com/docker/docker/image/spec/specs-go/v1"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// FlattenImage creates a new single-layer image from the image's filesystem,
+// preserving its config, and stores it under targetRef. The source image is
+// left untouched.
+func (i *ImageService) FlattenImage(ctx context.Context, refOrID string, targetRef reference.Named, outStream io.Writer) (retErr error) {
+	out := streamformatter.NewJSONProgressOutput(outStream, false)
+
+	desc, err := i.resolveDescriptor(ctx, refOrID)
+	if err != nil {
+		return err
+	}
+
+	cs := i.client.ContentStore()
+	confDesc, err := containerdimages.Config(ctx, cs, desc, cplatforms.Default())
+	if err != nil {
+		return err
+	}
+	configBytes, err := content.ReadBlob(ctx, cs, confDesc)
+	if err != nil {
+		return err
+	}
+	var imgConfig imagespec.DockerOCIImage
+	if err := json.Unmarshal(configBytes, &imgConfig); err != nil {
+		return err
+	}
+
+	if len(imgConfig.RootFS.DiffIDs) <= 1 {
+		return errdefs.InvalidParameter(errors.Errorf("image %s already has a single layer", refOrID))
+	}
+
+	// Don't gc me and clean the dirty data after 1 hour!
+	ctx, release, err := i.client.WithLease(ctx, leases.WithRandomID(), leases.WithExpiration(1*time.Hour))
+	if err != nil {
+		return errors.Wrap(err, "failed to create lease for flatten")
+	}
+	defer func() {
+		if err := release(ctx); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to release lease created for flatten")
+		}
+	}()
+
+	progress.Messagef(out, "", "Flattening %d layers of %s", len(imgConfig.RootFS.DiffIDs), reference.FamiliarString(targetRef))
+
+	// Mount a view of the full rootfs and diff it against an empty lower to
+	// produce the single merged layer.
+	sn := i.client.SnapshotService(i.snapshotter)
+	viewKey := "flatten-view-" + uniquePart()
+	mounts, err := sn.View(ctx, viewKey, identity.ChainID(imgConfig.RootFS.DiffIDs).String())
+	if err != nil {
+		return errors.Wrap(err, "failed to create view of image rootfs")
+	}
+	defer func() {
+		if err := sn.Remove(context.Background(), viewKey); err != nil {
+			log.G(ctx).WithError(err).WithField("key", viewKey).Warn("failed to remove view snapshot created for flatten")
+		}
+	}()
+
+	layerDesc, err := i.client.DiffService().Compare(ctx, nil, mounts,
+		diff.WithMediaType(ocispec.MediaTypeImageLayerGzip),
+		diff.WithReference("flatten-"+uniquePart()))
+	if err != nil {
+		return errors.Wrap(err, "failed to export flattened layer")
+	}
+
+	info, err := cs.Info(ctx, layerDesc.Digest)
+	if err != nil {
+		return err
+	}
+	diffIDStr, ok := info.Labels["containerd.io/uncompressed"]
+	if !ok {
+		return errors.New("invalid differ response with no diffID")
+	}
+	diffID, err := digest.Parse(diffIDStr)
+	if err != nil {
+		return err
+	}
+
+	createdTime := time.Now()
+	newConfig := imgConfig
+	newConfig.Created = &createdTime
+	newConfig.RootFS = ocispec.RootFS{
+		Type:    "layers",
+		DiffIDs: []digest.Digest{diffID},
+	}
+	// The original history is kept for provenance, but all of its entries
+	// become empty-layer as the single merged layer replaces them.
+	newConfig.History = make([]ocispec.History, len(imgConfig.History), len(imgConfig.History)+1)
+	for idx, h := range imgConfig.History {
+		h.EmptyLayer = true
+		newConfig.History[idx] = h
+	}
+	newConfig.History = append(newConfig.History, ocispec.History{
+		Created: &createdTime,
+		Comment: "flattened from " + desc.Digest.String(),
+	})
+
+	manifestDesc, err := writeContentsForImage(ctx, i.snapshotter, cs, newConfig, []ocispec.Descriptor{layerDesc})
+	if err != nil {
+		return err
+	}
+
+	// Unpack the merged layer so the new image is immediately usable.
+	if err := applyDiffLayer(ctx, identity.ChainID(newConfig.RootFS.DiffIDs).String(), imagespec.DockerOCIImage{}, sn, i.client.DiffService(), layerDesc); err != nil {
+		return errors.Wrap(err, "failed to unpack flattened layer")
+	}
+
+	img := containerdimages.Image{
+		Name:      targetRef.String(),
+		Target:    manifestDesc,
+		CreatedAt: time.Now(),
+	}
+	if _, err := i.client.ImageService().Update(ctx, img); err != nil {
+		if !cerrdefs.IsNotFound(err) {
+			return err
+		}
+		if _, err := i.client.ImageService().Create(ctx, img); err != nil {
+			return errors.Wrap(err, "failed to create flattened image")
+		}
+	}
+
+	i.LogImageEvent(manifestDesc.Digest.String(), reference.FamiliarString(targetRef), "flatten")
+	progress.Messagef(out, "", "Flattened %s: digest: %s", reference.FamiliarString(targetRef), manifestDesc.Digest)
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package containerd
+
+import (
+	"context"
+	"time"
+
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/errdefs"
+	units "github.com/docker/go-units"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// imageLabelPulledAt records when the image was pulled, or when a tag was
+// last validated against the registry by the "always" pull policy. The
+// max-image-age check prefers it over the record timestamps, which also
+// change on unrelated label updates.
+const imageLabelPulledAt = "org.mobyproject.image.pulled-at"
+
+// imageLabelPushedAt records when the image was last pushed to a registry by
+// this daemon. Together with the pulled-at and distribution source labels it
+// lets operators audit image provenance without external tracking.
+const imageLabelPushedAt = "org.mobyproject.image.pushed-at"
+
+// enforceImageFreshness applies the max-image-age daemon policy before a
+// container is created from a locally resolved image. Stale images are
+// warned about, or refused when the daemon is configured to do so.
+func (i *ImageService) enforceImageFreshness(ctx context.Context, refOrID string) error {
+	if i.maxImageAge == 0 {
+		return nil
+	}
+
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		// GetImage accepted the reference, so don't fail creation on a
+		// bookkeeping lookup; the policy just cannot be applied.
+		return nil
+	}
+
+	age := time.Since(imagePullTime(img))
+	if age <= i.maxImageAge {
+		return nil
+	}
+
+	if i.maxImageAgeRefuse {
+		return errdefs.Forbidden(errors.Errorf("image %s was pulled %s ago, exceeding the maximum image age of %s; pull it again to run it", refOrID, units.HumanDuration(age), units.HumanDuration(i.maxImageAge)))
+	}
+	log.G(ctx).WithFields(log.Fields{
+		"image": refOrID,
+		"age":   age,
+	}).Warn("container created from an image older than the maximum image age")
+	return nil
+}
+
+// imagePullTime returns when the image record was pulled or last validated
+// at the registry. Records from before the pulled-at label existed fall back
+// to the record creation time.
+func imagePullTime(img containerdimages.Image) time.Time {
+	if t, err := time.Parse(time.RFC3339, img.Labels[imageLabelPulledAt]); err == nil {
+		return t
+	}
+	return img.CreatedAt
+}
+
+// labelImagePullTime stamps the record with the current time; see
+// imageLabelPulledAt.
+func (i *ImageService) labelImagePullTime(ctx context.Context, img containerdimages.Image) {
+	if img.Labels == nil {
+		img.Labels = map[string]string{}
+	}
+	img.Labels[imageLabelPulledAt] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := i.client.ImageService().Update(ctx, img, "labels."+imageLabelPulledAt); err != nil {
+		log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to store pull time label")
+	}
+}
+
+// labelImagePushTime stamps the record with the current time; see
+// imageLabelPushedAt.
+func (i *ImageService) labelImagePushTime(ctx context.Context, img containerdimages.Image) {
+	if img.Labels == nil {
+		img.Labels = map[string]string{}
+	}
+	img.Labels[imageLabelPushedAt] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := i.client.ImageService().Update(ctx, img, "labels."+imageLabelPushedAt); err != nil {
+		log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to store push time label")
+	}
+}
+
+// refreshImagePullTime re-stamps every record sharing the given target, used
+// when the "always" pull policy has just validated that a tag still matches
+// the registry.
+func (i *ImageService) refreshImagePullTime(ctx context.Context, target digest.Digest) {
+	records, err := i.client.ImageService().List(ctx, "target.digest=="+target.String())
+	if err != nil {
+		log.G(ctx).WithError(err).WithField("digest", target).Warn("failed to list records to refresh pull time")
+		return
+	}
+	for _, record := range records {
+		i.labelImagePullTime(ctx, record)
+	}
+}
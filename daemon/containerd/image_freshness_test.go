@@ -0,0 +1,60 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestEnforceImageFreshness(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+	i.maxImageAge = time.Hour
+	i.maxImageAgeRefuse = true
+
+	newImage := func(name, seed string, pulledAt time.Time) containerdimages.Image {
+		img, err := i.client.ImageService().Create(ctx, containerdimages.Image{
+			Name: name,
+			Target: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromString(seed),
+				Size:      42,
+			},
+			Labels:    map[string]string{imageLabelPulledAt: pulledAt.UTC().Format(time.RFC3339)},
+			CreatedAt: pulledAt,
+		})
+		assert.NilError(t, err)
+		return img
+	}
+
+	stale := newImage("docker.io/library/stale:latest", "stale", time.Now().Add(-24*time.Hour))
+	newImage("docker.io/library/fresh:latest", "fresh", time.Now())
+
+	err := i.enforceImageFreshness(ctx, "stale:latest")
+	assert.Check(t, errdefs.IsForbidden(err), "stale image should be refused, got: %v", err)
+
+	assert.NilError(t, i.enforceImageFreshness(ctx, "fresh:latest"))
+
+	// With the default "warn" action stale images are still allowed.
+	i.maxImageAgeRefuse = false
+	assert.NilError(t, i.enforceImageFreshness(ctx, "stale:latest"))
+
+	// Refreshing the pull time, as the "always" pull policy does after
+	// validating the tag against the registry, makes the image fresh again.
+	stalePulledAt := stale.Labels[imageLabelPulledAt]
+	i.maxImageAgeRefuse = true
+	i.refreshImagePullTime(ctx, stale.Target.Digest)
+	assert.NilError(t, i.enforceImageFreshness(ctx, "stale:latest"))
+
+	records, err := i.client.ImageService().List(ctx, "target.digest=="+stale.Target.Digest.String())
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(records, 1))
+	assert.Check(t, records[0].Labels[imageLabelPulledAt] != stalePulledAt)
+}
@@ -15,16 +15,20 @@ import (
 )
 
 // ImageHistory returns a slice of HistoryResponseItem structures for the
-// specified image name by walking the image lineage.
-func (i *ImageService) ImageHistory(ctx context.Context, name string) ([]*imagetype.HistoryResponseItem, error) {
+// specified image name by walking the image lineage. When a platform is
+// requested the history of the matching manifest is returned, rather than
+// the one closest to the host platform.
+func (i *ImageService) ImageHistory(ctx context.Context, name string, platform *ocispec.Platform) ([]*imagetype.HistoryResponseItem, error) {
 	desc, err := i.resolveImage(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
 	cs := i.client.ContentStore()
-	// TODO: pass platform in from the CLI
-	platform := platforms.AllPlatformsWithPreference(cplatforms.Default())
+	comparer := platforms.AllPlatformsWithPreference(cplatforms.Default())
+	if platform != nil {
+		comparer = cplatforms.OnlyStrict(*platform)
+	}
 
 	var presentImages []ocispec.Image
 	err = i.walkImageManifests(ctx, desc, func(img *ImageManifest) error {
@@ -33,7 +37,7 @@ func (i *ImageService) ImageHistory(ctx context.Context, name string) ([]*imaget
 			return err
 		}
 		var ociimage ocispec.Image
-		if err := readConfig(ctx, cs, conf, &ociimage); err != nil {
+		if err := i.readConfig(ctx, cs, conf, &ociimage); err != nil {
 			return err
 		}
 		presentImages = append(presentImages, ociimage)
@@ -47,9 +51,12 @@ func (i *ImageService) ImageHistory(ctx context.Context, name string) ([]*imaget
 	}
 
 	sort.SliceStable(presentImages, func(i, j int) bool {
-		return platform.Less(presentImages[i].Platform, presentImages[j].Platform)
+		return comparer.Less(presentImages[i].Platform, presentImages[j].Platform)
 	})
 	ociimage := presentImages[0]
+	if platform != nil && !comparer.Match(ociimage.Platform) {
+		return nil, errdefs.NotFound(errors.Errorf("image %s was found but does not provide platform %s", name, cplatforms.Format(*platform)))
+	}
 
 	var (
 		history []*imagetype.HistoryResponseItem
@@ -27,6 +27,7 @@ import (
 	"github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ImportImage imports an image, getting the archived layer data from layerReader.
@@ -43,6 +44,18 @@ func (i *ImageService) ImportImage(ctx context.Context, ref reference.Named, pla
 	}
 	logger := log.G(ctx).WithField("ref", refString)
 
+	if ref != nil {
+		if err := validateImageName(ref); err != nil {
+			return "", err
+		}
+		// The imported image is only tagged, not fetched from or uploaded
+		// to the registry, but refuse references into forbidden registries
+		// to not create images that can never be pushed.
+		if err := i.registryService.CheckRegistryIsAllowed(reference.Domain(ref)); err != nil {
+			return "", err
+		}
+	}
+
 	ctx, release, err := i.client.WithLease(ctx)
 	if err != nil {
 		return "", errdefs.System(err)
@@ -89,7 +102,7 @@ func (i *ImageService) ImportImage(ctx context.Context, ref reference.Named, pla
 	}
 
 	dockerCfg := containerConfigToDockerOCIImageConfig(imageConfig)
-	createdAt := time.Now()
+	createdAt := time.Now().UTC()
 	config := imagespec.DockerOCIImage{
 		Image: ocispec.Image{
 			Platform: *platform,
@@ -149,7 +162,7 @@ func (i *ImageService) ImportImage(ctx context.Context, ref reference.Named, pla
 		logger.WithError(err).Debug("failed to save image")
 		return "", err
 	}
-	err = i.unpackImage(ctx, img, *platform)
+	err = i.unpackImage(ctx, img, *platform, i.snapshotter)
 	if err != nil {
 		logger.WithError(err).Debug("failed to unpack image")
 	} else {
@@ -312,15 +325,22 @@ func (i *ImageService) saveImage(ctx context.Context, img images.Image) error {
 	return nil
 }
 
-// unpackImage unpacks the image into the snapshotter.
-func (i *ImageService) unpackImage(ctx context.Context, img images.Image, platform ocispec.Platform) error {
+// unpackImage unpacks the image into the given snapshotter.
+func (i *ImageService) unpackImage(ctx context.Context, img images.Image, platform ocispec.Platform, snapshotter string) (retErr error) {
+	ctx, span := startSpan(ctx, "ImageService.unpackImage",
+		attribute.String("image", img.Name),
+		attribute.String("platform", platforms.Format(platform)),
+		attribute.String("snapshotter", snapshotter),
+	)
+	defer completeSpan(span, &retErr)
+
 	c8dImg := containerd.NewImageWithPlatform(i.client, img, platforms.Only(platform))
-	unpacked, err := c8dImg.IsUnpacked(ctx, i.snapshotter)
+	unpacked, err := c8dImg.IsUnpacked(ctx, snapshotter)
 	if err != nil {
 		return err
 	}
 	if !unpacked {
-		err = c8dImg.Unpack(ctx, i.snapshotter)
+		err = c8dImg.Unpack(ctx, snapshotter, i.unpackOpts()...)
 	}
 
 	return err
@@ -0,0 +1,33 @@
+package containerd
+
+import (
+	"context"
+
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+)
+
+// imageLabelInternal marks image records that were pulled by the daemon for
+// its own use (buildkit frontends, emulation helpers, ...) rather than by a
+// user. Internal images are hidden from listings unless the "internal" filter
+// is given.
+const imageLabelInternal = "org.mobyproject.image.internal"
+
+// isInternalImage reports whether the image record is an internal helper
+// image; see imageLabelInternal.
+func isInternalImage(img containerdimages.Image) bool {
+	_, ok := img.Labels[imageLabelInternal]
+	return ok
+}
+
+// labelImageInternal stores the internal marker as a label on the image
+// record, so listings can hide it without further lookups.
+func (i *ImageService) labelImageInternal(ctx context.Context, img containerdimages.Image) {
+	if img.Labels == nil {
+		img.Labels = map[string]string{}
+	}
+	img.Labels[imageLabelInternal] = "true"
+	if _, err := i.client.ImageService().Update(ctx, img, "labels."+imageLabelInternal); err != nil {
+		log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to store internal image label")
+	}
+}
@@ -0,0 +1,120 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/log"
+	cplatforms "github.com/containerd/containerd/platforms"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/image"
+	imagespec "github.com/docker/docker/image/spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// UpdateImageLabels adds the given labels to an existing image without
+// rebuilding it. Depending on the requested mode the labels are either baked
+// into a derived image config, which produces a new image ID that the
+// resolved reference is updated to point at, or attached to the containerd
+// image record only, leaving the image ID unchanged.
+func (i *ImageService) UpdateImageLabels(ctx context.Context, refOrID string, update imagetypes.LabelsUpdate) (image.ID, error) {
+	if len(update.Labels) == 0 {
+		return "", errdefs.InvalidParameter(errors.New("no labels provided"))
+	}
+
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return "", err
+	}
+
+	switch update.Mode {
+	case imagetypes.LabelsModeRecord:
+		return i.updateRecordLabels(ctx, img, update.Labels)
+	case imagetypes.LabelsModeConfig, "":
+		return i.updateConfigLabels(ctx, img, update.Labels)
+	default:
+		return "", errdefs.InvalidParameter(errors.Errorf("invalid label update mode %q", update.Mode))
+	}
+}
+
+// updateRecordLabels attaches the labels to the containerd image record. The
+// image content is untouched, so the image ID doesn't change and the labels
+// are local to this daemon.
+func (i *ImageService) updateRecordLabels(ctx context.Context, img containerdimages.Image, labels map[string]string) (image.ID, error) {
+	if img.Labels == nil {
+		img.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		img.Labels[k] = v
+	}
+
+	if _, err := i.client.ImageService().Update(ctx, img, "labels"); err != nil {
+		return "", errdefs.System(errors.Wrapf(err, "failed to update labels of image %s", img.Name))
+	}
+
+	i.LogImageEvent(img.Target.Digest.String(), img.Target.Digest.String(), "label")
+	return image.ID(img.Target.Digest), nil
+}
+
+// updateConfigLabels derives a new image config with the labels added and
+// rewrites the manifest to reference it. The resolved image record is updated
+// to point at the new manifest, and the new image ID is returned. The
+// original content is left in place, turning into a dangling image unless
+// it's still referenced.
+func (i *ImageService) updateConfigLabels(ctx context.Context, img containerdimages.Image, labels map[string]string) (image.ID, error) {
+	cs := i.client.ContentStore()
+
+	manifest, err := containerdimages.Manifest(ctx, cs, img.Target, cplatforms.Default())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read image manifest")
+	}
+
+	configBytes, err := content.ReadBlob(ctx, cs, manifest.Config)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read image config")
+	}
+	var imgConfig imagespec.DockerOCIImage
+	if err := json.Unmarshal(configBytes, &imgConfig); err != nil {
+		return "", err
+	}
+
+	if imgConfig.Config.Labels == nil {
+		imgConfig.Config.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		imgConfig.Config.Labels[k] = v
+	}
+
+	// Don't gc me and clean the dirty data after 1 hour!
+	ctx, release, err := i.client.WithLease(ctx, leases.WithRandomID(), leases.WithExpiration(1*time.Hour))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create lease for label update")
+	}
+	defer func() {
+		if err := release(ctx); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to release lease created for label update")
+		}
+	}()
+
+	newManifestDesc, err := writeContentsForImage(ctx, i.snapshotter, cs, imgConfig, manifest.Layers)
+	if err != nil {
+		return "", err
+	}
+
+	newImg := containerdimages.Image{
+		Name:      img.Name,
+		Target:    newManifestDesc,
+		CreatedAt: time.Now(),
+	}
+	if _, err := i.client.ImageService().Update(ctx, newImg); err != nil {
+		return "", errdefs.System(errors.Wrapf(err, "failed to update image %s to the derived config", img.Name))
+	}
+
+	i.LogImageEvent(newManifestDesc.Digest.String(), newManifestDesc.Digest.String(), "label")
+	return image.ID(newManifestDesc.Digest), nil
+}
@@ -2,7 +2,6 @@ package containerd
 
 import (
 	"context"
-	"encoding/json"
 	"sort"
 	"strings"
 	"time"
@@ -40,6 +39,7 @@ var acceptedImageFilterTags = map[string]bool{
 	"since":     true,
 	"reference": true,
 	"until":     true,
+	"internal":  true,
 }
 
 // byCreated is a temporary type used to sort a list of images by creation
@@ -52,7 +52,6 @@ func (r byCreated) Less(i, j int) bool { return r[i].Created < r[j].Created }
 
 // Images returns a filtered list of images.
 //
-// TODO(thaJeztah): implement opts.ContainerCount (used for docker system df); see https://github.com/moby/moby/issues/43853
 // TODO(thaJeztah): verify behavior of `RepoDigests` and `RepoTags` for images without (untagged) or multiple tags; see https://github.com/moby/moby/issues/43861
 // TODO(thaJeztah): verify "Size" vs "VirtualSize" in images; see https://github.com/moby/moby/issues/43862
 func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions) ([]*types.ImageSummary, error) {
@@ -71,17 +70,15 @@ func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions)
 	}
 
 	// TODO(thaJeztah): do we need to take multiple snapshotters into account? See https://github.com/moby/moby/issues/45273
-	snapshotter := i.client.SnapshotService(i.snapshotter)
-	sizeCache := make(map[digest.Digest]int64)
+	usageMap, err := i.getSnapshotUsage(ctx, i.snapshotter)
+	if err != nil {
+		return nil, err
+	}
 	snapshotSizeFn := func(d digest.Digest) (int64, error) {
-		if s, ok := sizeCache[d]; ok {
-			return s, nil
-		}
-		usage, err := snapshotter.Usage(ctx, d.String())
+		usage, err := usageMap.directUsage(d.String())
 		if err != nil {
 			return 0, err
 		}
-		sizeCache[d] = usage.Size
 		return usage.Size, nil
 	}
 
@@ -98,15 +95,34 @@ func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions)
 	contentStore := i.client.ContentStore()
 	uniqueImages := map[digest.Digest]images.Image{}
 	tagsByDigest := map[digest.Digest][]string{}
+	pinnedByDigest := map[digest.Digest]bool{}
+	lastPulledByDigest := map[digest.Digest]time.Time{}
+	lastPushedByDigest := map[digest.Digest]time.Time{}
 
 	for _, img := range imgs {
 		if !filter(img) {
 			continue
 		}
+		// Temporary images backing an in-flight platform-specific push are
+		// an implementation detail; never list them.
+		if isTemporaryImage(img) {
+			continue
+		}
 
 		dgst := img.Target.Digest
 		uniqueImages[dgst] = img
 
+		if isPinnedImage(img) {
+			pinnedByDigest[dgst] = true
+		}
+
+		if t, err := time.Parse(time.RFC3339, img.Labels[imageLabelPulledAt]); err == nil && t.After(lastPulledByDigest[dgst]) {
+			lastPulledByDigest[dgst] = t
+		}
+		if t, err := time.Parse(time.RFC3339, img.Labels[imageLabelPushedAt]); err == nil && t.After(lastPushedByDigest[dgst]) {
+			lastPushedByDigest[dgst] = t
+		}
+
 		if isDanglingImage(img) {
 			continue
 		}
@@ -118,7 +134,46 @@ func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions)
 		tagsByDigest[dgst] = append(tagsByDigest[dgst], reference.FamiliarString(ref))
 	}
 
+	// Order the image records deterministically (newest first) so that a
+	// paginated listing is stable, and cut the page out before the
+	// expensive per-image work below; only the images on the requested
+	// page have their manifests walked and sizes computed.
+	ordered := make([]images.Image, 0, len(uniqueImages))
 	for _, img := range uniqueImages {
+		ordered = append(ordered, img)
+	}
+	sort.Slice(ordered, func(a, b int) bool {
+		if !ordered[a].CreatedAt.Equal(ordered[b].CreatedAt) {
+			return ordered[a].CreatedAt.After(ordered[b].CreatedAt)
+		}
+		return ordered[a].Target.Digest < ordered[b].Target.Digest
+	})
+	if opts.Cursor != "" {
+		next := -1
+		for n, img := range ordered {
+			if img.Target.Digest.String() == opts.Cursor {
+				next = n + 1
+				break
+			}
+		}
+		if next < 0 {
+			return nil, errdefs.InvalidParameter(errors.Errorf("unknown cursor %q", opts.Cursor))
+		}
+		ordered = ordered[next:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(ordered) {
+		ordered = ordered[:opts.Limit]
+	}
+
+	for _, img := range ordered {
+		if summary := artifactSummary(img, tagsByDigest[img.Target.Digest]); summary != nil {
+			summaries = append(summaries, summary)
+			if opts.SharedSize {
+				root = append(root, &[]digest.Digest{})
+			}
+			continue
+		}
+
 		err := i.walkImageManifests(ctx, img, func(img *ImageManifest) error {
 			if isPseudo, err := img.IsPseudoImage(ctx); isPseudo || err != nil {
 				return err
@@ -170,6 +225,28 @@ func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions)
 		}
 	}
 
+	for _, summary := range summaries {
+		if pinnedByDigest[digest.Digest(summary.ID)] {
+			summary.Pinned = true
+		}
+		if t := lastPulledByDigest[digest.Digest(summary.ID)]; !t.IsZero() {
+			summary.LastPulled = t.Unix()
+		}
+		if t := lastPushedByDigest[digest.Digest(summary.ID)]; !t.IsZero() {
+			summary.LastPushed = t.Unix()
+		}
+	}
+
+	if opts.ContainerCount {
+		containersByImage := make(map[digest.Digest]int64)
+		for _, ctr := range i.containers.List() {
+			containersByImage[ctr.ImageID.Digest()]++
+		}
+		for _, summary := range summaries {
+			summary.Containers = containersByImage[digest.Digest(summary.ID)]
+		}
+	}
+
 	sort.Sort(sort.Reverse(byCreated(summaries)))
 
 	return summaries, nil
@@ -182,10 +259,13 @@ func (i *ImageService) singlePlatformImage(ctx context.Context, contentStore con
 	}
 
 	// TODO(thaJeztah): do we need to take multiple snapshotters into account? See https://github.com/moby/moby/issues/45273
-	snapshotter := i.client.SnapshotService(i.snapshotter)
+	usageMap, err := i.getSnapshotUsage(ctx, i.snapshotter)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	imageSnapshotID := identity.ChainID(diffIDs).String()
-	unpackedUsage, err := calculateSnapshotTotalUsage(ctx, snapshotter, imageSnapshotID)
+	unpackedUsage, err := usageMap.totalUsage(imageSnapshotID)
 	if err != nil {
 		if !cerrdefs.IsNotFound(err) {
 			log.G(ctx).WithError(err).WithFields(log.Fields{
@@ -239,7 +319,7 @@ func (i *ImageService) singlePlatformImage(ctx context.Context, contentStore con
 		return nil, nil, err
 	}
 	var cfg configLabels
-	if err := readConfig(ctx, contentStore, cfgDesc, &cfg); err != nil {
+	if err := i.readConfig(ctx, contentStore, cfgDesc, &cfg); err != nil {
 		return nil, nil, err
 	}
 
@@ -262,6 +342,34 @@ func (i *ImageService) singlePlatformImage(ctx context.Context, contentStore con
 	return summary, identity.ChainIDs(diffIDs), nil
 }
 
+// artifactSummary returns a list entry for images that were recognized as OCI
+// artifacts at pull time, or nil for regular images. Artifacts carry no
+// runnable configuration or rootfs, so none of the config-derived fields can
+// be populated.
+func artifactSummary(img images.Image, repoTags []string) *types.ImageSummary {
+	artifactType, ok := img.Labels[imageLabelArtifactType]
+	if !ok {
+		return nil
+	}
+	target := img.Target.Digest
+	var repoDigests []string
+	if ref, err := reference.ParseNamed(img.Name); err == nil {
+		if digested, err := reference.WithDigest(reference.TrimNamed(ref), target); err == nil {
+			repoDigests = append(repoDigests, digested.String())
+		}
+	}
+	return &types.ImageSummary{
+		ID:           target.String(),
+		Created:      img.CreatedAt.Unix(),
+		RepoTags:     repoTags,
+		RepoDigests:  repoDigests,
+		Size:         img.Target.Size,
+		ArtifactType: artifactType,
+		SharedSize:   -1,
+		Containers:   -1,
+	}
+}
+
 type imageFilterFunc func(image images.Image) bool
 
 // setupFilters constructs an imageFilterFunc from the given imageFilters.
@@ -331,7 +439,7 @@ func (i *ImageService) setupFilters(ctx context.Context, imageFilters filters.Ar
 		return nil, err
 	}
 
-	labelFn, err := setupLabelFilter(i.client.ContentStore(), imageFilters)
+	labelFn, err := i.setupLabelFilter(i.client.ContentStore(), imageFilters)
 	if err != nil {
 		return nil, err
 	}
@@ -339,6 +447,22 @@ func (i *ImageService) setupFilters(ctx context.Context, imageFilters filters.Ar
 		fltrs = append(fltrs, labelFn)
 	}
 
+	// Internal helper images are an implementation detail; hide them unless
+	// the listing asks for them explicitly.
+	if imageFilters.Contains("internal") {
+		internalValue, err := imageFilters.GetBoolOrDefault("internal", false)
+		if err != nil {
+			return nil, err
+		}
+		fltrs = append(fltrs, func(image images.Image) bool {
+			return internalValue == isInternalImage(image)
+		})
+	} else {
+		fltrs = append(fltrs, func(image images.Image) bool {
+			return !isInternalImage(image)
+		})
+	}
+
 	if imageFilters.Contains("dangling") {
 		danglingValue, err := imageFilters.GetBoolOrDefault("dangling", false)
 		if err != nil {
@@ -381,7 +505,7 @@ func (i *ImageService) setupFilters(ctx context.Context, imageFilters filters.Ar
 // setupLabelFilter parses filter args for "label" and "label!" and returns a
 // filter func which will check if any image config from the given image has
 // labels that match given predicates.
-func setupLabelFilter(store content.Store, fltrs filters.Args) (func(image images.Image) bool, error) {
+func (i *ImageService) setupLabelFilter(store content.Store, fltrs filters.Args) (func(image images.Image) bool, error) {
 	type labelCheck struct {
 		key        string
 		value      string
@@ -427,7 +551,7 @@ func setupLabelFilter(store content.Store, fltrs filters.Args) (func(image image
 				return nil, nil
 			}
 			var cfg configLabels
-			if err := readConfig(ctx, store, desc, &cfg); err != nil {
+			if err := i.readConfig(ctx, store, desc, &cfg); err != nil {
 				return nil, err
 			}
 
@@ -493,26 +617,3 @@ func computeSharedSize(chainIDs []digest.Digest, layers map[digest.Digest]int, s
 	}
 	return sharedSize, nil
 }
-
-// readConfig reads content pointed by the descriptor and unmarshals it into a specified output.
-func readConfig(ctx context.Context, store content.Provider, desc ocispec.Descriptor, out interface{}) error {
-	data, err := content.ReadBlob(ctx, store, desc)
-	if err != nil {
-		err = errors.Wrapf(err, "failed to read config content")
-		if cerrdefs.IsNotFound(err) {
-			return errdefs.NotFound(err)
-		}
-		return err
-	}
-
-	err = json.Unmarshal(data, out)
-	if err != nil {
-		err = errors.Wrapf(err, "could not deserialize image config")
-		if cerrdefs.IsNotFound(err) {
-			return errdefs.NotFound(err)
-		}
-		return err
-	}
-
-	return nil
-}
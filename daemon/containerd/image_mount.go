@@ -0,0 +1,137 @@
+package containerd
+
+import (
+	"context"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/log"
+	cplatforms "github.com/containerd/containerd/platforms"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// imageMountKey is the snapshot key (and lease ID) used for a read-only view
+// of a mounted image.
+func imageMountKey(dgst digest.Digest) string {
+	return "dockermount-" + dgst.Encoded()
+}
+
+// MountImage mounts the image's filesystem read-only at a daemon-managed
+// location and returns the mount point. The mount is backed by a view
+// snapshot which is protected by a lease, so the image content cannot be
+// garbage-collected until UnmountImage is called.
+func (i *ImageService) MountImage(ctx context.Context, refOrID string, platform *ocispec.Platform) (_ string, retErr error) {
+	desc, err := i.resolveDescriptor(ctx, refOrID)
+	if err != nil {
+		return "", err
+	}
+
+	platMatcher := cplatforms.Default()
+	if platform != nil {
+		platMatcher = cplatforms.Only(*platform)
+	}
+
+	cs := i.client.ContentStore()
+	confDesc, err := containerdimages.Config(ctx, cs, desc, platMatcher)
+	if err != nil {
+		return "", err
+	}
+	diffIDs, err := containerdimages.RootFS(ctx, cs, confDesc)
+	if err != nil {
+		return "", err
+	}
+	parent := identity.ChainID(diffIDs).String()
+
+	key := imageMountKey(desc.Digest)
+
+	lm := i.client.LeasesService()
+	lease, err := lm.Create(ctx, leases.WithID(key))
+	if err != nil {
+		if cerrdefs.IsAlreadyExists(err) {
+			return "", errdefs.Conflict(errors.Errorf("image %s is already mounted", refOrID))
+		}
+		return "", err
+	}
+	defer func() {
+		if retErr != nil {
+			if err := lm.Delete(context.Background(), lease); err != nil {
+				log.G(ctx).WithError(err).WithField("lease", lease.ID).Warn("failed to delete lease created for image mount")
+			}
+		}
+	}()
+	if err := lm.AddResource(ctx, lease, leases.Resource{ID: key, Type: "snapshots/" + i.snapshotter}); err != nil {
+		return "", err
+	}
+
+	sn := i.client.SnapshotService(i.snapshotter)
+	mounts, err := sn.View(ctx, key, parent)
+	if err != nil {
+		if cerrdefs.IsAlreadyExists(err) {
+			return "", errdefs.Conflict(errors.Errorf("image %s is already mounted", refOrID))
+		}
+		return "", err
+	}
+	defer func() {
+		if retErr != nil {
+			if err := sn.Remove(context.Background(), key); err != nil {
+				log.G(ctx).WithError(err).WithField("key", key).Warn("failed to remove view snapshot created for image mount")
+			}
+		}
+	}()
+
+	root, err := i.refCountMounter.Mount(mounts, key)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to mount image %s", refOrID)
+	}
+
+	i.imageMountsMu.Lock()
+	if i.imageMounts == nil {
+		i.imageMounts = map[string]string{}
+	}
+	i.imageMounts[key] = root
+	i.imageMountsMu.Unlock()
+
+	log.G(ctx).WithFields(log.Fields{
+		"image":  refOrID,
+		"digest": desc.Digest,
+		"root":   root,
+	}).Debug("image mounted via snapshotter")
+
+	return root, nil
+}
+
+// UnmountImage unmounts an image previously mounted with MountImage and
+// releases the view snapshot and its lease.
+func (i *ImageService) UnmountImage(ctx context.Context, refOrID string) error {
+	desc, err := i.resolveDescriptor(ctx, refOrID)
+	if err != nil {
+		return err
+	}
+	key := imageMountKey(desc.Digest)
+
+	i.imageMountsMu.Lock()
+	root, mounted := i.imageMounts[key]
+	delete(i.imageMounts, key)
+	i.imageMountsMu.Unlock()
+	if !mounted {
+		return errdefs.NotFound(errors.Errorf("image %s is not mounted", refOrID))
+	}
+
+	if err := i.refCountMounter.Unmount(root); err != nil {
+		return errors.Wrapf(err, "failed to unmount %s", root)
+	}
+
+	if err := i.client.SnapshotService(i.snapshotter).Remove(ctx, key); err != nil && !cerrdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("key", key).Warn("failed to remove view snapshot of unmounted image")
+	}
+	if err := i.client.LeasesService().Delete(ctx, leases.Lease{ID: key}); err != nil && !cerrdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("lease", key).Warn("failed to delete lease of unmounted image")
+	}
+
+	return nil
+}
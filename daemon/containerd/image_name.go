@@ -0,0 +1,23 @@
+package containerd
+
+import (
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// validateImageName rejects references that collide with naming schemes the
+// daemon relies on when resolving images. A repository named after the digest
+// algorithm makes references like sha256:<hex> ambiguous with image IDs, and
+// the dangling-image repository is reserved for the store's own bookkeeping
+// records.
+func validateImageName(ref reference.Named) error {
+	switch name := reference.FamiliarName(ref); name {
+	case string(digest.Canonical):
+		return errdefs.InvalidParameter(errors.Errorf("refusing to create an ambiguous reference: repository name %q collides with image digests", name))
+	case danglingImageRepository:
+		return errdefs.InvalidParameter(errors.Errorf("repository name %q is reserved for internal use", name))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestValidateImageName(t *testing.T) {
+	for _, tc := range []struct {
+		ref   string
+		valid bool
+	}{
+		{ref: "busybox:latest", valid: true},
+		{ref: "docker.io/library/busybox:latest", valid: true},
+		{ref: "registry.example.com:5000/sha512:latest", valid: true},
+		{ref: "sha256:latest", valid: false},
+		{ref: "docker.io/library/sha256:tag", valid: false},
+		{ref: "moby-dangling:latest", valid: false},
+		{ref: "docker.io/library/moby-dangling:tag", valid: false},
+	} {
+		tc := tc
+		t.Run(tc.ref, func(t *testing.T) {
+			named, err := reference.ParseNormalizedNamed(tc.ref)
+			assert.NilError(t, err)
+
+			err = validateImageName(named)
+			if tc.valid {
+				assert.Check(t, err)
+			} else {
+				assert.Check(t, is.ErrorType(err, errdefs.IsInvalidParameter))
+			}
+		})
+	}
+}
+
+func TestReconcileImageNames(t *testing.T) {
+	ctx := context.Background()
+	service := fakeImageService(t)
+	is8 := service.client.ImageService()
+
+	newImage := func(name, seed string) containerdimages.Image {
+		img, err := is8.Create(ctx, containerdimages.Image{
+			Name: name,
+			Target: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromString(seed),
+				Size:      int64(len(seed)),
+			},
+		})
+		assert.NilError(t, err)
+		return img
+	}
+
+	tagged := newImage("docker.io/library/busybox:latest", "tagged")
+	dangling := newImage(danglingImageName(digest.FromString("dangling")), "dangling")
+	ambiguous := newImage("docker.io/library/sha256:tag", "ambiguous")
+	forged := newImage(danglingImageRepository+"@"+digest.FromString("other").String(), "forged")
+
+	service.reconcileImageNames(ctx)
+
+	// Legitimate records are left alone.
+	for _, name := range []string{tagged.Name, dangling.Name} {
+		_, err := is8.Get(ctx, name)
+		assert.Check(t, err, "image %s should have been kept", name)
+	}
+
+	// Conflicting records are renamed to dangling images of their target.
+	for _, img := range []containerdimages.Image{ambiguous, forged} {
+		_, err := is8.Get(ctx, img.Name)
+		assert.Check(t, cerrdefs.IsNotFound(err), "image %s should have been renamed", img.Name)
+		_, err = is8.Get(ctx, danglingImageName(img.Target.Digest))
+		assert.Check(t, err, "image %s should have a dangling replacement", img.Name)
+	}
+}
@@ -0,0 +1,75 @@
+package containerd
+
+import (
+	"context"
+
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// imageLabelPinned marks image records that were pinned with
+// "docker image pin". Pinned images are never removed by image prune or by
+// the automatic image garbage collector, even with the "all" filter. The
+// image record itself keeps the content rooted against containerd's garbage
+// collector, so pinning doesn't need a separate lease.
+const imageLabelPinned = "org.mobyproject.image.pinned"
+
+// isPinnedImage reports whether the image record is pinned; see
+// imageLabelPinned.
+func isPinnedImage(img containerdimages.Image) bool {
+	_, ok := img.Labels[imageLabelPinned]
+	return ok
+}
+
+// PinImage marks the image as pinned, protecting it from image prune and
+// from the automatic image garbage collector. Pinning is a property of the
+// image, not of a single tag: the label is applied to every record sharing
+// the resolved target, so the image survives no matter which of its
+// references a prune considers.
+func (i *ImageService) PinImage(ctx context.Context, refOrID string) error {
+	return i.setImagePinned(ctx, refOrID, true)
+}
+
+// UnpinImage removes the pinned marker from the image, making it eligible
+// for image prune and automatic garbage collection again.
+func (i *ImageService) UnpinImage(ctx context.Context, refOrID string) error {
+	return i.setImagePinned(ctx, refOrID, false)
+}
+
+func (i *ImageService) setImagePinned(ctx context.Context, refOrID string, pinned bool) error {
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return err
+	}
+
+	is := i.client.ImageService()
+	records, err := is.List(ctx, "target.digest=="+img.Target.Digest.String())
+	if err != nil {
+		return errdefs.System(errors.Wrapf(err, "failed to list records of image %s", img.Name))
+	}
+
+	for _, record := range records {
+		if isPinnedImage(record) == pinned {
+			continue
+		}
+		if record.Labels == nil {
+			record.Labels = map[string]string{}
+		}
+		if pinned {
+			record.Labels[imageLabelPinned] = "true"
+		} else {
+			delete(record.Labels, imageLabelPinned)
+		}
+		if _, err := is.Update(ctx, record, "labels"); err != nil {
+			return errdefs.System(errors.Wrapf(err, "failed to update pinned label of %s", record.Name))
+		}
+	}
+
+	action := "pin"
+	if !pinned {
+		action = "unpin"
+	}
+	i.LogImageEvent(img.Target.Digest.String(), refOrID, action)
+	return nil
+}
@@ -0,0 +1,85 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPinUnpinImage(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+
+	is8 := i.client.ImageService()
+	target := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    "sha256:c0de891b2e243280bf1cd8ad710ec3f0931a6e6b6855d76b2d7b0be30e81860b",
+		Size:      42,
+	}
+	// Two records of the same image; pinning either one must protect both.
+	for _, name := range []string{
+		"docker.io/library/busybox:latest",
+		"docker.io/library/busybox@" + target.Digest.String(),
+	} {
+		_, err := is8.Create(ctx, containerdimages.Image{Name: name, Target: target})
+		assert.NilError(t, err)
+	}
+
+	assert.NilError(t, i.PinImage(ctx, "busybox"))
+
+	records, err := is8.List(ctx, "target.digest=="+target.Digest.String())
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(records, 2))
+	for _, record := range records {
+		assert.Check(t, isPinnedImage(record), "record %s should be pinned", record.Name)
+	}
+
+	assert.NilError(t, i.UnpinImage(ctx, "busybox:latest"))
+
+	records, err = is8.List(ctx, "target.digest=="+target.Digest.String())
+	assert.NilError(t, err)
+	for _, record := range records {
+		assert.Check(t, !isPinnedImage(record), "record %s should not be pinned", record.Name)
+	}
+}
+
+func TestPruneSkipsPinnedImages(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+
+	is8 := i.client.ImageService()
+	newImage := func(name, seed string) containerdimages.Image {
+		img, err := is8.Create(ctx, containerdimages.Image{
+			Name: name,
+			Target: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromString(seed),
+				Size:      int64(len(seed)),
+			},
+		})
+		assert.NilError(t, err)
+		return img
+	}
+
+	pinned := newImage("docker.io/library/base:latest", "base")
+	unpinned := newImage("docker.io/library/scratchpad:latest", "scratchpad")
+
+	assert.NilError(t, i.PinImage(ctx, "base"))
+
+	// Prune everything, like `docker image prune -a` with no filters.
+	report, err := i.pruneUnused(ctx, nil, func(containerdimages.Image) bool { return true }, false)
+	assert.NilError(t, err)
+
+	_, err = is8.Get(ctx, pinned.Name)
+	assert.Check(t, err, "pinned image should have been kept")
+	_, err = is8.Get(ctx, unpinned.Name)
+	assert.Check(t, cerrdefs.IsNotFound(err), "unpinned image should have been pruned")
+
+	assert.Check(t, is.Equal(report.ImagesSkipped["pinned"], uint64(1)))
+}
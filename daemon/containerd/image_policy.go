@@ -0,0 +1,58 @@
+package containerd
+
+import (
+	"context"
+
+	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/remotes"
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/imagepolicy"
+	"github.com/pkg/errors"
+)
+
+// admitPull consults the configured image policy plugins before any content
+// for the image is fetched. The reference is resolved at the registry first,
+// so plugins get to decide on the concrete digest rather than a mutable tag.
+func (i *ImageService) admitPull(ctx context.Context, ref reference.Named, resolver remotes.Resolver, options imagetypes.PullOptions) error {
+	if len(i.imagePolicyPlugins) == 0 {
+		return nil
+	}
+
+	_, desc, err := resolver.Resolve(ctx, ref.String())
+	if err != nil {
+		return translatePullError(ctx, ref, err)
+	}
+
+	req := &imagepolicy.PullRequest{
+		Reference: ref.String(),
+		Digest:    desc.Digest.String(),
+		MediaType: desc.MediaType,
+	}
+	if options.AllPlatforms {
+		req.Platforms = []string{"all"}
+	} else {
+		for _, p := range options.Platforms {
+			req.Platforms = append(req.Platforms, platforms.Format(p))
+		}
+	}
+
+	for _, plugin := range i.imagePolicyPlugins {
+		res, err := plugin.AdmitPull(req)
+		if err != nil {
+			return errdefs.System(errors.Wrapf(err, "image policy plugin %s failed", plugin.Name()))
+		}
+		if res.Err != "" {
+			return errdefs.System(errors.Errorf("image policy plugin %s returned an error: %s", plugin.Name(), res.Err))
+		}
+		if !res.Allow {
+			msg := res.Msg
+			if msg == "" {
+				msg = "denied by policy"
+			}
+			return errdefs.Forbidden(errors.Errorf("pull of %s denied by image policy plugin %s: %s", reference.FamiliarString(ref), plugin.Name(), msg))
+		}
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"strings"
 
+	"io"
+
 	cerrdefs "github.com/containerd/containerd/errdefs"
 	containerdimages "github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/log"
@@ -11,6 +13,8 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/hashicorp/go-multierror"
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -28,8 +32,20 @@ var imagesAcceptedFilters = map[string]bool{
 // one is in progress
 var errPruneRunning = errdefs.Conflict(errors.New("a prune operation is already running"))
 
-// ImagesPrune removes unused images
-func (i *ImageService) ImagesPrune(ctx context.Context, fltrs filters.Args) (*types.ImagesPruneReport, error) {
+// Machine-readable reasons for per-image prune failures, reported through
+// the Failures field of the prune response.
+const (
+	pruneFailureContentStore = "content store I/O error"
+	pruneFailureDelete       = "delete failed"
+)
+
+// ImagesPrune removes unused images. When outStream is non-nil, untagged
+// and deleted records are streamed to it as they happen instead of only
+// being collected in the final report.
+func (i *ImageService) ImagesPrune(ctx context.Context, fltrs filters.Args, outStream io.Writer) (_ *types.ImagesPruneReport, retErr error) {
+	ctx, span := startSpan(ctx, "ImageService.ImagesPrune")
+	defer completeSpan(span, &retErr)
+
 	if !i.pruneRunning.CompareAndSwap(false, true) {
 		return nil, errPruneRunning
 	}
@@ -57,11 +73,25 @@ func (i *ImageService) ImagesPrune(ctx context.Context, fltrs filters.Args) (*ty
 		return nil, err
 	}
 
-	return i.pruneUnused(ctx, filterFunc, danglingOnly)
+	var out progress.Output
+	if outStream != nil {
+		out = streamformatter.NewJSONProgressOutput(outStream, false)
+	}
+
+	report, err := i.pruneUnused(ctx, out, filterFunc, danglingOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pruning is the natural moment to also clean up state leaked by
+	// previous daemon crashes.
+	i.ReconcileStorage(ctx)
+
+	return report, nil
 }
 
-func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFunc, danglingOnly bool) (*types.ImagesPruneReport, error) {
-	report := types.ImagesPruneReport{}
+func (i *ImageService) pruneUnused(ctx context.Context, out progress.Output, filterFunc imageFilterFunc, danglingOnly bool) (*types.ImagesPruneReport, error) {
+	report := types.ImagesPruneReport{ImagesSkipped: map[string]uint64{}}
 	is := i.client.ImageService()
 	store := i.client.ContentStore()
 
@@ -77,6 +107,13 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 	for _, img := range allImages {
 		digestRefCount[img.Target.Digest] += 1
 
+		// Pinned images are never pruned, regardless of the filters given;
+		// that's the whole point of pinning.
+		if isPinnedImage(img) {
+			report.ImagesSkipped["pinned"]++
+			continue
+		}
+
 		if !danglingOnly || isDanglingImage(img) {
 			canBePruned := filterFunc(img)
 			log.G(ctx).WithFields(log.Fields{
@@ -86,8 +123,11 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 
 			if canBePruned {
 				imagesToPrune[img.Name] = img
+			} else {
+				report.ImagesSkipped["filter mismatch"]++
 			}
-
+		} else {
+			report.ImagesSkipped["filter mismatch"]++
 		}
 	}
 
@@ -95,9 +135,15 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 	usedDigests := map[digest.Digest]struct{}{}
 
 	// Exclude images used by existing containers
+	skipInUse := func(name string) {
+		if _, ok := imagesToPrune[name]; ok {
+			delete(imagesToPrune, name)
+			report.ImagesSkipped["in use"]++
+		}
+	}
 	for _, ctr := range i.containers.List() {
 		// If the original image was deleted, make sure we don't delete the dangling image
-		delete(imagesToPrune, danglingImageName(ctr.ImageID.Digest()))
+		skipInUse(danglingImageName(ctr.ImageID.Digest()))
 
 		// Config.Image is the image reference passed by user.
 		// Config.ImageID is the resolved content digest based on the user's Config.Image.
@@ -124,7 +170,7 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 		if err == nil {
 			// If user provided a specific image name, exclude that image.
 			name := reference.TagNameOnly(ref)
-			delete(imagesToPrune, name.String())
+			skipInUse(name.String())
 		}
 	}
 
@@ -165,18 +211,39 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 			return nil
 		})
 		if err != nil {
-			errs = multierror.Append(errs, err)
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				errs = multierror.Append(errs, err)
 				return &report, errs
 			}
+			// Recorded per image rather than folded into one flattened
+			// error, so callers can see exactly which images failed and
+			// why the rest of the prune still went ahead.
+			report.Failures = append(report.Failures, types.ImagePruneFailure{
+				Image:  img.Name,
+				Reason: pruneFailureContentStore,
+				Error:  err.Error(),
+			})
 			continue
 		}
 		err = is.Delete(ctx, img.Name, containerdimages.SynchronousDelete())
 		if err != nil && !cerrdefs.IsNotFound(err) {
-			errs = multierror.Append(errs, err)
+			if cerrdefs.IsFailedPrecondition(err) {
+				// Typically a lease held by another containerd client; the
+				// image isn't unused after all, so it's a skip rather than
+				// a failure.
+				log.G(ctx).WithError(err).WithField("image", img.Name).Debug("image is leased, skipping")
+				report.ImagesSkipped["lease held"]++
+				continue
+			}
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				errs = multierror.Append(errs, err)
 				return &report, errs
 			}
+			report.Failures = append(report.Failures, types.ImagePruneFailure{
+				Image:  img.Name,
+				Reason: pruneFailureDelete,
+				Error:  err.Error(),
+			})
 			continue
 		}
 
@@ -185,6 +252,9 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 				Untagged: img.Name,
 			},
 		)
+		if out != nil {
+			progress.Messagef(out, "", "Untagged: %s", img.Name)
+		}
 
 		// Check which blobs have been deleted and sum their sizes
 		for _, blob := range blobs {
@@ -197,10 +267,16 @@ func (i *ImageService) pruneUnused(ctx context.Context, filterFunc imageFilterFu
 					},
 				)
 				report.SpaceReclaimed += uint64(blob.Size)
+				if out != nil {
+					progress.Messagef(out, "", "Deleted: %s", blob.Digest)
+				}
 			}
 		}
 	}
 
+	if len(report.ImagesSkipped) == 0 {
+		report.ImagesSkipped = nil
+	}
 	return &report, errs
 }
 
@@ -3,6 +3,14 @@ package containerd
 import (
 	"context"
 	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/containerd/containerd"
 	cerrdefs "github.com/containerd/containerd/errdefs"
@@ -10,20 +18,54 @@ import (
 	"github.com/containerd/containerd/log"
 	"github.com/containerd/containerd/pkg/snapshotters"
 	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/remotes/docker"
+	remoteerrors "github.com/containerd/containerd/remotes/errors"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/streamformatter"
+	units "github.com/docker/go-units"
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 )
 
 // PullImage initiates a pull operation. image is the repository name to pull, and
 // tagOrDigest may be either empty, or indicate a specific tag or digest to pull.
-func (i *ImageService) PullImage(ctx context.Context, image, tagOrDigest string, platform *ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
+// Multiple platforms (or all platforms available in the remote index) can be
+// requested through options; they are fetched in a single resolver session.
+//
+// TODO: once the vendored containerd client is v1.7 or later, pulls should go
+// through the transfer service when the daemon it's connected to supports it,
+// gaining its built-in progress reporting, concurrency limits and tracing;
+// the resolver-based path below stays as the fallback for older daemons.
+func (i *ImageService) PullImage(ctx context.Context, image, tagOrDigest string, options imagetypes.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) (retErr error) {
+	ctx, span := startSpan(ctx, "ImageService.PullImage", attribute.String("image", image))
+	defer completeSpan(span, &retErr)
+
+	if err := i.ensureConnection(); err != nil {
+		return err
+	}
+
+	// Content committed to the store after this point was transferred by
+	// this pull; see pullResult.
+	start := time.Now()
+
 	var opts []containerd.RemoteOpt
-	if platform != nil {
-		opts = append(opts, containerd.WithPlatform(platforms.Format(*platform)))
+	if !options.AllPlatforms {
+		var formatted []string
+		for _, p := range options.Platforms {
+			formatted = append(formatted, platforms.Format(p))
+			opts = append(opts, containerd.WithPlatform(platforms.Format(p)))
+		}
+		if len(formatted) > 0 {
+			span.SetAttributes(attribute.StringSlice("platforms", formatted))
+		}
 	}
 	ref, err := reference.ParseNormalizedNamed(image)
 	if err != nil {
@@ -45,9 +87,22 @@ func (i *ImageService) PullImage(ctx context.Context, image, tagOrDigest string,
 		}
 	}
 
+	if err := i.registryService.CheckRegistryIsAllowed(reference.Domain(ref)); err != nil {
+		return err
+	}
+
+	snapshotter := i.pullSnapshotter(ref, options.Snapshotter)
+	if snapshotter != i.snapshotter {
+		span.SetAttributes(attribute.String("snapshotter", snapshotter))
+	}
+
 	resolver, _ := i.newResolverFromAuthConfig(ctx, authConfig)
 	opts = append(opts, containerd.WithResolver(resolver))
 
+	if err := i.admitPull(ctx, ref, resolver, options); err != nil {
+		return err
+	}
+
 	jobs := newJobs()
 	h := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
 		if desc.MediaType != images.MediaTypeDockerSchema1Manifest {
@@ -58,35 +113,399 @@ func (i *ImageService) PullImage(ctx context.Context, image, tagOrDigest string,
 	opts = append(opts, containerd.WithImageHandler(h))
 
 	out := streamformatter.NewJSONProgressOutput(outStream, false)
-	finishProgress := jobs.showProgress(ctx, out, pullProgress{Store: i.client.ContentStore(), ShowExists: true})
+	// The extract updater must run before the pull updater, which removes
+	// finished jobs from the tracker.
+	finishProgress := jobs.showProgress(ctx, out, combinedProgress{
+		&extractProgress{Store: i.client.ContentStore(), Snapshotter: i.client.SnapshotService(snapshotter)},
+		pullProgress{Store: i.client.ContentStore(), ShowExists: true},
+	})
 	defer finishProgress()
 
-	opts = append(opts, containerd.WithPullUnpack)
-	// TODO(thaJeztah): we may have to pass the snapshotter to use if the pull is part of a "docker run" (container create -> pull image if missing). See https://github.com/moby/moby/issues/45273
-	opts = append(opts, containerd.WithPullSnapshotter(i.snapshotter))
-
 	// AppendInfoHandlerWrapper will annotate the image with basic information like manifest and layer digests as labels;
 	// this information is used to enable remote snapshotters like nydus and stargz to query a registry.
 	infoHandler := snapshotters.AppendInfoHandlerWrapper(ref.String())
-	opts = append(opts, containerd.WithImageHandlerWrapper(infoHandler))
-
-	img, err := i.client.Pull(ctx, ref.String(), opts...)
+	// Label each fetched blob with the registry/repository it came from so
+	// pushes can cross-repo mount and damaged content can be re-fetched.
+	sourceWrapper, err := i.appendDistributionSourceHandler(ref)
 	if err != nil {
 		return err
 	}
+	handlerWrapper := func(h images.Handler) images.Handler {
+		return sourceWrapper(infoHandler(h))
+	}
+	if limit := i.pullSizeLimit(reference.Domain(ref)); limit > 0 {
+		limitHandler := enforcePullSizeLimit(limit)
+		inner := handlerWrapper
+		handlerWrapper = func(h images.Handler) images.Handler {
+			return limitHandler(inner(h))
+		}
+	}
+	if i.sharedContentStore != "" {
+		// Copy blobs out of the shared read-only content store before the
+		// download handler runs, so only blobs missing from both stores
+		// are fetched from the registry; see shared_content.go.
+		inner := handlerWrapper
+		handlerWrapper = func(h images.Handler) images.Handler {
+			return i.preseedFromSharedStore(inner(h))
+		}
+	}
+	opts = append(opts, containerd.WithImageHandlerWrapper(handlerWrapper))
+
+	var target ocispec.Descriptor
+	if options.AllPlatforms || len(options.Platforms) > 1 || options.ContentOnly {
+		// containerd's Pull only supports a single platform, so fetch the
+		// content for all requested platforms in one resolver session and
+		// unpack the requested ones afterwards. A content-only pull uses
+		// the same path and simply skips the unpack.
+		var fetched images.Image
+		err := i.withDownloadRetries(ctx, out, func() error {
+			var err error
+			fetched, err = i.client.Fetch(ctx, ref.String(), opts...)
+			return err
+		})
+		if err != nil {
+			record, ok := i.resolveDuplicatePull(ctx, ref, err)
+			if !ok {
+				return translatePullError(ctx, ref, err)
+			}
+			fetched = record
+		}
+		target = fetched.Target
+
+		if !options.ContentOnly {
+			// Platforms have independent layer chains, so they can be
+			// unpacked in parallel; the chain ordering constraint only
+			// exists within a platform, where containerd's unpacker
+			// already overlaps decompression with apply.
+			eg, egCtx := errgroup.WithContext(ctx)
+			eg.SetLimit(i.maxConcurrentUnpacks)
+			for _, p := range options.Platforms {
+				p := p
+				eg.Go(func() error {
+					if err := i.unpackImage(egCtx, fetched, p, snapshotter); err != nil {
+						return errors.Wrapf(err, "failed to unpack platform %s", platforms.Format(p))
+					}
+					return nil
+				})
+			}
+			if err := eg.Wait(); err != nil {
+				return err
+			}
+		}
+		if options.AllPlatforms && !options.ContentOnly {
+			// Unpack the host platform if it's present in the index; the
+			// remaining platforms stay content-only.
+			if err := i.unpackImage(ctx, fetched, platforms.DefaultSpec(), snapshotter); err != nil {
+				log.G(ctx).WithError(err).WithField("remote", ref.String()).Warn("host platform not unpacked after pulling all platforms")
+			}
+		}
+	} else {
+		// A single platform unpacks while the pull is still downloading:
+		// containerd's unpacker applies each layer as soon as it and its
+		// parent are ready, overlapping decompression with apply. The
+		// chain ordering constraint leaves nothing else to parallelize
+		// within one platform, so max-concurrent-unpacks plays no part
+		// here; it only bounds the multi-platform branch above.
+		opts = append(opts, containerd.WithPullUnpack)
+		if unpackOpts := i.unpackOpts(); len(unpackOpts) > 0 {
+			opts = append(opts, containerd.WithUnpackOpts(unpackOpts))
+		}
+		// TODO(thaJeztah): we may have to pass the snapshotter to use if the pull is part of a "docker run" (container create -> pull image if missing). See https://github.com/moby/moby/issues/45273
+		opts = append(opts, containerd.WithPullSnapshotter(snapshotter))
+
+		var img containerd.Image
+		err := i.withDownloadRetries(ctx, out, func() error {
+			var err error
+			img, err = i.client.Pull(ctx, ref.String(), opts...)
+			return err
+		})
+		if err != nil {
+			if record, ok := i.resolveDuplicatePull(ctx, ref, err); ok {
+				target = record.Target
+			} else {
+				// Unpacking an OCI artifact fails because there is no image
+				// configuration or layers; report what it is instead of the
+				// confusing unpack error. The content itself was fetched.
+				if record, gerr := i.client.ImageService().Get(ctx, ref.String()); gerr == nil {
+					if artifactType, aerr := i.imageArtifactType(ctx, record); aerr == nil && artifactType != "" {
+						i.labelImageArtifactType(ctx, record)
+						return errdefs.InvalidParameter(errors.Errorf("%s is an OCI artifact of type %q, not an image; it cannot be unpacked or run", reference.FamiliarString(ref), artifactType))
+					}
+				}
+				return translatePullError(ctx, ref, err)
+			}
+		} else {
+			target = img.Target()
+		}
+	}
+	span.SetAttributes(attribute.String("digest", target.Digest.String()))
 
 	logger := log.G(ctx).WithFields(log.Fields{
-		"digest": img.Target().Digest,
+		"digest": target.Digest,
 		"remote": ref.String(),
 	})
 	logger.Info("image pulled")
 
+	// Recognize OCI artifacts (wasm modules, helm charts, ...) at pull time
+	// and label them, so list and inspect can report them as artifacts.
+	if record, err := i.client.ImageService().Get(ctx, ref.String()); err == nil {
+		i.labelImageArtifactType(ctx, record)
+		i.labelImageDistributionSource(ctx, record, ref)
+		i.labelImagePullTime(ctx, record)
+		if options.Internal {
+			i.labelImageInternal(ctx, record)
+		}
+	}
+
 	// The pull succeeded, so try to remove any dangling image we have for this target
-	err = i.client.ImageService().Delete(context.Background(), danglingImageName(img.Target().Digest))
+	err = i.client.ImageService().Delete(context.Background(), danglingImageName(target.Digest))
 	if err != nil && !cerrdefs.IsNotFound(err) {
 		// Image pull succeeded, but cleaning up the dangling image failed. Ignore the
 		// error to not mark the pull as failed.
 		logger.WithError(err).Warn("unexpected error while removing outdated dangling image reference")
 	}
+
+	// Summarize what the pull produced as a final aux message so
+	// automation can capture the resolved digest, the fetched platforms
+	// and the transferred bytes without parsing the progress lines.
+	resultSnapshotter := snapshotter
+	if options.ContentOnly {
+		resultSnapshotter = ""
+	}
+	result := i.pullResult(ctx, target, resultSnapshotter, start)
+
+	// Blobs are fetched only when the content store doesn't already hold
+	// them, no matter which repository they were originally pulled from,
+	// so a pull that shares layers with local images downloads only the
+	// missing ones. Report how much was actually transferred; when
+	// everything was already present the image is simply up to date.
+	if result.BytesTransferred > 0 {
+		progress.Messagef(out, "", "Status: Downloaded newer image for %s (%s transferred)", reference.FamiliarString(ref), units.HumanSize(float64(result.BytesTransferred)))
+	} else {
+		progress.Message(out, "", "Status: Image is up to date for "+reference.FamiliarString(ref))
+	}
+	progress.Aux(out, result)
+
+	// Scan the freshly pulled image in the background when the daemon is
+	// configured to do so; see the scan-on-pull option.
+	i.scanAfterPull(reference.FamiliarString(ref))
+
 	return nil
 }
+
+// resolveDuplicatePull recovers from the race where a concurrent pull of
+// the same reference created the image record first, making this pull fail
+// with "already exists". The image the caller asked for is fully present,
+// so the duplicate pull reports success with the winner's record instead
+// of surfacing the error.
+func (i *ImageService) resolveDuplicatePull(ctx context.Context, ref reference.Named, err error) (images.Image, bool) {
+	if !cerrdefs.IsAlreadyExists(err) {
+		return images.Image{}, false
+	}
+	record, gerr := i.client.ImageService().Get(ctx, ref.String())
+	if gerr != nil {
+		return images.Image{}, false
+	}
+	log.G(ctx).WithField("remote", ref.String()).Info("image was created by a concurrent pull; treating duplicate pull as successful")
+	return record, true
+}
+
+// pullResult summarizes what a finished pull produced for the aux message
+// at the end of the progress stream. It walks the pulled tree in the
+// content store: blobs committed after the pull started count as
+// transferred, blobs that were already present do not, and manifests that
+// are missing entirely belong to platforms that were not requested.
+func (i *ImageService) pullResult(ctx context.Context, target ocispec.Descriptor, snapshotter string, start time.Time) types.PullResult {
+	res := types.PullResult{
+		Digest:      target.Digest.String(),
+		Snapshotter: snapshotter,
+	}
+
+	store := i.client.ContentStore()
+	var mu sync.Mutex
+	handler := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		info, err := store.Info(ctx, desc.Digest)
+		if err != nil {
+			if cerrdefs.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		mu.Lock()
+		if info.CreatedAt.After(start) {
+			res.BytesTransferred += desc.Size
+		}
+		if images.IsManifestType(desc.MediaType) && desc.Platform != nil && desc.Platform.OS != "unknown" {
+			res.Platforms = append(res.Platforms, platforms.Format(*desc.Platform))
+		}
+		mu.Unlock()
+
+		if images.IsManifestType(desc.MediaType) || images.IsIndexType(desc.MediaType) {
+			return images.Children(ctx, store, desc)
+		}
+		return nil, nil
+	})
+	if err := images.Dispatch(ctx, handler, nil, target); err != nil {
+		log.G(ctx).WithError(err).WithField("digest", target.Digest).Warn("failed to summarize what the pull transferred")
+	}
+	sort.Strings(res.Platforms)
+
+	if len(res.Platforms) == 0 && images.IsManifestType(target.MediaType) {
+		// Single-manifest image: the platform only lives in the image
+		// configuration.
+		if cfgDesc, err := images.Config(ctx, store, target, platforms.All); err == nil {
+			var cfg ocispec.Image
+			if err := i.readConfig(ctx, store, cfgDesc, &cfg); err == nil {
+				res.Platforms = []string{platforms.Format(ocispec.Platform{
+					OS:           cfg.OS,
+					Architecture: cfg.Architecture,
+					Variant:      cfg.Variant,
+				})}
+			}
+		}
+	}
+
+	return res
+}
+
+// pullSizeLimit returns the configured maximum pull size for the given
+// registry host, or 0 when pulls from it are unlimited.
+func (i *ImageService) pullSizeLimit(host string) int64 {
+	if limit, ok := i.registryMaxPullSize[host]; ok {
+		return limit
+	}
+	return i.maxPullSize
+}
+
+// enforcePullSizeLimit wraps the image handler so that the pull is aborted as
+// soon as the manifests fetched so far declare more than limit bytes of
+// content, failing before any layer is downloaded.
+func enforcePullSizeLimit(limit int64) func(images.Handler) images.Handler {
+	var total atomic.Int64
+	return func(h images.Handler) images.Handler {
+		return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			children, err := h.Handle(ctx, desc)
+			if err != nil {
+				return nil, err
+			}
+			if images.IsManifestType(desc.MediaType) {
+				var declared int64
+				for _, c := range children {
+					declared += c.Size
+				}
+				if t := total.Add(declared); t > limit {
+					return nil, errdefs.Forbidden(errors.Errorf("image exceeds the maximum allowed pull size: manifest declares %s, limit is %s", units.HumanSize(float64(t)), units.HumanSize(float64(limit))))
+				}
+			}
+			return children, nil
+		})
+	}
+}
+
+// translatePullError converts errors returned from containerd's pull and
+// fetch into the daemon's typed errors, taking care of client disconnects.
+func translatePullError(ctx context.Context, ref reference.Named, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// The client disconnected or gave up; don't log this as a pull
+		// failure and make sure the API reports a clean cancellation.
+		log.G(ctx).WithField("remote", ref.String()).Info("pull has been canceled")
+		return errdefs.Cancelled(errors.Wrap(err, "pull has been canceled"))
+	}
+	var rlErr errRateLimited
+	if errors.As(err, &rlErr) {
+		// Return the rate-limit error itself: it maps to 429 through its
+		// registry error code, and its message carries the remaining quota.
+		return rlErr
+	}
+	return translateConnectionError(err)
+}
+
+// withDownloadRetries runs fetch, which downloads image content through the
+// resolver, and retries it when it fails with an error that a later attempt
+// may recover from; see retryableDownloadError. Blobs downloaded by a failed
+// attempt stay in the content store, so a retry resumes the pull rather than
+// starting over. The number of attempts is the daemon's
+// max-download-attempts setting, and each retry is announced on the progress
+// stream after a linearly growing delay, matching the graphdriver download
+// manager.
+func (i *ImageService) withDownloadRetries(ctx context.Context, out progress.Output, fetch func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fetch()
+		if err == nil {
+			return nil
+		}
+		// If the context was cancelled the error is just fallout; don't
+		// retry, and don't make the caller wait for the backoff either.
+		if ctx.Err() != nil || attempt >= i.maxDownloadAttempts || !retryableDownloadError(err) {
+			if attempt > 1 {
+				log.G(ctx).WithError(err).Errorf("Download failed after %d attempts", attempt)
+			}
+			return err
+		}
+		log.G(ctx).WithError(err).Infof("Download failed, retrying (%d/%d)", attempt, i.maxDownloadAttempts)
+
+		delay := retryDelaySeconds(err, attempt)
+		ticker := time.NewTicker(i.downloadRetryWait)
+	selectLoop:
+		for {
+			progress.Messagef(out, "", "Retrying in %d second%s", delay, (map[bool]string{true: "s"})[delay != 1])
+			select {
+			case <-ticker.C:
+				delay--
+				if delay == 0 {
+					ticker.Stop()
+					break selectLoop
+				}
+			case <-ctx.Done():
+				ticker.Stop()
+				return err
+			}
+		}
+	}
+}
+
+// retryableDownloadError reports whether a failed download is worth retrying:
+// registry responses that signal a transient server-side condition (429 Too
+// Many Requests and the 5xx range) and errors from the connection itself.
+// Responses that cannot succeed on a later attempt, such as 401 Unauthorized
+// or 404 Not Found, as well as local errors, are not retried.
+func retryableDownloadError(err error) bool {
+	var rlErr errRateLimited
+	if errors.As(err, &rlErr) {
+		return true
+	}
+	var statusErr remoteerrors.ErrUnexpectedStatus
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	if errors.Is(err, docker.ErrInvalidAuthorization) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// maxRateLimitDelay caps how long a registry's Retry-After hint can make a
+// single retry wait, so a pull doesn't silently stall for however long the
+// registry suggests.
+const maxRateLimitDelay = 60 * time.Second
+
+// retryDelaySeconds returns how many seconds to wait before the next
+// download attempt. The default backoff grows linearly with the attempt
+// number, matching the graphdriver download manager, but a rate-limited
+// registry's Retry-After hint takes precedence up to maxRateLimitDelay.
+func retryDelaySeconds(err error, attempt int) int {
+	delay := attempt * 5
+	var rlErr errRateLimited
+	if errors.As(err, &rlErr) && rlErr.retryAfter > 0 {
+		delay = int(math.Ceil(rlErr.retryAfter.Seconds()))
+		if limit := int(maxRateLimitDelay / time.Second); delay > limit {
+			delay = limit
+		}
+	}
+	return delay
+}
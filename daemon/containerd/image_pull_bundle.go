@@ -0,0 +1,51 @@
+package containerd
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// PullImageBundle pulls every image in refs sequentially onto the same
+// progress stream. The whole operation runs under a single lease, so content
+// pulled for earlier images cannot be garbage-collected while later ones are
+// still downloading. The first failing pull aborts the bundle; images pulled
+// before the failure are kept.
+func (i *ImageService) PullImageBundle(ctx context.Context, refs []string, options imagetypes.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
+	if err := i.ensureConnection(); err != nil {
+		return err
+	}
+
+	ctx, release, err := i.client.WithLease(ctx)
+	if err != nil {
+		return errdefs.System(err)
+	}
+	defer func() {
+		if err := release(ctx); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to release lease created for bundle pull")
+		}
+	}()
+
+	for _, name := range refs {
+		ref, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return errdefs.InvalidParameter(errors.Wrapf(err, "invalid reference %q", name))
+		}
+		tagOrDigest := ""
+		if tagged, ok := ref.(reference.Tagged); ok {
+			tagOrDigest = tagged.Tag()
+		} else if digested, ok := ref.(reference.Digested); ok {
+			tagOrDigest = digested.Digest().String()
+		}
+		if err := i.PullImage(ctx, ref.Name(), tagOrDigest, options, metaHeaders, authConfig, outStream); err != nil {
+			return errors.Wrapf(err, "failed to pull %s", reference.FamiliarString(ref))
+		}
+	}
+	return nil
+}
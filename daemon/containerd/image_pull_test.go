@@ -0,0 +1,96 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	remoteerrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestRetryableDownloadError(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "server error", err: remoteerrors.ErrUnexpectedStatus{StatusCode: 500, Status: "500 Internal Server Error"}, retryable: true},
+		{name: "too many requests", err: remoteerrors.ErrUnexpectedStatus{StatusCode: 429, Status: "429 Too Many Requests"}, retryable: true},
+		{name: "unauthorized", err: remoteerrors.ErrUnexpectedStatus{StatusCode: 401, Status: "401 Unauthorized"}, retryable: false},
+		{name: "not found", err: remoteerrors.ErrUnexpectedStatus{StatusCode: 404, Status: "404 Not Found"}, retryable: false},
+		{name: "wrapped status", err: errors.Wrap(remoteerrors.ErrUnexpectedStatus{StatusCode: 503, Status: "503 Service Unavailable"}, "failed to do request"), retryable: true},
+		{name: "dns error", err: &net.DNSError{Err: "no such host", Name: "registry.example.com"}, retryable: true},
+		{name: "connection reset", err: errors.Wrap(syscall.ECONNRESET, "failed to read blob"), retryable: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, retryable: true},
+		{name: "local error", err: errors.New("mount: no space left on device"), retryable: false},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Check(t, is.Equal(retryableDownloadError(tc.err), tc.retryable))
+		})
+	}
+}
+
+func TestWithDownloadRetries(t *testing.T) {
+	newService := func(attempts int) *ImageService {
+		return &ImageService{
+			maxDownloadAttempts: attempts,
+			downloadRetryWait:   time.Millisecond,
+		}
+	}
+	out := streamformatter.NewJSONProgressOutput(io.Discard, false)
+	transient := remoteerrors.ErrUnexpectedStatus{StatusCode: 500, Status: "500 Internal Server Error"}
+
+	t.Run("succeeds within budget", func(t *testing.T) {
+		calls := 0
+		err := newService(3).withDownloadRetries(context.Background(), out, func() error {
+			calls++
+			if calls < 3 {
+				return transient
+			}
+			return nil
+		})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(calls, 3))
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		calls := 0
+		err := newService(2).withDownloadRetries(context.Background(), out, func() error {
+			calls++
+			return transient
+		})
+		assert.Check(t, is.Error(err, transient.Error()))
+		assert.Check(t, is.Equal(calls, 2))
+	})
+
+	t.Run("fails fast on permanent errors", func(t *testing.T) {
+		permanent := remoteerrors.ErrUnexpectedStatus{StatusCode: 404, Status: "404 Not Found"}
+		calls := 0
+		err := newService(5).withDownloadRetries(context.Background(), out, func() error {
+			calls++
+			return permanent
+		})
+		assert.Check(t, is.Error(err, permanent.Error()))
+		assert.Check(t, is.Equal(calls, 1))
+	})
+
+	t.Run("does not retry after cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := newService(5).withDownloadRetries(ctx, out, func() error {
+			calls++
+			cancel()
+			return transient
+		})
+		assert.Check(t, is.Error(err, transient.Error()))
+		assert.Check(t, is.Equal(calls, 1))
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -35,13 +36,37 @@ import (
 // pointing to the new target repository. This will allow subsequent pushes
 // to perform cross-repo mounts of the shared content when pushing to a different
 // repository on the same registry.
-func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) (retErr error) {
+//
+// Non-distributable (foreign) layers are skipped unless withNondistributable
+// is set or the target registry is configured to allow them through the
+// allow-nondistributable-artifacts daemon option.
+//
+// When platforms are given, only those platforms' manifests are pushed
+// instead of the whole index, via a temporary image that is removed
+// afterwards.
+//
+// TODO: once the vendored containerd client is v1.7 or later, pushes should go
+// through the transfer service when the daemon it's connected to supports it
+// (see the corresponding note on PullImage), keeping this resolver-based path
+// as the fallback for older daemons.
+func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named, pushPlatforms []ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer, withNondistributable bool) (retErr error) {
+	ctx, span := startSpan(ctx, "ImageService.PushImage", attribute.String("image", targetRef.String()))
+	defer completeSpan(span, &retErr)
+
 	if _, tagged := targetRef.(reference.Tagged); !tagged {
 		if _, digested := targetRef.(reference.Digested); !digested {
 			return errdefs.NotImplemented(errors.New("push all tags is not implemented"))
 		}
 	}
 
+	if err := i.ensureConnection(); err != nil {
+		return err
+	}
+
+	if err := i.registryService.CheckRegistryIsAllowed(reference.Domain(targetRef)); err != nil {
+		return err
+	}
+
 	leasedCtx, release, err := i.client.WithLease(ctx)
 	if err != nil {
 		return err
@@ -62,6 +87,18 @@ func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named,
 	target := img.Target
 	store := i.client.ContentStore()
 
+	if len(pushPlatforms) > 0 {
+		// Extract the requested platforms into a temporary image and push
+		// that instead of the full index; see push_platform.go for how
+		// leftovers of an interrupted push are cleaned up.
+		tmpImg, err := i.createTempPlatformImage(ctx, img, pushPlatforms)
+		if err != nil {
+			return err
+		}
+		defer i.deleteTempPlatformImage(tmpImg.Name)
+		target = tmpImg.Target
+	}
+
 	resolver, tracker := i.newResolverFromAuthConfig(ctx, authConfig)
 	pp := pushProgress{Tracker: tracker}
 	jobsQueue := newJobs()
@@ -73,7 +110,7 @@ func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named,
 		finishProgress()
 		if retErr == nil {
 			if tagged, ok := targetRef.(reference.Tagged); ok {
-				progress.Messagef(out, "", "%s: digest: %s size: %d", tagged.Tag(), target.Digest, img.Target.Size)
+				progress.Messagef(out, "", "%s: digest: %s size: %d", tagged.Tag(), target.Digest, target.Size)
 			}
 		}
 	}()
@@ -98,6 +135,12 @@ func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named,
 	if err != nil {
 		return err
 	}
+	if i.pushChunkSize > 0 {
+		pusher, err = i.newChunkedPusher(pusher, targetRef, authConfig, tracker, mountableBlobs)
+		if err != nil {
+			return err
+		}
+	}
 
 	addChildrenToJobs := containerdimages.HandlerFunc(
 		func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
@@ -115,12 +158,26 @@ func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named,
 		},
 	)
 
+	if !withNondistributable {
+		withNondistributable = i.registryService.AllowsNondistributableArtifacts(reference.Domain(targetRef))
+	}
+
 	handlerWrapper := func(h images.Handler) images.Handler {
-		return containerdimages.Handlers(addChildrenToJobs, h)
+		handler := containerdimages.Handlers(addChildrenToJobs, h)
+		if !withNondistributable {
+			handler = remotes.SkipNonDistributableBlobs(handler.Handle)
+		}
+		return handler
 	}
 
 	err = remotes.PushContent(ctx, pusher, target, store, limiter, platforms.All, handlerWrapper)
 	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// The client disconnected or gave up; stop the upload promptly
+			// and report a clean cancellation instead of a push failure.
+			log.G(ctx).WithField("image", targetRef.String()).Info("push has been canceled")
+			return errdefs.Cancelled(errors.Wrap(err, "push has been canceled"))
+		}
 		if containerdimages.IsIndexType(target.MediaType) {
 			if cerrdefs.IsNotFound(err) {
 				err = errdefs.NotFound(fmt.Errorf(
@@ -145,6 +202,19 @@ func (i *ImageService) PushImage(ctx context.Context, targetRef reference.Named,
 			// because it doesn't affect the pushed data.
 			log.G(ctx).WithError(err).Warn("failed to append distribution source labels to pushed content")
 		}
+
+		// Record when the image was last pushed on its record, next to
+		// the pull provenance labels.
+		i.labelImagePushTime(ctx, img)
+
+		if i.pushSigningKey != nil {
+			// A daemon configured for signing must not report a push as
+			// successful without its signature in place, so a signing
+			// failure fails the push even though the image data is up.
+			if err := i.signPushedManifest(ctx, resolver, targetRef, target, out); err != nil {
+				return errdefs.System(errors.Wrap(err, "failed to sign pushed image"))
+			}
+		}
 	}
 
 	return err
@@ -0,0 +1,114 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ExportReferences dumps the name-to-digest mapping of the image store, so
+// backup tools can save tags separately from content.
+func (i *ImageService) ExportReferences(ctx context.Context) (map[string]string, error) {
+	imgs, err := i.client.ImageService().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string, len(imgs))
+	for _, img := range imgs {
+		if isDanglingImage(img) {
+			continue
+		}
+		refs[img.Name] = img.Target.Digest.String()
+	}
+	return refs, nil
+}
+
+// ImportReferences re-associates image references with their targets after a
+// content-level restore. References whose target manifest is not present in
+// the content store are reported as skipped instead of failing the import.
+func (i *ImageService) ImportReferences(ctx context.Context, refs map[string]string) (*imagetypes.ReferencesRestoreReport, error) {
+	cs := i.client.ContentStore()
+	report := &imagetypes.ReferencesRestoreReport{}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		named, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return nil, errdefs.InvalidParameter(errors.Wrapf(err, "invalid reference %q", name))
+		}
+		named = reference.TagNameOnly(named)
+		if err := validateImageName(named); err != nil {
+			return nil, err
+		}
+
+		dgst, err := digest.Parse(refs[name])
+		if err != nil {
+			return nil, errdefs.InvalidParameter(errors.Wrapf(err, "invalid digest for reference %q", name))
+		}
+
+		info, err := cs.Info(ctx, dgst)
+		if err != nil {
+			if cerrdefs.IsNotFound(err) {
+				report.Skipped = append(report.Skipped, named.String())
+				continue
+			}
+			return nil, errdefs.System(err)
+		}
+
+		desc := ocispec.Descriptor{Digest: dgst, Size: info.Size}
+		desc.MediaType, err = detectManifestMediaType(ctx, cs, desc)
+		if err != nil {
+			return nil, errdefs.System(errors.Wrapf(err, "failed to detect media type of manifest %s", dgst))
+		}
+
+		if err := i.saveImage(ctx, images.Image{Name: named.String(), Target: desc}); err != nil {
+			return nil, err
+		}
+		report.Restored = append(report.Restored, named.String())
+	}
+
+	return report, nil
+}
+
+// detectManifestMediaType returns the media type a manifest blob declares in
+// its body; the content store itself doesn't record media types.
+func detectManifestMediaType(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (string, error) {
+	blob, err := content.ReadBlob(ctx, cs, desc)
+	if err != nil {
+		return "", err
+	}
+	var partial struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(blob, &partial); err != nil {
+		return "", err
+	}
+	if partial.MediaType != "" {
+		return partial.MediaType, nil
+	}
+	// OCI manifests may omit their media type; tell manifests and indexes
+	// apart by shape.
+	var index struct {
+		Manifests []json.RawMessage `json:"manifests"`
+	}
+	if err := json.Unmarshal(blob, &index); err == nil && index.Manifests != nil {
+		return ocispec.MediaTypeImageIndex, nil
+	}
+	return ocispec.MediaTypeImageManifest, nil
+}
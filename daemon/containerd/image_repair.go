@@ -0,0 +1,159 @@
+package containerd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes"
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// repairImageContent attempts to re-fetch the image's missing and corrupt
+// blobs from the registries the image was pulled from, using the recorded
+// distribution source labels (falling back to the image's own reference).
+// Descriptors that were fetched successfully are removed from v and their
+// digests returned; blobs that could not be repaired stay in v. When a
+// manifest is re-fetched, the content it references is verified and
+// repaired as well before the image is considered repaired.
+func (i *ImageService) repairImageContent(ctx context.Context, v *imageVerification) (repaired []string) {
+	sources := i.repairSources(ctx, v.img)
+	if len(sources) == 0 {
+		log.G(ctx).WithField("image", v.img.Target.Digest).Warn("no registry source known for image, cannot repair")
+		return nil
+	}
+
+	cs := i.client.ContentStore()
+	resolver, _ := i.newResolverFromAuthConfig(ctx, nil)
+
+	repairOne := func(desc ocispec.Descriptor, corrupt bool) bool {
+		if corrupt {
+			if err := cs.Delete(ctx, desc.Digest); err != nil && !cerrdefs.IsNotFound(err) {
+				log.G(ctx).WithError(err).WithField("digest", desc.Digest).Warn("failed to delete corrupt blob before repair")
+				return false
+			}
+		}
+		for _, source := range sources {
+			if err := fetchBlob(ctx, resolver, cs, source, desc); err != nil {
+				log.G(ctx).WithError(err).WithFields(log.Fields{
+					"digest": desc.Digest,
+					"source": source,
+				}).Warn("failed to fetch blob for repair")
+				continue
+			}
+			repaired = append(repaired, desc.Digest.String())
+			return true
+		}
+		return false
+	}
+
+	for {
+		var missing, corrupt []ocispec.Descriptor
+		var fetchedManifest bool
+		for _, desc := range v.missing {
+			if repairOne(desc, false) {
+				fetchedManifest = fetchedManifest || isManifestLike(desc)
+			} else {
+				missing = append(missing, desc)
+			}
+		}
+		for _, desc := range v.corrupt {
+			if repairOne(desc, true) {
+				fetchedManifest = fetchedManifest || isManifestLike(desc)
+			} else {
+				corrupt = append(corrupt, desc)
+			}
+		}
+		v.missing, v.corrupt = missing, corrupt
+
+		if !fetchedManifest {
+			return repaired
+		}
+		// A re-fetched manifest references config and layer blobs that
+		// could not be enumerated while it was broken; verify the tree
+		// again and repair what it now reaches. This terminates: every
+		// extra round requires another manifest to have been repaired,
+		// and repaired content stays present and valid.
+		m, c, err := i.verifyContentTree(ctx, v.img.Target)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("image", v.img.Target.Digest).Warn("failed to re-verify image after repairing manifests")
+			return repaired
+		}
+		v.missing, v.corrupt = m, c
+		if len(m) == 0 && len(c) == 0 {
+			return repaired
+		}
+	}
+}
+
+// isManifestLike reports whether the descriptor references a manifest or an
+// index, i.e. content that references further blobs.
+func isManifestLike(desc ocispec.Descriptor) bool {
+	return containerdimages.IsManifestType(desc.MediaType) || containerdimages.IsIndexType(desc.MediaType)
+}
+
+// repairSources returns the references the image's blobs can be re-fetched
+// from: the repositories recorded in distribution source labels on the
+// image's present content, and the image's own reference if it is tagged.
+func (i *ImageService) repairSources(ctx context.Context, img containerdimages.Image) []string {
+	var sources []string
+	seen := map[string]struct{}{}
+	add := func(ref string) {
+		if _, dup := seen[ref]; !dup {
+			seen[ref] = struct{}{}
+			sources = append(sources, ref)
+		}
+	}
+
+	if !isDanglingImage(img) {
+		if ref, err := reference.ParseNamed(img.Name); err == nil {
+			add(reference.TrimNamed(ref).String())
+		}
+	}
+
+	cs := i.client.ContentStore()
+	err := i.walkPresentChildren(ctx, img.Target, func(ctx context.Context, desc ocispec.Descriptor) error {
+		info, err := cs.Info(ctx, desc.Digest)
+		if err != nil {
+			return nil
+		}
+		for label, repos := range info.Labels {
+			if !strings.HasPrefix(label, labelDistributionSource) {
+				continue
+			}
+			host := strings.TrimPrefix(label, labelDistributionSource)
+			for _, repo := range strings.Split(repos, ",") {
+				if repo != "" {
+					add(host + "/" + repo)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.G(ctx).WithError(err).WithField("image", img.Target.Digest).Warn("failed to collect distribution sources for repair")
+	}
+
+	return sources
+}
+
+// fetchBlob fetches a single blob from the given repository and commits it
+// to the content store. The digest of the fetched data is verified by the
+// content store on commit.
+func fetchBlob(ctx context.Context, resolver remotes.Resolver, cs content.Store, source string, desc ocispec.Descriptor) error {
+	fetcher, err := resolver.Fetcher(ctx, source)
+	if err != nil {
+		return err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return content.WriteBlob(ctx, cs, remotes.MakeRefKey(ctx, desc), rc, desc)
+}
@@ -0,0 +1,322 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/mount"
+	cplatforms "github.com/containerd/containerd/platforms"
+	imagetype "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/dockerversion"
+	"github.com/docker/docker/errdefs"
+	"github.com/google/uuid"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	spdxjson "github.com/spdx/tools-golang/json"
+	spdxcommon "github.com/spdx/tools-golang/spdx/common"
+	spdx "github.com/spdx/tools-golang/spdx/v2_3"
+)
+
+// mediaTypeSPDXJSON is the media (and artifact) type of SPDX documents in
+// JSON encoding.
+const mediaTypeSPDXJSON = "application/spdx+json"
+
+// imageLabelSubject is set on image records whose manifest names another
+// manifest as its subject (referrer artifacts, like generated SBOMs). It
+// holds the subject digest, so the referrers of an image can be listed
+// without reading every manifest back.
+const imageLabelSubject = "org.mobyproject.image.subject"
+
+// sbomImageRepository is the repository the daemon uses for bookkeeping
+// records of generated SBOM artifacts. Like moby-dangling, it is reserved.
+const sbomImageRepository = "moby-sbom"
+
+func sbomImageName(dgst digest.Digest) string {
+	return sbomImageRepository + "@" + dgst.String()
+}
+
+// GenerateImageSBOM scans the image's filesystem with the embedded scanner
+// and stores the resulting SPDX document as a referrer artifact in the
+// content store: an OCI artifact manifest whose subject is the image, with
+// the document as its single layer. The artifact is kept alive by an
+// internal image record and is listed by ImageReferrers. A previously
+// generated SBOM of the image is replaced. External scanners can attach
+// their own results the same way by pushing a referrer manifest.
+func (i *ImageService) GenerateImageSBOM(ctx context.Context, refOrID string, options imagetype.SBOMOptions) (*imagetype.SBOMReport, error) {
+	switch options.Format {
+	case "", imagetype.SBOMFormatSPDXJSON:
+	default:
+		return nil, errdefs.InvalidParameter(errors.Errorf("unsupported SBOM format %q", options.Format))
+	}
+
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	platMatcher := cplatforms.Default()
+	if options.Platform != nil {
+		platMatcher = cplatforms.Only(*options.Platform)
+	}
+
+	cs := i.client.ContentStore()
+	confDesc, err := containerdimages.Config(ctx, cs, img.Target, platMatcher)
+	if err != nil {
+		return nil, err
+	}
+	diffIDs, err := containerdimages.RootFS(ctx, cs, confDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, files, err := i.scanImageFilesystem(ctx, refOrID, img.Target.Digest, identity.ChainID(diffIDs).String())
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := spdxjson.Save2_3(doc, &buf); err != nil {
+		return nil, errdefs.System(errors.Wrap(err, "failed to encode SPDX document"))
+	}
+
+	mfstDesc, err := i.writeReferrerArtifact(ctx, img.Target, mediaTypeSPDXJSON, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	i.LogImageEvent(img.Target.Digest.String(), refOrID, "sbom")
+
+	return &imagetype.SBOMReport{
+		Digest: mfstDesc.Digest.String(),
+		Format: imagetype.SBOMFormatSPDXJSON,
+		Size:   int64(buf.Len()),
+		Files:  files,
+	}, nil
+}
+
+// scanImageFilesystem mounts a read-only view of the image's unpacked
+// snapshot and inventories its files into an SPDX document.
+func (i *ImageService) scanImageFilesystem(ctx context.Context, refOrID string, target digest.Digest, chainID string) (*spdx.Document, int, error) {
+	rnd, err := uuid.NewRandom()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sn := i.client.SnapshotService(i.snapshotter)
+	mounts, err := sn.View(ctx, rnd.String(), chainID)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, 0, errdefs.Conflict(errors.Errorf("image %s is not unpacked in the %s snapshotter", refOrID, i.snapshotter))
+		}
+		return nil, 0, err
+	}
+	defer func() {
+		if err := sn.Remove(ctx, rnd.String()); err != nil {
+			log.G(ctx).WithError(err).WithField("key", rnd.String()).Warn("remove temporary snapshot of scanned image")
+		}
+	}()
+
+	pkg := &spdx.Package{
+		PackageName:             refOrID,
+		PackageSPDXIdentifier:   "Package-image",
+		PackageVersion:          target.String(),
+		PackageDownloadLocation: "NOASSERTION",
+		FilesAnalyzed:           true,
+	}
+
+	err = mount.WithReadonlyTempMount(ctx, mounts, func(root string) error {
+		n := 0
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			sum1, sum256, err := fileChecksums(path)
+			if err != nil {
+				return err
+			}
+			pkg.Files = append(pkg.Files, &spdx.File{
+				FileName:           "/" + filepath.ToSlash(rel),
+				FileSPDXIdentifier: spdxcommon.ElementID(fmt.Sprintf("File-%d", n)),
+				Checksums: []spdxcommon.Checksum{
+					{Algorithm: spdxcommon.SHA1, Value: sum1},
+					{Algorithm: spdxcommon.SHA256, Value: sum256},
+				},
+			})
+			n++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to scan filesystem of image %s", refOrID)
+	}
+	sort.Slice(pkg.Files, func(a, b int) bool { return pkg.Files[a].FileName < pkg.Files[b].FileName })
+
+	doc := &spdx.Document{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXIdentifier:    "DOCUMENT",
+		DocumentName:      refOrID,
+		DocumentNamespace: "https://mobyproject.org/spdxdocs/" + rnd.String(),
+		CreationInfo: &spdx.CreationInfo{
+			Creators: []spdxcommon.Creator{
+				{CreatorType: "Tool", Creator: "docker-" + dockerversion.Version},
+			},
+			Created: time.Now().UTC().Format(time.RFC3339),
+		},
+		Packages: []*spdx.Package{pkg},
+		Relationships: []*spdx.Relationship{
+			{
+				RefA:         spdxcommon.MakeDocElementID("", "DOCUMENT"),
+				RefB:         spdxcommon.MakeDocElementID("", "Package-image"),
+				Relationship: "DESCRIBES",
+			},
+		},
+	}
+	return doc, len(pkg.Files), nil
+}
+
+// fileChecksums returns the hex-encoded SHA1 and SHA256 digests of the file
+// at path. SHA1 is mandatory for SPDX file entries; SHA256 is what the rest
+// of the image machinery speaks.
+func fileChecksums(path string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	h1, h256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(h1, h256), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h1.Sum(nil)), hex.EncodeToString(h256.Sum(nil)), nil
+}
+
+// writeReferrerArtifact stores blob in the content store and wraps it in an
+// OCI artifact manifest of the given artifact type whose subject is the
+// image's target. The manifest is rooted against garbage collection by an
+// internal image record labeled with the subject digest; existing referrer
+// records of the same artifact type for the same subject are replaced.
+func (i *ImageService) writeReferrerArtifact(ctx context.Context, subject ocispec.Descriptor, artifactType string, blob []byte) (ocispec.Descriptor, error) {
+	cs := i.client.ContentStore()
+
+	blobDesc := ocispec.Descriptor{
+		MediaType: artifactType,
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+
+	subjectDesc := subject
+	subjectDesc.Annotations = nil
+
+	mfst := ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Config:       ocispec.DescriptorEmptyJSON,
+		Layers:       []ocispec.Descriptor{blobDesc},
+		Subject:      &subjectDesc,
+	}
+	mfstJSON, err := json.MarshalIndent(mfst, "", "    ")
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	mfstDesc := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Digest:       digest.FromBytes(mfstJSON),
+		Size:         int64(len(mfstJSON)),
+	}
+
+	if err := content.WriteBlob(ctx, cs, blobDesc.Digest.String(), bytes.NewReader(blob), blobDesc); err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to write artifact blob")
+	}
+	if err := content.WriteBlob(ctx, cs, ocispec.DescriptorEmptyJSON.Digest.String(), bytes.NewReader(ocispec.DescriptorEmptyJSON.Data), ocispec.DescriptorEmptyJSON); err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to write artifact config")
+	}
+	labels := map[string]string{
+		"containerd.io/gc.ref.content.0": ocispec.DescriptorEmptyJSON.Digest.String(),
+		"containerd.io/gc.ref.content.1": blobDesc.Digest.String(),
+	}
+	if err := content.WriteBlob(ctx, cs, mfstDesc.Digest.String(), bytes.NewReader(mfstJSON), mfstDesc, content.WithLabels(labels)); err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to write artifact manifest")
+	}
+
+	is := i.client.ImageService()
+
+	// Replace any previous artifact of the same type for this subject.
+	old, err := is.List(ctx, fmt.Sprintf(`labels.%q==%s`, imageLabelSubject, subject.Digest.String()))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	for _, record := range old {
+		if record.Labels[imageLabelArtifactType] != artifactType || record.Target.Digest == mfstDesc.Digest {
+			continue
+		}
+		if err := is.Delete(ctx, record.Name); err != nil && !cerrdefs.IsNotFound(err) {
+			log.G(ctx).WithError(err).WithField("image", record.Name).Warn("failed to delete superseded referrer artifact")
+		}
+	}
+
+	_, err = is.Create(ctx, containerdimages.Image{
+		Name:   sbomImageName(mfstDesc.Digest),
+		Target: mfstDesc,
+		Labels: map[string]string{
+			imageLabelInternal:     "true",
+			imageLabelSubject:      subject.Digest.String(),
+			imageLabelArtifactType: artifactType,
+		},
+	})
+	if err != nil && !cerrdefs.IsAlreadyExists(err) {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to create artifact image record")
+	}
+
+	return mfstDesc, nil
+}
+
+// ImageReferrers lists the artifacts stored by the daemon that reference the
+// image, such as generated SBOMs, as OCI descriptors.
+func (i *ImageService) ImageReferrers(ctx context.Context, refOrID string) ([]ocispec.Descriptor, error) {
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := i.client.ImageService().List(ctx, fmt.Sprintf(`labels.%q==%s`, imageLabelSubject, img.Target.Digest.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	referrers := make([]ocispec.Descriptor, 0, len(records))
+	for _, record := range records {
+		desc := record.Target
+		if desc.ArtifactType == "" {
+			desc.ArtifactType = record.Labels[imageLabelArtifactType]
+		}
+		referrers = append(referrers, desc)
+	}
+	sort.Slice(referrers, func(a, b int) bool { return referrers[a].Digest < referrers[b].Digest })
+	return referrers, nil
+}
@@ -0,0 +1,61 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	containerdimages "github.com/containerd/containerd/images"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestWriteReferrerArtifact(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+
+	is8 := i.client.ImageService()
+	target := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    "sha256:c0de891b2e243280bf1cd8ad710ec3f0931a6e6b6855d76b2d7b0be30e81860b",
+		Size:      42,
+	}
+	_, err := is8.Create(ctx, containerdimages.Image{Name: "docker.io/library/busybox:latest", Target: target})
+	assert.NilError(t, err)
+
+	mfstDesc, err := i.writeReferrerArtifact(ctx, target, mediaTypeSPDXJSON, []byte(`{"spdxVersion":"SPDX-2.3"}`))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(mediaTypeSPDXJSON, mfstDesc.ArtifactType))
+
+	// The stored manifest must name the image as its subject.
+	mfstJSON, err := content.ReadBlob(ctx, i.client.ContentStore(), mfstDesc)
+	assert.NilError(t, err)
+	var mfst ocispec.Manifest
+	assert.NilError(t, json.Unmarshal(mfstJSON, &mfst))
+	assert.Assert(t, mfst.Subject != nil)
+	assert.Check(t, is.Equal(target.Digest, mfst.Subject.Digest))
+
+	referrers, err := i.ImageReferrers(ctx, "busybox")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(referrers, 1))
+	assert.Check(t, is.Equal(mfstDesc.Digest, referrers[0].Digest))
+	assert.Check(t, is.Equal(mediaTypeSPDXJSON, referrers[0].ArtifactType))
+
+	// Regenerating replaces the previous artifact of the same type.
+	newDesc, err := i.writeReferrerArtifact(ctx, target, mediaTypeSPDXJSON, []byte(`{"spdxVersion":"SPDX-2.3","name":"updated"}`))
+	assert.NilError(t, err)
+
+	referrers, err = i.ImageReferrers(ctx, "busybox")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(referrers, 1))
+	assert.Check(t, is.Equal(newDesc.Digest, referrers[0].Digest))
+
+	// The artifact records are internal bookkeeping and must stay hidden
+	// from regular image listings.
+	records, err := is8.List(ctx, "name=="+sbomImageName(newDesc.Digest))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(records, 1))
+	assert.Check(t, isInternalImage(records[0]))
+}
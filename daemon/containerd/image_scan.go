@@ -0,0 +1,209 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/mount"
+	cplatforms "github.com/containerd/containerd/platforms"
+	imagetype "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/imagescan"
+	"github.com/google/uuid"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+	"github.com/pkg/errors"
+)
+
+// mediaTypeScanResults is the media (and artifact) type the daemon stores
+// full scan reports under. The report payload itself is in the scanner
+// plugin's format.
+const mediaTypeScanResults = "application/vnd.mobyproject.image.scan.v1+json"
+
+// Labels holding the outcome of the latest vulnerability scan on image
+// records. Like pinning, a scan is a property of the image, so the labels
+// are applied to every record sharing the scanned target.
+const (
+	imageLabelScanStatus  = "org.mobyproject.image.scan.status"
+	imageLabelScanSummary = "org.mobyproject.image.scan.summary"
+	imageLabelScanTime    = "org.mobyproject.image.scan.time"
+	imageLabelScanDigest  = "org.mobyproject.image.scan.digest"
+)
+
+// ScanImage scans the image's filesystem with the configured scanner plugin
+// and records the outcome: the status and summary are stored as labels on
+// the image records, and the full report, when the plugin produces one, is
+// stored as a referrer artifact next to any generated SBOM. A previous scan
+// of the image is replaced.
+func (i *ImageService) ScanImage(ctx context.Context, refOrID string) (*imagetype.ScanReport, error) {
+	if i.imageScanPlugin == nil {
+		return nil, errdefs.NotImplemented(errors.New("no image scan plugin is configured"))
+	}
+
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := i.client.ContentStore()
+	confDesc, err := containerdimages.Config(ctx, cs, img.Target, cplatforms.Default())
+	if err != nil {
+		return nil, err
+	}
+	diffIDs, err := containerdimages.RootFS(ctx, cs, confDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &imagescan.ScanRequest{
+		Reference: refOrID,
+		Digest:    img.Target.Digest.String(),
+	}
+	// Hand the scanner the digest of the image's SBOM artifact, if one has
+	// been generated, so it can scan the inventory instead of the files.
+	for _, referrer := range i.listReferrerRecords(ctx, img.Target.Digest, mediaTypeSPDXJSON) {
+		req.SBOM = referrer.Target.Digest.String()
+	}
+
+	var res *imagescan.ScanResponse
+	err = i.withImageView(ctx, identity.ChainID(diffIDs).String(), func(root string) error {
+		req.RootFS = root
+		res, err = i.imageScanPlugin.Scan(req)
+		return err
+	})
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, errdefs.Conflict(errors.Errorf("image %s is not unpacked in the %s snapshotter", refOrID, i.snapshotter))
+		}
+		return nil, errdefs.System(errors.Wrapf(err, "image scan plugin %s failed", i.imageScanPlugin.Name()))
+	}
+	if res.Err != "" {
+		return nil, errdefs.System(errors.Errorf("image scan plugin %s returned an error: %s", i.imageScanPlugin.Name(), res.Err))
+	}
+
+	report := &imagetype.ScanReport{
+		Status:    res.Status,
+		Summary:   res.Summary,
+		ScannedAt: time.Now().UTC(),
+	}
+	if len(res.Results) > 0 {
+		mfstDesc, err := i.writeReferrerArtifact(ctx, img.Target, mediaTypeScanResults, res.Results)
+		if err != nil {
+			return nil, err
+		}
+		report.ResultsDigest = mfstDesc.Digest.String()
+	}
+
+	if err := i.setImageScanLabels(ctx, img, report); err != nil {
+		return nil, err
+	}
+
+	i.LogImageEvent(img.Target.Digest.String(), refOrID, "scan")
+	return report, nil
+}
+
+// ImageScanStatus returns the outcome of the latest scan of the image, or a
+// not-found error when it has never been scanned.
+func (i *ImageService) ImageScanStatus(ctx context.Context, refOrID string) (*imagetype.ScanReport, error) {
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, ok := img.Labels[imageLabelScanStatus]
+	if !ok {
+		return nil, errdefs.NotFound(errors.Errorf("image %s has not been scanned", refOrID))
+	}
+
+	report := &imagetype.ScanReport{
+		Status:        status,
+		Summary:       img.Labels[imageLabelScanSummary],
+		ResultsDigest: img.Labels[imageLabelScanDigest],
+	}
+	if t, err := time.Parse(time.RFC3339, img.Labels[imageLabelScanTime]); err == nil {
+		report.ScannedAt = t
+	}
+	return report, nil
+}
+
+// scanAfterPull runs the configured scanner against a freshly pulled image
+// in the background; see the scan-on-pull daemon option. Failures are logged
+// rather than surfaced, the pull itself has already succeeded.
+func (i *ImageService) scanAfterPull(refOrID string) {
+	if !i.scanOnPull || i.imageScanPlugin == nil {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		if _, err := i.ScanImage(ctx, refOrID); err != nil {
+			log.G(ctx).WithError(err).WithField("image", refOrID).Warn("post-pull image scan failed")
+		}
+	}()
+}
+
+// withImageView mounts a read-only view of the given snapshot chain at a
+// temporary location for the duration of the callback.
+func (i *ImageService) withImageView(ctx context.Context, chainID string, fn func(root string) error) error {
+	rnd, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+
+	sn := i.client.SnapshotService(i.snapshotter)
+	mounts, err := sn.View(ctx, rnd.String(), chainID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sn.Remove(ctx, rnd.String()); err != nil {
+			log.G(ctx).WithError(err).WithField("key", rnd.String()).Warn("remove temporary view snapshot")
+		}
+	}()
+
+	return mount.WithReadonlyTempMount(ctx, mounts, fn)
+}
+
+// listReferrerRecords returns the bookkeeping image records of referrer
+// artifacts of the given artifact type whose subject is the given digest.
+func (i *ImageService) listReferrerRecords(ctx context.Context, subject digest.Digest, artifactType string) []containerdimages.Image {
+	records, err := i.client.ImageService().List(ctx, fmt.Sprintf(`labels.%q==%s`, imageLabelSubject, subject.String()))
+	if err != nil {
+		log.G(ctx).WithError(err).WithField("subject", subject).Warn("failed to list referrer artifacts")
+		return nil
+	}
+	var matched []containerdimages.Image
+	for _, record := range records {
+		if record.Labels[imageLabelArtifactType] == artifactType {
+			matched = append(matched, record)
+		}
+	}
+	return matched
+}
+
+// setImageScanLabels stores the scan outcome as labels on every record
+// sharing the scanned target.
+func (i *ImageService) setImageScanLabels(ctx context.Context, img containerdimages.Image, report *imagetype.ScanReport) error {
+	is := i.client.ImageService()
+	records, err := is.List(ctx, "target.digest=="+img.Target.Digest.String())
+	if err != nil {
+		return errdefs.System(errors.Wrapf(err, "failed to list records of image %s", img.Name))
+	}
+
+	for _, record := range records {
+		if record.Labels == nil {
+			record.Labels = map[string]string{}
+		}
+		record.Labels[imageLabelScanStatus] = report.Status
+		record.Labels[imageLabelScanSummary] = report.Summary
+		record.Labels[imageLabelScanTime] = report.ScannedAt.Format(time.RFC3339)
+		record.Labels[imageLabelScanDigest] = report.ResultsDigest
+		if _, err := is.Update(ctx, record, "labels"); err != nil {
+			return errdefs.System(errors.Wrapf(err, "failed to update scan labels of %s", record.Name))
+		}
+	}
+	return nil
+}
@@ -2,14 +2,13 @@ package containerd
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/containerd/containerd"
 	cerrdefs "github.com/containerd/containerd/errdefs"
 	containerdimages "github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/log"
 	"github.com/containerd/containerd/platforms"
-	"github.com/containerd/containerd/snapshots"
 	"github.com/docker/docker/errdefs"
 	"github.com/opencontainers/image-spec/identity"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -27,10 +26,11 @@ func (i *ImageService) PrepareSnapshot(ctx context.Context, id string, parentIma
 
 		cs := i.client.ContentStore()
 
-		matcher := platforms.Default()
+		p := platforms.DefaultSpec()
 		if platform != nil {
-			matcher = platforms.Only(*platform)
+			p = *platform
 		}
+		matcher := platforms.Only(p)
 
 		platformImg := containerd.NewImageWithPlatform(i.client, img, matcher)
 		unpacked, err := platformImg.IsUnpacked(ctx, i.snapshotter)
@@ -39,8 +39,20 @@ func (i *ImageService) PrepareSnapshot(ctx context.Context, id string, parentIma
 		}
 
 		if !unpacked {
-			if err := platformImg.Unpack(ctx, i.snapshotter); err != nil {
-				return err
+			if err := platformImg.Unpack(ctx, i.snapshotter, i.unpackOpts()...); err != nil {
+				// The platform may have been pulled content-only, or not
+				// pulled at all (e.g. running a foreign platform through
+				// binfmt when only the host platform was pulled). Fetch
+				// what is missing and retry before giving up.
+				if !cerrdefs.IsNotFound(errors.Cause(err)) {
+					return err
+				}
+				if err := i.fetchMissingPlatform(ctx, img, p); err != nil {
+					return err
+				}
+				if err := platformImg.Unpack(ctx, i.snapshotter, i.unpackOpts()...); err != nil {
+					return errors.Wrapf(err, "failed to unpack platform %s", platforms.Format(p))
+				}
 			}
 		}
 
@@ -76,45 +88,27 @@ func (i *ImageService) PrepareSnapshot(ctx context.Context, id string, parentIma
 	return err
 }
 
-// calculateSnapshotParentUsage returns the usage of all ancestors of the
-// provided snapshot. It doesn't include the size of the snapshot itself.
-func calculateSnapshotParentUsage(ctx context.Context, snapshotter snapshots.Snapshotter, snapshotID string) (snapshots.Usage, error) {
-	info, err := snapshotter.Stat(ctx, snapshotID)
-	if err != nil {
-		if cerrdefs.IsNotFound(err) {
-			return snapshots.Usage{}, errdefs.NotFound(err)
-		}
-		return snapshots.Usage{}, errdefs.System(errors.Wrapf(err, "snapshotter.Stat failed for %s", snapshotID))
-	}
-	if info.Parent == "" {
-		return snapshots.Usage{}, errdefs.NotFound(fmt.Errorf("snapshot %s has no parent", snapshotID))
+// fetchMissingPlatform pulls the content of the given platform for an image
+// that is only partially present locally, using the image's own reference.
+// Progress is reported through the daemon log; there is no client stream at
+// container create time.
+func (i *ImageService) fetchMissingPlatform(ctx context.Context, img containerdimages.Image, platform ocispec.Platform) error {
+	if isDanglingImage(img) {
+		return errdefs.NotFound(errors.Errorf("platform %s of image %s is not available locally and the image has no reference to pull it from", platforms.Format(platform), img.Target.Digest))
 	}
 
-	return calculateSnapshotTotalUsage(ctx, snapshotter, info.Parent)
-}
-
-// calculateSnapshotTotalUsage returns the total usage of that snapshot
-// including all of its ancestors.
-func calculateSnapshotTotalUsage(ctx context.Context, snapshotter snapshots.Snapshotter, snapshotID string) (snapshots.Usage, error) {
-	var total snapshots.Usage
-	next := snapshotID
-
-	for next != "" {
-		usage, err := snapshotter.Usage(ctx, next)
-		if err != nil {
-			if cerrdefs.IsNotFound(err) {
-				return total, errdefs.NotFound(errors.Wrapf(err, "non-existing ancestor of %s", snapshotID))
-			}
-			return total, errdefs.System(errors.Wrapf(err, "snapshotter.Usage failed for %s", next))
-		}
-		total.Size += usage.Size
-		total.Inodes += usage.Inodes
+	log.G(ctx).WithFields(log.Fields{
+		"image":    img.Name,
+		"platform": platforms.Format(platform),
+	}).Info("pulling missing platform for container create")
 
-		info, err := snapshotter.Stat(ctx, next)
-		if err != nil {
-			return total, errdefs.System(errors.Wrapf(err, "snapshotter.Stat failed for %s", next))
-		}
-		next = info.Parent
+	resolver, _ := i.newResolverFromAuthConfig(ctx, nil)
+	_, err := i.client.Fetch(ctx, img.Name,
+		containerd.WithResolver(resolver),
+		containerd.WithPlatform(platforms.Format(platform)),
+	)
+	if err != nil {
+		return errors.Wrapf(translateConnectionError(err), "platform %s of image %s is not available locally and could not be pulled", platforms.Format(platform), img.Name)
 	}
-	return total, nil
+	return nil
 }
@@ -0,0 +1,100 @@
+package containerd
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// appendDistributionSourceHandler wraps the image handler so that every blob
+// stored during the pull is labelled with the registry and repository it came
+// from. The labels enable cross-repo mounts on push, repair of damaged
+// content without a full re-pull, and provenance auditing while offline.
+func (i *ImageService) appendDistributionSourceHandler(ref reference.Named) (func(containerdimages.Handler) containerdimages.Handler, error) {
+	sourceHandler, err := docker.AppendDistributionSourceLabel(i.client.ContentStore(), ref.String())
+	if err != nil {
+		return nil, err
+	}
+	return func(h containerdimages.Handler) containerdimages.Handler {
+		return containerdimages.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			children, err := h.Handle(ctx, desc)
+			if err != nil {
+				return nil, err
+			}
+			// The inner handler has fetched the content by now, so the
+			// label can be attached to it.
+			if _, err := sourceHandler.Handle(ctx, desc); err != nil {
+				log.G(ctx).WithError(err).WithField("digest", desc.Digest).Debug("failed to append distribution source label")
+			}
+			return children, nil
+		})
+	}, nil
+}
+
+// labelImageDistributionSource records the registry and repository the image
+// was pulled from as a label on the image record itself, mirroring the labels
+// on its blobs.
+func (i *ImageService) labelImageDistributionSource(ctx context.Context, img containerdimages.Image, ref reference.Named) {
+	key := labelDistributionSource + reference.Domain(ref)
+	repo := reference.Path(ref)
+
+	if img.Labels == nil {
+		img.Labels = map[string]string{}
+	}
+	if existing := img.Labels[key]; existing != "" {
+		found := false
+		for _, r := range strings.Split(existing, ",") {
+			if r == repo {
+				found = true
+				break
+			}
+		}
+		if found {
+			return
+		}
+		repo = existing + "," + repo
+	}
+	img.Labels[key] = repo
+	if _, err := i.client.ImageService().Update(ctx, img, "labels."+key); err != nil {
+		log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to store distribution source label")
+	}
+}
+
+// imageDistributionSources returns the registry repositories the image is
+// known to have been pulled from, as "host/repository" strings, sorted. They
+// are collected from the distribution source labels on the image record and
+// on its target blob.
+func (i *ImageService) imageDistributionSources(ctx context.Context, img containerdimages.Image) []string {
+	seen := map[string]struct{}{}
+	collect := func(labels map[string]string) {
+		for label, repos := range labels {
+			if !strings.HasPrefix(label, labelDistributionSource) {
+				continue
+			}
+			host := strings.TrimPrefix(label, labelDistributionSource)
+			for _, repo := range strings.Split(repos, ",") {
+				if repo != "" {
+					seen[host+"/"+repo] = struct{}{}
+				}
+			}
+		}
+	}
+
+	collect(img.Labels)
+	if info, err := i.client.ContentStore().Info(ctx, img.Target.Digest); err == nil {
+		collect(info.Labels)
+	}
+
+	sources := make([]string, 0, len(seen))
+	for source := range seen {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
@@ -13,7 +13,16 @@ import (
 )
 
 // TagImage creates an image named as newTag and targeting the given descriptor id.
+//
+// Tagging is a metadata-only operation: it must not unpack the target into a
+// snapshot, as the image may be for a foreign platform and the unpack would
+// be both expensive and useless. Images are unpacked on demand when a
+// container is created from them.
 func (i *ImageService) TagImage(ctx context.Context, imageID image.ID, newTag reference.Named) error {
+	if err := validateImageName(newTag); err != nil {
+		return err
+	}
+
 	target, err := i.resolveDescriptor(ctx, imageID.String())
 	if err != nil {
 		return errors.Wrapf(err, "failed to resolve image id %q to a descriptor", imageID.String())
@@ -43,6 +52,12 @@ func (i *ImageService) TagImage(ctx context.Context, imageID image.ID, newTag re
 			return nil
 		}
 
+		if replacedName, err := reference.ParseNormalizedNamed(replacedImg.Name); err == nil {
+			if image.IsProtectedTag(i.protectedTags, replacedName) {
+				return errdefs.Forbidden(errors.Errorf("tag %s is protected and cannot be moved; delete it with force first", reference.FamiliarString(replacedName)))
+			}
+		}
+
 		// If there already exists an image with this tag, delete it
 		if err := i.softImageDelete(ctx, replacedImg); err != nil {
 			return errors.Wrapf(err, "failed to delete previous image %s", replacedImg.Name)
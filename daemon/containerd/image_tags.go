@@ -0,0 +1,39 @@
+package containerd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
+)
+
+// ImageTags returns every reference pointing at the same target digest as
+// the given image, as familiar strings. The image itself may be referred to
+// by any reference or ID prefix.
+func (i *ImageService) ImageTags(ctx context.Context, refOrID string) ([]string, error) {
+	img, err := i.resolveImage(ctx, refOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	imgs, err := i.client.ImageService().List(ctx, "target.digest=="+img.Target.Digest.String())
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, len(imgs))
+	for _, img := range imgs {
+		if isDanglingImage(img) {
+			continue
+		}
+		ref, err := reference.ParseNamed(img.Name)
+		if err != nil {
+			log.G(ctx).WithField("name", img.Name).WithError(err).Error("failed to parse image name as reference")
+			continue
+		}
+		refs = append(refs, reference.FamiliarString(ref))
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
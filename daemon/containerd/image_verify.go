@@ -0,0 +1,168 @@
+package containerd
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// VerifyImages walks every blob referenced by the local images, re-hashes it
+// and reports content that is missing or no longer matches its digest (for
+// example after disk corruption). When options.Repair is set, broken blobs
+// are re-fetched from the registries the image was pulled from. When
+// options.Delete is set, images that remain broken are deleted.
+func (i *ImageService) VerifyImages(ctx context.Context, options imagetypes.VerifyOptions) (*imagetypes.VerifyReport, error) {
+	imgs, err := i.client.ImageService().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &imagetypes.VerifyReport{}
+	tagsByDigest := map[string][]string{}
+	verifications := map[string]*imageVerification{}
+
+	for _, img := range imgs {
+		dgst := img.Target.Digest.String()
+		if !isDanglingImage(img) {
+			if ref, err := reference.ParseNamed(img.Name); err == nil {
+				tagsByDigest[dgst] = append(tagsByDigest[dgst], reference.FamiliarString(ref))
+			}
+		}
+
+		if _, done := verifications[dgst]; done {
+			continue
+		}
+		report.ImagesVerified++
+
+		missing, corrupt, err := i.verifyContentTree(ctx, img.Target)
+		if err != nil {
+			return nil, err
+		}
+		verifications[dgst] = &imageVerification{
+			img:     img,
+			missing: missing,
+			corrupt: corrupt,
+		}
+	}
+
+	for dgst, v := range verifications {
+		if len(v.missing) == 0 && len(v.corrupt) == 0 {
+			continue
+		}
+
+		res := imagetypes.VerifyResult{
+			ID:       dgst,
+			RepoTags: tagsByDigest[dgst],
+		}
+
+		if options.Repair {
+			res.Repaired = i.repairImageContent(ctx, v)
+		}
+		for _, desc := range v.missing {
+			res.Missing = append(res.Missing, desc.Digest.String())
+		}
+		for _, desc := range v.corrupt {
+			res.Corrupt = append(res.Corrupt, desc.Digest.String())
+		}
+
+		if len(res.Missing) == 0 && len(res.Corrupt) == 0 {
+			// Everything was repaired.
+			report.Broken = append(report.Broken, res)
+			continue
+		}
+
+		if options.Delete {
+			if _, err := i.ImageDelete(ctx, dgst, true, false); err != nil {
+				log.G(ctx).WithError(err).WithField("image", dgst).Warn("failed to delete broken image")
+			} else {
+				res.Deleted = true
+			}
+		}
+		report.Broken = append(report.Broken, res)
+	}
+
+	return report, nil
+}
+
+// imageVerification holds the intermediate result of verifying one image.
+type imageVerification struct {
+	img     containerdimages.Image
+	missing []ocispec.Descriptor
+	corrupt []ocispec.Descriptor
+}
+
+// verifyContentTree walks the descriptor tree rooted at target and returns
+// the descriptors of blobs that are missing from the content store and of
+// blobs whose content does not match their digest. Only content reachable
+// through locally present manifests is verified: platform manifests of an
+// index that were never pulled are expected to be absent and do not count
+// as missing. Children of missing or corrupt manifests cannot be
+// enumerated and are not reported.
+func (i *ImageService) verifyContentTree(ctx context.Context, target ocispec.Descriptor) (missing, corrupt []ocispec.Descriptor, _ error) {
+	cs := i.client.ContentStore()
+
+	handler := containerdimages.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		ok, err := verifyBlob(ctx, cs, desc)
+		if err != nil {
+			if cerrdefs.IsNotFound(err) {
+				missing = append(missing, desc)
+				return nil, nil
+			}
+			return nil, err
+		}
+		if !ok {
+			corrupt = append(corrupt, desc)
+			return nil, nil
+		}
+		children, err := containerdimages.Children(ctx, cs, desc)
+		if err != nil {
+			return nil, err
+		}
+		if containerdimages.IsIndexType(desc.MediaType) {
+			// An index references the manifests of every platform, but a
+			// pull only stores the platforms it was asked for. Manifests
+			// that aren't present were never pulled, not damaged; only
+			// descend into the ones the store has.
+			present := children[:0]
+			for _, child := range children {
+				if _, err := cs.Info(ctx, child.Digest); err != nil {
+					if cerrdefs.IsNotFound(err) {
+						continue
+					}
+					return nil, err
+				}
+				present = append(present, child)
+			}
+			children = present
+		}
+		return children, nil
+	})
+
+	if err := containerdimages.Walk(ctx, handler, target); err != nil {
+		return nil, nil, err
+	}
+	return missing, corrupt, nil
+}
+
+// verifyBlob re-hashes the blob's content and reports whether it still
+// matches the digest it is stored under.
+func verifyBlob(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (bool, error) {
+	ra, err := cs.ReaderAt(ctx, desc)
+	if err != nil {
+		return false, err
+	}
+	defer ra.Close()
+
+	verifier := desc.Digest.Verifier()
+	if _, err := io.Copy(verifier, content.NewReader(ra)); err != nil {
+		return false, err
+	}
+	return verifier.Verified(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/mount"
 	"github.com/docker/docker/container"
 )
 
@@ -28,6 +29,46 @@ func (i *ImageService) Mount(ctx context.Context, container *container.Container
 	return nil
 }
 
+// MountReadOnly mounts the container filesystem read-only in a temporary
+// location, for operations that only read from it, such as docker cp out of
+// a stopped container. The container's writable rootfs mount is left alone,
+// so readers do not share mount reference counts with a concurrent start.
+// Use imageService.Unmount to release the mount.
+func (i *ImageService) MountReadOnly(ctx context.Context, container *container.Container) error {
+	snapshotter := i.client.SnapshotService(container.Driver)
+	mounts, err := snapshotter.Mounts(ctx, container.ID)
+	if err != nil {
+		return err
+	}
+
+	var root string
+	if root, err = i.refCountMounter.Mount(readOnlyMounts(mounts), container.ID+"-ro"); err != nil {
+		return fmt.Errorf("failed to mount %s: %w", root, err)
+	}
+
+	log.G(ctx).WithField("container", container.ID).Debugf("container mounted read-only via snapshotter: %v", root)
+
+	container.BaseFS = root
+	return nil
+}
+
+// readOnlyMounts returns a copy of mounts with the read-only option set.
+func readOnlyMounts(mounts []mount.Mount) []mount.Mount {
+	ro := make([]mount.Mount, len(mounts))
+	for i, m := range mounts {
+		opts := make([]string, 0, len(m.Options)+1)
+		for _, o := range m.Options {
+			if o == "rw" {
+				continue
+			}
+			opts = append(opts, o)
+		}
+		m.Options = append(opts, "ro")
+		ro[i] = m
+	}
+	return ro
+}
+
 // Unmount unmounts the container base filesystem
 func (i *ImageService) Unmount(ctx context.Context, container *container.Container) error {
 	root := container.BaseFS
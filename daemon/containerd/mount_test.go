@@ -0,0 +1,20 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd/mount"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestReadOnlyMounts(t *testing.T) {
+	ro := readOnlyMounts([]mount.Mount{
+		{Type: "bind", Source: "/src", Options: []string{"rbind", "rw"}},
+		{Type: "overlay", Source: "overlay", Options: []string{"lowerdir=/l", "upperdir=/u", "workdir=/w"}},
+	})
+
+	assert.Assert(t, is.Len(ro, 2))
+	assert.Check(t, is.DeepEqual(ro[0].Options, []string{"rbind", "ro"}))
+	assert.Check(t, is.DeepEqual(ro[1].Options, []string{"lowerdir=/l", "upperdir=/u", "workdir=/w", "ro"}))
+}
@@ -2,18 +2,23 @@ package containerd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/containerd/containerd/content"
 	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/log"
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/containerd/snapshots"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -124,6 +129,13 @@ func (p pullProgress) UpdateProgress(ctx context.Context, ongoing *jobs, out pro
 		pulling[status.Ref] = status
 	}
 
+	// Aggregate of all in-flight downloads, used for the overall ETA line.
+	var (
+		aggrCurrent int64
+		aggrTotal   int64
+		downloading int
+	)
+
 	for _, j := range ongoing.Jobs() {
 		key := remotes.MakeRefKey(ctx, j)
 		if info, ok := pulling[key]; ok {
@@ -131,8 +143,14 @@ func (p pullProgress) UpdateProgress(ctx context.Context, ongoing *jobs, out pro
 				ID:      stringid.TruncateID(j.Digest.Encoded()),
 				Action:  "Downloading",
 				Current: info.Offset,
-				Total:   info.Total,
+				// StartedAt lets clients compute the per-layer transfer
+				// rate and an ETA from the offset.
+				Start: info.StartedAt.Unix(),
+				Total: info.Total,
 			})
+			downloading++
+			aggrCurrent += info.Offset
+			aggrTotal += info.Total
 			continue
 		}
 
@@ -159,9 +177,165 @@ func (p pullProgress) UpdateProgress(ctx context.Context, ongoing *jobs, out pro
 			ongoing.Remove(j)
 		}
 	}
+
+	if downloading > 1 && aggrTotal > 0 {
+		// Summary line across all in-flight layers so CLI users and CI
+		// logs can tell whether the pull as a whole is stalled.
+		out.WriteProgress(progress.Progress{
+			ID:      "Total",
+			Action:  "Downloading",
+			Current: aggrCurrent,
+			Total:   aggrTotal,
+			Start:   start.Unix(),
+		})
+	}
+	return nil
+}
+
+// extractProgress reports per-layer "Extracting" and "Pull complete" lines
+// for the unpack phase of a pull, matching the legacy image store
+// experience. It maps the layer chain IDs being committed to the snapshotter
+// back to the layer digests shown during download, and polls the active
+// extract snapshot for the bytes applied so far.
+type extractProgress struct {
+	Store       content.Store
+	Snapshotter snapshots.Snapshotter
+
+	mu     sync.Mutex
+	parsed map[digest.Digest]struct{} // manifests already inspected
+	layers []extractingLayer          // layers in apply order
+	states map[digest.Digest]int      // layer digest -> extractState
+}
+
+type extractingLayer struct {
+	layer   ocispec.Descriptor
+	chainID digest.Digest
+}
+
+const (
+	extractStateNone = iota
+	extractStateExtracting
+	extractStateDone
+)
+
+func (p *extractProgress) UpdateProgress(ctx context.Context, ongoing *jobs, out progress.Output, start time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.parsed == nil {
+		p.parsed = map[digest.Digest]struct{}{}
+		p.states = map[digest.Digest]int{}
+	}
+
+	// Resolve the layer chain as soon as the manifest and config have been
+	// downloaded; the jobs tracker sees every descriptor of the pull.
+	for _, j := range ongoing.Jobs() {
+		if images.IsManifestType(j.MediaType) {
+			if _, done := p.parsed[j.Digest]; !done {
+				p.parseManifest(ctx, j)
+			}
+		}
+	}
+
+	// Collect the active extract snapshots, keyed by the chain ID they are
+	// unpacking, to report bytes applied.
+	active := map[string]string{}
+	err := p.Snapshotter.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
+		if name, ok := unpackKeyChainID(info.Name); ok {
+			active[name] = info.Name
+		}
+		return nil
+	}, "kind==active")
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		log.G(ctx).WithError(err).Debug("failed to walk active snapshots for extract progress")
+	}
+
+	for _, l := range p.layers {
+		id := stringid.TruncateID(l.layer.Digest.Encoded())
+		switch p.states[l.layer.Digest] {
+		case extractStateDone:
+			continue
+		case extractStateNone, extractStateExtracting:
+			if key, ok := active[l.chainID.String()]; ok {
+				pg := progress.Progress{
+					ID:     id,
+					Action: "Extracting",
+					Total:  l.layer.Size,
+				}
+				if usage, err := p.Snapshotter.Usage(ctx, key); err == nil {
+					pg.Current = usage.Size
+				}
+				out.WriteProgress(pg)
+				p.states[l.layer.Digest] = extractStateExtracting
+				continue
+			}
+			info, err := p.Snapshotter.Stat(ctx, l.chainID.String())
+			if err != nil {
+				continue
+			}
+			// Only report completion for snapshots created by this pull
+			// (or observed extracting); pre-existing chains are reported
+			// as "Already exists" by the download progress.
+			if p.states[l.layer.Digest] == extractStateExtracting || info.Created.After(start) {
+				out.WriteProgress(progress.Progress{
+					ID:         id,
+					Action:     "Pull complete",
+					HideCounts: true,
+					LastUpdate: true,
+				})
+			}
+			p.states[l.layer.Digest] = extractStateDone
+		}
+	}
 	return nil
 }
 
+// parseManifest resolves the layer descriptors and their chain IDs once the
+// manifest and its config are available in the content store.
+func (p *extractProgress) parseManifest(ctx context.Context, desc ocispec.Descriptor) {
+	mfstBytes, err := content.ReadBlob(ctx, p.Store, desc)
+	if err != nil {
+		return
+	}
+	var mfst ocispec.Manifest
+	if err := json.Unmarshal(mfstBytes, &mfst); err != nil {
+		return
+	}
+	configBytes, err := content.ReadBlob(ctx, p.Store, mfst.Config)
+	if err != nil {
+		return
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return
+	}
+	if len(config.RootFS.DiffIDs) != len(mfst.Layers) {
+		return
+	}
+
+	for c := range mfst.Layers {
+		p.layers = append(p.layers, extractingLayer{
+			layer:   mfst.Layers[c],
+			chainID: identity.ChainID(config.RootFS.DiffIDs[:c+1]),
+		})
+	}
+	p.parsed[desc.Digest] = struct{}{}
+}
+
+// unpackKeyChainID extracts the chain ID from an active extract snapshot key
+// (of the form "extract-<unique> <chain ID>") created by containerd's
+// unpacker.
+func unpackKeyChainID(key string) (string, bool) {
+	if !strings.HasPrefix(key, snapshots.UnpackKeyPrefix) {
+		return "", false
+	}
+	i := strings.LastIndex(key, " ")
+	if i < 0 {
+		return "", false
+	}
+	return key[i+1:], true
+}
+
 type pushProgress struct {
 	Tracker   docker.StatusTracker
 	mountable map[digest.Digest]struct{}
@@ -216,7 +390,10 @@ func (p *pushProgress) UpdateProgress(ctx context.Context, ongoing *jobs, out pr
 			ID:      id,
 			Action:  "Pushing",
 			Current: status.Offset,
-			Total:   status.Total,
+			// StartedAt lets clients compute the per-layer transfer
+			// rate and an ETA from the offset.
+			Start: status.StartedAt.Unix(),
+			Total: status.Total,
 		})
 	}
 
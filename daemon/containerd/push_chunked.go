@@ -0,0 +1,461 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	cdreference "github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	remoteerrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/docker/distribution/reference"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// maxChunkRetries is how many times a single chunk upload is retried before
+// the blob push is failed. Between attempts the upload session is re-queried,
+// so only the part of the chunk the registry didn't receive is sent again.
+const maxChunkRetries = 5
+
+// chunkedPusher is a remotes.Pusher that uploads layer blobs of at least
+// chunkSize bytes in chunks (POST to open an upload session, PATCH per
+// chunk, PUT to commit), so that a connection dropping mid-upload only loses
+// the current chunk instead of the whole blob: the session is re-queried for
+// the bytes the registry received and the upload resumes from there.
+// Everything else — manifests, configs, small and cross-repo mountable blobs
+// — is delegated to the wrapped monolithic pusher.
+type chunkedPusher struct {
+	inner     remotes.Pusher
+	host      docker.RegistryHost
+	refspec   cdreference.Spec
+	repo      string
+	chunkSize int64
+	tracker   docker.StatusTracker
+	mountable map[digest.Digest]distributionSource
+}
+
+// newChunkedPusher wraps inner for the given push target. It resolves the
+// push-capable registry host with the same configuration (auth, proxy, TLS)
+// the resolver uses.
+func (i *ImageService) newChunkedPusher(inner remotes.Pusher, targetRef reference.Named, authConfig *registrytypes.AuthConfig, tracker docker.StatusTracker, mountable map[digest.Digest]distributionSource) (remotes.Pusher, error) {
+	refspec, err := cdreference.Parse(targetRef.String())
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := hostsWrapper(i.registryHosts, nil, authConfig, i.credentialHelpers, i.registryService)(refspec.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	var pushHost *docker.RegistryHost
+	for h := range hosts {
+		if hosts[h].Capabilities.Has(docker.HostCapabilityPush) {
+			pushHost = &hosts[h]
+			break
+		}
+	}
+	if pushHost == nil {
+		return nil, errors.Errorf("no push-capable host for %s", refspec.Hostname())
+	}
+
+	return &chunkedPusher{
+		inner:     inner,
+		host:      *pushHost,
+		refspec:   refspec,
+		repo:      strings.TrimPrefix(refspec.Locator, refspec.Hostname()+"/"),
+		chunkSize: i.pushChunkSize,
+		tracker:   tracker,
+		mountable: mountable,
+	}, nil
+}
+
+func (p *chunkedPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	if !images.IsLayerType(desc.MediaType) || desc.Size < p.chunkSize {
+		return p.inner.Push(ctx, desc)
+	}
+	if _, ok := p.mountable[desc.Digest]; ok {
+		// Cross-repo mounts complete server-side; no data to chunk.
+		return p.inner.Push(ctx, desc)
+	}
+
+	ctx, err := docker.ContextWithRepositoryScope(ctx, p.refspec, true)
+	if err != nil {
+		return nil, err
+	}
+	ref := remotes.MakeRefKey(ctx, desc)
+
+	if exists, err := p.blobExists(ctx, desc.Digest); err != nil {
+		log.G(ctx).WithError(err).WithField("digest", desc.Digest).Debug("failed to check blob existence before chunked upload")
+	} else if exists {
+		now := time.Now()
+		p.tracker.SetStatus(ref, docker.Status{
+			Committed: true,
+			Status: content.Status{
+				Ref:       ref,
+				Total:     desc.Size,
+				Offset:    desc.Size,
+				StartedAt: now,
+				UpdatedAt: now,
+			},
+		})
+		return nil, fmt.Errorf("content %v on remote: %w", desc.Digest, cerrdefs.ErrAlreadyExists)
+	}
+
+	location, err := p.startSession(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open upload session")
+	}
+
+	now := time.Now()
+	p.tracker.SetStatus(ref, docker.Status{
+		Status: content.Status{
+			Ref:       ref,
+			Total:     desc.Size,
+			Expected:  desc.Digest,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+	})
+
+	return &chunkedBlobWriter{
+		pusher:    p,
+		ctx:       ctx,
+		ref:       ref,
+		expected:  desc.Digest,
+		total:     desc.Size,
+		location:  location,
+		digester:  digest.Canonical.Digester(),
+		startedAt: now,
+	}, nil
+}
+
+// url joins path components onto the host's API base, e.g.
+// https://registry-1.docker.io/v2/library/busybox/blobs/uploads/.
+func (p *chunkedPusher) url(ps ...string) string {
+	return fmt.Sprintf("%s://%s%s/%s", p.host.Scheme, p.host.Host, p.host.Path, strings.Join(ps, "/"))
+}
+
+// do sends the request built by build, authorizing it with the host's
+// authorizer and retrying once with refreshed credentials after a 401.
+func (p *chunkedPusher) do(ctx context.Context, build func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := build()
+		if err != nil {
+			return nil, err
+		}
+		if p.host.Authorizer != nil {
+			if err := p.host.Authorizer.Authorize(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+		client := p.host.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || p.host.Authorizer == nil || attempt > 0 {
+			return resp, nil
+		}
+		if err := p.host.Authorizer.AddResponses(ctx, []*http.Response{resp}); err != nil {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+func (p *chunkedPusher) blobExists(ctx context.Context, dgst digest.Digest) (bool, error) {
+	resp, err := p.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodHead, p.url(p.repo, "blobs", dgst.String()), nil)
+	})
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// startSession opens a new upload session and returns its location.
+func (p *chunkedPusher) startSession(ctx context.Context) (*url.URL, error) {
+	resp, err := p.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, p.url(p.repo, "blobs", "uploads/"), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, errors.Errorf("unexpected status %s opening upload session", resp.Status)
+	}
+	return uploadLocation(resp)
+}
+
+// uploadLocation resolves the Location header of an upload API response,
+// which registries may return relative to the request URL.
+func uploadLocation(resp *http.Response) (*url.URL, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, errors.New("upload session response carries no Location")
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Request.URL.ResolveReference(u), nil
+}
+
+// chunkedBlobWriter is the content.Writer side of chunkedPusher: it buffers
+// written data and PATCHes it to the upload session one chunk at a time,
+// then commits the session with a digest-carrying PUT.
+type chunkedBlobWriter struct {
+	pusher *chunkedPusher
+	ctx    context.Context
+
+	ref      string
+	expected digest.Digest
+	total    int64
+
+	location  *url.URL
+	buf       []byte
+	sent      int64 // bytes the registry acknowledged
+	digester  digest.Digester
+	startedAt time.Time
+	committed bool
+	closed    bool
+}
+
+func (w *chunkedBlobWriter) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed chunked upload")
+	}
+	w.digester.Hash().Write(data)
+	w.buf = append(w.buf, data...)
+	if int64(len(w.buf)) >= w.pusher.chunkSize {
+		if err := w.flush(false); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// flush uploads buffered data, one chunk-sized PATCH at a time. A trailing
+// partial chunk is kept buffered unless all is set; Commit sets it to drain
+// the remainder.
+func (w *chunkedBlobWriter) flush(all bool) error {
+	for len(w.buf) > 0 && (all || int64(len(w.buf)) >= w.pusher.chunkSize) {
+		chunk := w.buf
+		if int64(len(chunk)) > w.pusher.chunkSize {
+			chunk = chunk[:w.pusher.chunkSize]
+		}
+		if err := w.sendChunk(chunk); err != nil {
+			return err
+		}
+		w.buf = w.buf[len(chunk):]
+		w.updateStatus()
+	}
+	return nil
+}
+
+// sendChunk uploads one chunk, retrying with session resumption on
+// transient failures.
+func (w *chunkedBlobWriter) sendChunk(data []byte) error {
+	for attempt := 1; len(data) > 0; attempt++ {
+		err := w.patch(data)
+		if err == nil {
+			w.sent += int64(len(data))
+			break
+		}
+		if attempt >= maxChunkRetries || !retryableDownloadError(err) {
+			return errors.Wrapf(err, "failed to upload chunk at offset %d", w.sent)
+		}
+		log.G(w.ctx).WithError(err).WithFields(log.Fields{
+			"digest": w.expected,
+			"offset": w.sent,
+		}).Info("chunk upload failed, resuming session")
+
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+
+		// Ask the registry how much of the chunk made it and only resend
+		// the rest.
+		received, err := w.resume()
+		if err != nil {
+			continue
+		}
+		if received < w.sent || received > w.sent+int64(len(data)) {
+			return errors.Errorf("upload session out of sync: registry has %d bytes, expected between %d and %d", received, w.sent, w.sent+int64(len(data)))
+		}
+		data = data[received-w.sent:]
+		w.sent = received
+	}
+	return nil
+}
+
+func (w *chunkedBlobWriter) patch(data []byte) error {
+	resp, err := w.pusher.do(w.ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(w.ctx, http.MethodPatch, w.location.String(), bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", w.sent, w.sent+int64(len(data))-1))
+		req.ContentLength = int64(len(data))
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
+		return errors.Wrap(remoteerrors.NewUnexpectedStatusErr(resp), "failed to upload chunk")
+	}
+	if location, err := uploadLocation(resp); err == nil {
+		w.location = location
+	}
+	return nil
+}
+
+// resume queries the upload session for the number of bytes the registry
+// has received, refreshing the session location on the way.
+func (w *chunkedBlobWriter) resume() (int64, error) {
+	resp, err := w.pusher.do(w.ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(w.ctx, http.MethodGet, w.location.String(), nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
+		return 0, errors.Errorf("unexpected status %s querying upload session", resp.Status)
+	}
+	if location, err := uploadLocation(resp); err == nil {
+		w.location = location
+	}
+	_, end, ok := strings.Cut(resp.Header.Get("Range"), "-")
+	if !ok {
+		return 0, errors.New("upload session response carries no Range")
+	}
+	received, err := strconv.ParseInt(end, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return received + 1, nil
+}
+
+func (w *chunkedBlobWriter) updateStatus() {
+	status, err := w.pusher.tracker.GetStatus(w.ref)
+	if err != nil {
+		status = docker.Status{Status: content.Status{Ref: w.ref, Total: w.total, Expected: w.expected, StartedAt: w.startedAt}}
+	}
+	status.Offset = w.sent
+	status.UpdatedAt = time.Now()
+	status.Committed = w.committed
+	w.pusher.tracker.SetStatus(w.ref, status)
+}
+
+func (w *chunkedBlobWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	if err := w.flush(true); err != nil {
+		return err
+	}
+	if size > 0 && size != w.sent {
+		return errors.Errorf("unexpected size %d, expected %d", w.sent, size)
+	}
+	if expected != "" && expected != w.digester.Digest() {
+		return errors.Errorf("unexpected digest %s, expected %s", w.digester.Digest(), expected)
+	}
+
+	u := *w.location
+	q := u.Query()
+	q.Set("digest", w.expected.String())
+	u.RawQuery = q.Encode()
+
+	resp, err := w.pusher.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = 0
+		return req, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to commit upload session")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return errors.Errorf("unexpected status %s committing upload session", resp.Status)
+	}
+
+	w.committed = true
+	w.updateStatus()
+	return nil
+}
+
+func (w *chunkedBlobWriter) Status() (content.Status, error) {
+	return content.Status{
+		Ref: w.ref,
+		// Buffered bytes were consumed from the source already, so they
+		// count towards the copy offset even though they're not sent yet.
+		Offset:    w.sent + int64(len(w.buf)),
+		Total:     w.total,
+		Expected:  w.expected,
+		StartedAt: w.startedAt,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+func (w *chunkedBlobWriter) Digest() digest.Digest {
+	return w.digester.Digest()
+}
+
+func (w *chunkedBlobWriter) Truncate(size int64) error {
+	if size != 0 {
+		return errors.New("chunked upload only supports truncating to 0")
+	}
+	location, err := w.pusher.startSession(w.ctx)
+	if err != nil {
+		return err
+	}
+	w.location = location
+	w.buf = w.buf[:0]
+	w.sent = 0
+	w.digester = digest.Canonical.Digester()
+	return nil
+}
+
+func (w *chunkedBlobWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if !w.committed {
+		// Abandon the session; registries clean these up on their own,
+		// this just speeds it along.
+		if resp, err := w.pusher.do(w.ctx, func() (*http.Request, error) {
+			return http.NewRequestWithContext(w.ctx, http.MethodDelete, w.location.String(), nil)
+		}); err == nil {
+			resp.Body.Close()
+		}
+	}
+	return nil
+}
+
+var _ io.Writer = (*chunkedBlobWriter)(nil)
+var _ content.Writer = (*chunkedBlobWriter)(nil)
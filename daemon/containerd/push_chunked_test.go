@@ -0,0 +1,214 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	cdreference "github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type pusherFunc func(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error)
+
+func (f pusherFunc) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	return f(ctx, desc)
+}
+
+// fakeUploadRegistry implements just enough of the registry V2 blob upload
+// API (POST/PATCH/GET/PUT on the uploads resource) to exercise chunked
+// uploads, including a partially applied chunk to force a resume.
+type fakeUploadRegistry struct {
+	t *testing.T
+
+	blobs      map[digest.Digest][]byte
+	session    []byte
+	patches    int
+	failPatch  bool // fail the next PATCH after storing half its body
+	statusGets int
+}
+
+func (r *fakeUploadRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodHead && strings.Contains(req.URL.Path, "/blobs/sha256:"):
+		dgst := digest.Digest(req.URL.Path[strings.Index(req.URL.Path, "sha256:"):])
+		if _, ok := r.blobs[dgst]; ok {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/blobs/uploads/"):
+		r.session = nil
+		w.Header().Set("Location", "/v2/test/repo/blobs/uploads/session")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPatch:
+		r.patches++
+		data, err := io.ReadAll(req.Body)
+		assert.NilError(r.t, err)
+		start, _, _ := strings.Cut(req.Header.Get("Content-Range"), "-")
+		offset, err := strconv.Atoi(start)
+		assert.NilError(r.t, err)
+		assert.Equal(r.t, offset, len(r.session), "chunk offset does not match session state")
+		if r.failPatch {
+			r.failPatch = false
+			r.session = append(r.session, data[:len(data)/2]...)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		r.session = append(r.session, data...)
+		w.Header().Set("Location", "/v2/test/repo/blobs/uploads/session")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodGet:
+		r.statusGets++
+		w.Header().Set("Location", "/v2/test/repo/blobs/uploads/session")
+		w.Header().Set("Range", "0-"+strconv.Itoa(len(r.session)-1))
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodPut:
+		dgst := digest.Digest(req.URL.Query().Get("digest"))
+		if digest.FromBytes(r.session) != dgst {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[dgst] = r.session
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func testChunkedPusher(t *testing.T, inner remotes.Pusher, chunkSize int64) (*chunkedPusher, *fakeUploadRegistry, docker.StatusTracker) {
+	reg := &fakeUploadRegistry{t: t, blobs: map[digest.Digest][]byte{}}
+	srv := httptest.NewServer(reg)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	assert.NilError(t, err)
+	refspec, err := cdreference.Parse(u.Host + "/test/repo:latest")
+	assert.NilError(t, err)
+
+	tracker := docker.NewInMemoryTracker()
+	return &chunkedPusher{
+		inner: inner,
+		host: docker.RegistryHost{
+			Client:       srv.Client(),
+			Host:         u.Host,
+			Scheme:       "http",
+			Path:         "/v2",
+			Capabilities: docker.HostCapabilityPush,
+		},
+		refspec:   refspec,
+		repo:      "test/repo",
+		chunkSize: chunkSize,
+		tracker:   tracker,
+	}, reg, tracker
+}
+
+func layerDesc(data []byte) ocispec.Descriptor {
+	return ocispec.Descriptor{
+		MediaType: images.MediaTypeDockerSchema2LayerGzip,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+}
+
+func pushBlob(t *testing.T, p *chunkedPusher, data []byte) error {
+	t.Helper()
+	desc := layerDesc(data)
+	w, err := p.Push(context.Background(), desc)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = w.Write(data)
+	assert.NilError(t, err)
+	return w.Commit(context.Background(), desc.Size, desc.Digest)
+}
+
+func TestChunkedPusher(t *testing.T) {
+	inner := pusherFunc(func(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+		t.Error("inner pusher should not be used for large layers")
+		return nil, nil
+	})
+
+	t.Run("uploads large layers in chunks", func(t *testing.T) {
+		p, reg, _ := testChunkedPusher(t, inner, 4)
+		data := []byte("0123456789")
+		assert.NilError(t, pushBlob(t, p, data))
+		assert.Check(t, is.DeepEqual(reg.blobs[digest.FromBytes(data)], data))
+		// 10 bytes in 4-byte chunks: two full chunks plus the remainder
+		// flushed by Commit.
+		assert.Check(t, is.Equal(reg.patches, 3))
+	})
+
+	t.Run("resumes after a failed chunk", func(t *testing.T) {
+		p, reg, _ := testChunkedPusher(t, inner, 4)
+		reg.failPatch = true
+		data := []byte("0123456789")
+		assert.NilError(t, pushBlob(t, p, data))
+		assert.Check(t, is.DeepEqual(reg.blobs[digest.FromBytes(data)], data))
+		// The failed chunk was re-queried and only its missing half resent.
+		assert.Check(t, is.Equal(reg.statusGets, 1))
+	})
+
+	t.Run("reports already pushed blobs", func(t *testing.T) {
+		p, reg, tracker := testChunkedPusher(t, inner, 4)
+		data := []byte("0123456789")
+		desc := layerDesc(data)
+		reg.blobs[desc.Digest] = data
+
+		_, err := p.Push(context.Background(), desc)
+		assert.Check(t, cerrdefs.IsAlreadyExists(err))
+
+		ctx := context.Background()
+		status, err := tracker.GetStatus(remotes.MakeRefKey(ctx, desc))
+		assert.NilError(t, err)
+		assert.Check(t, status.Committed)
+		assert.Check(t, is.Equal(status.Offset, desc.Size))
+	})
+
+	t.Run("delegates small blobs and manifests", func(t *testing.T) {
+		innerCalled := 0
+		delegating := pusherFunc(func(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+			innerCalled++
+			return nil, nil
+		})
+		p, _, _ := testChunkedPusher(t, delegating, 1024)
+
+		_, err := p.Push(context.Background(), layerDesc([]byte("small")))
+		assert.NilError(t, err)
+		_, err = p.Push(context.Background(), ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Size:      1 << 20,
+		})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(innerCalled, 2))
+	})
+
+	t.Run("delegates mountable blobs", func(t *testing.T) {
+		innerCalled := 0
+		delegating := pusherFunc(func(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+			innerCalled++
+			return nil, nil
+		})
+		data := []byte("0123456789")
+		desc := layerDesc(data)
+		p, _, _ := testChunkedPusher(t, delegating, 4)
+		p.mountable = map[digest.Digest]distributionSource{desc.Digest: {}}
+
+		_, err := p.Push(context.Background(), desc)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(innerCalled, 1))
+	})
+}
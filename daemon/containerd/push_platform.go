@@ -0,0 +1,115 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/images/converter"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Platform-specific pushes work on a temporary image: the converter extracts
+// the requested platform out of the source index into a new image record,
+// the push runs against that record's target and a defer deletes it again.
+// The record is labelled so it can be told apart from real images: the image
+// listing hides it, and leftovers from a push that never reached its defer —
+// a daemon panic or restart mid-push — are garbage-collected the next time
+// the service starts.
+const imageLabelTemporary = "org.mobyproject.image.temporary"
+
+// tempPlatformImageName derives the name of the temporary image holding the
+// platform-specific manifests of name. The platforms are part of the name so
+// concurrent pushes of the same image for different platforms don't collide.
+func tempPlatformImageName(name string, pushPlatforms []ocispec.Platform) string {
+	formatted := make([]string, len(pushPlatforms))
+	for n, p := range pushPlatforms {
+		formatted[n] = strings.ReplaceAll(platforms.Format(p), "/", "-")
+	}
+	return name + "-tmp-platformspecific-" + strings.Join(formatted, "-")
+}
+
+// isTemporaryImage reports whether img is a temporary image created for a
+// platform-specific push. The name is checked as well as the label, so
+// records created in the window before the label update are still
+// recognized.
+func isTemporaryImage(img containerdimages.Image) bool {
+	if _, ok := img.Labels[imageLabelTemporary]; ok {
+		return true
+	}
+	return strings.Contains(img.Name, "-tmp-platformspecific")
+}
+
+// createTempPlatformImage extracts the requested platforms of img into a
+// labelled temporary image record and returns it. A single platform produces
+// a plain manifest, several produce a sub-index. The caller is responsible
+// for deleting the record with deleteTempPlatformImage once the push is done.
+func (i *ImageService) createTempPlatformImage(ctx context.Context, img containerdimages.Image, pushPlatforms []ocispec.Platform) (containerdimages.Image, error) {
+	matcher := platforms.OnlyStrict(pushPlatforms[0])
+	if len(pushPlatforms) > 1 {
+		matcher = platforms.Any(pushPlatforms...)
+	}
+	tmpRef := tempPlatformImageName(img.Name, pushPlatforms)
+	platformImg, err := converter.Convert(ctx, i.client, tmpRef, img.Name,
+		converter.WithPlatform(matcher))
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			formatted := make([]string, len(pushPlatforms))
+			for n, p := range pushPlatforms {
+				formatted[n] = platforms.Format(p)
+			}
+			return containerdimages.Image{}, errdefs.NotFound(errors.Wrapf(err,
+				"image %s does not provide platform %s", img.Name, strings.Join(formatted, ", ")))
+		}
+		return containerdimages.Image{}, translateConnectionError(err)
+	}
+
+	if platformImg.Labels == nil {
+		platformImg.Labels = map[string]string{}
+	}
+	platformImg.Labels[imageLabelTemporary] = "true"
+	if updated, err := i.client.ImageService().Update(ctx, *platformImg, "labels."+imageLabelTemporary); err == nil {
+		platformImg = &updated
+	} else {
+		log.G(ctx).WithError(err).WithField("image", platformImg.Name).Warn("failed to label temporary platform-specific image")
+	}
+	return *platformImg, nil
+}
+
+// deleteTempPlatformImage removes the temporary image created for a
+// platform-specific push. It deliberately doesn't use the push context,
+// which may already be cancelled when the deferred cleanup runs.
+func (i *ImageService) deleteTempPlatformImage(name string) {
+	err := i.client.ImageService().Delete(context.Background(), name, containerdimages.SynchronousDelete())
+	if err != nil && !cerrdefs.IsNotFound(err) {
+		log.G(context.TODO()).WithError(err).WithField("image", name).Warn("failed to delete temporary platform-specific image")
+	}
+}
+
+// cleanupTemporaryImages deletes temporary images left behind by pushes that
+// never ran their cleanup, e.g. because the daemon panicked or was restarted
+// mid-push. It runs once at service start.
+func (i *ImageService) cleanupTemporaryImages(ctx context.Context) {
+	imgs, err := i.client.ImageService().List(ctx, fmt.Sprintf("labels.%q", imageLabelTemporary))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.G(ctx).WithError(err).Warn("failed to list leftover temporary images")
+		}
+		return
+	}
+	for _, img := range imgs {
+		if !isTemporaryImage(img) {
+			continue
+		}
+		log.G(ctx).WithField("image", img.Name).Info("removing leftover temporary image")
+		if err := i.client.ImageService().Delete(ctx, img.Name, containerdimages.SynchronousDelete()); err != nil && !cerrdefs.IsNotFound(err) {
+			log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to remove leftover temporary image")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package containerd
+
+import (
+	"testing"
+
+	containerdimages "github.com/containerd/containerd/images"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestTempPlatformImageName(t *testing.T) {
+	name := tempPlatformImageName("docker.io/library/busybox:latest", []ocispec.Platform{{OS: "linux", Architecture: "arm64"}})
+	assert.Check(t, is.Equal(name, "docker.io/library/busybox:latest-tmp-platformspecific-linux-arm64"))
+
+	// Names of different platforms must not collide so concurrent pushes
+	// don't step on each other.
+	other := tempPlatformImageName("docker.io/library/busybox:latest", []ocispec.Platform{{OS: "linux", Architecture: "amd64"}})
+	assert.Check(t, name != other)
+
+	// A platform subset includes every requested platform in the name.
+	subset := tempPlatformImageName("docker.io/library/busybox:latest", []ocispec.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64"},
+	})
+	assert.Check(t, is.Equal(subset, "docker.io/library/busybox:latest-tmp-platformspecific-linux-amd64-linux-arm64"))
+}
+
+func TestIsTemporaryImage(t *testing.T) {
+	assert.Check(t, isTemporaryImage(containerdimages.Image{
+		Name:   "docker.io/library/busybox:latest-tmp-platformspecific-linux-amd64",
+		Labels: map[string]string{imageLabelTemporary: "true"},
+	}))
+	// The name alone is enough: the label update can be lost if the daemon
+	// dies right after the converter created the record.
+	assert.Check(t, isTemporaryImage(containerdimages.Image{
+		Name: "docker.io/library/busybox:latest-tmp-platformspecific-linux-amd64",
+	}))
+	assert.Check(t, !isTemporaryImage(containerdimages.Image{
+		Name: "docker.io/library/busybox:latest",
+	}))
+}
@@ -0,0 +1,219 @@
+package containerd
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"strings"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Push-time signatures are produced in the cosign format: a signature image
+// tagged sha256-<digest>.sig in the pushed repository, whose single layer is
+// the signed "simple signing" payload and whose layer annotation carries the
+// signature. cosign, policy-controller and compatible verifiers discover and
+// check them without any daemon involvement. Only a daemon-held key file is
+// supported; keyless (Fulcio/Rekor) signing needs an interactive identity
+// flow that has no place in the daemon.
+const (
+	mediaTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	annotationSignature    = "dev.cosignproject.cosign/signature"
+)
+
+// LoadPushSigningKey reads a PEM-encoded private key (PKCS#8, EC or PKCS#1)
+// from path for use as the daemon's push signing key.
+func LoadPushSigningKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM block found in %s", path)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse private key from %s", path)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("key in %s cannot sign", path)
+	}
+	return signer, nil
+}
+
+// signaturePayload is the cosign "simple signing" claim that is signed: it
+// binds the manifest digest to the repository it was pushed to.
+type signaturePayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest digest.Digest `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+// signatureContent is one piece of the signature image, in push order.
+type signatureContent struct {
+	desc ocispec.Descriptor
+	data []byte
+}
+
+// buildSignatureManifest signs the pushed manifest digest and assembles the
+// cosign signature image. The returned content is ordered with the manifest
+// last, so the signature tag never points at an incomplete image.
+func buildSignatureManifest(key crypto.Signer, repo string, target digest.Digest) ([]signatureContent, error) {
+	var payload signaturePayload
+	payload.Critical.Identity.DockerReference = repo
+	payload.Critical.Image.DockerManifestDigest = target
+	payload.Critical.Type = "cosign container image signature"
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signPayload(key, payloadJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign push payload")
+	}
+
+	configJSON := []byte("{}")
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(configJSON),
+			Size:      int64(len(configJSON)),
+		},
+		Layers: []ocispec.Descriptor{{
+			MediaType: mediaTypeSimpleSigning,
+			Digest:    digest.FromBytes(payloadJSON),
+			Size:      int64(len(payloadJSON)),
+			Annotations: map[string]string{
+				annotationSignature: base64.StdEncoding.EncodeToString(sig),
+			},
+		}},
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return []signatureContent{
+		{desc: manifest.Layers[0], data: payloadJSON},
+		{desc: manifest.Config, data: configJSON},
+		{
+			desc: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageManifest,
+				Digest:    digest.FromBytes(manifestJSON),
+				Size:      int64(len(manifestJSON)),
+			},
+			data: manifestJSON,
+		},
+	}, nil
+}
+
+// signPayload signs payload with key. ECDSA and RSA keys sign a SHA-256
+// digest; Ed25519 signs the payload directly, as cosign does.
+func signPayload(key crypto.Signer, payload []byte) ([]byte, error) {
+	switch key.(type) {
+	case *ecdsa.PrivateKey, *rsa.PrivateKey:
+		digested := sha256.Sum256(payload)
+		return key.Sign(rand.Reader, digested[:], crypto.SHA256)
+	case ed25519.PrivateKey:
+		return key.Sign(rand.Reader, payload, crypto.Hash(0))
+	default:
+		return nil, errors.Errorf("unsupported signing key type %T", key)
+	}
+}
+
+// signatureTag is the tag cosign looks the signature image up under.
+func signatureTag(target digest.Digest) string {
+	return strings.Replace(target.String(), ":", "-", 1) + ".sig"
+}
+
+// signPushedManifest creates the signature image for the just-pushed target
+// and uploads it next to it, so a push configured for signing only succeeds
+// once the signature is in place.
+func (i *ImageService) signPushedManifest(ctx context.Context, resolver remotes.Resolver, targetRef reference.Named, target ocispec.Descriptor, out progress.Output) error {
+	repo := reference.TrimNamed(targetRef)
+	sigRef, err := reference.WithTag(repo, signatureTag(target.Digest))
+	if err != nil {
+		return err
+	}
+
+	contents, err := buildSignatureManifest(i.pushSigningKey, repo.Name(), target.Digest)
+	if err != nil {
+		return err
+	}
+
+	pusher, err := resolver.Pusher(ctx, sigRef.String())
+	if err != nil {
+		return err
+	}
+	for _, c := range contents {
+		if err := pushSignatureBlob(ctx, pusher, c.desc, c.data); err != nil {
+			return errors.Wrapf(err, "failed to push signature content %s", c.desc.Digest)
+		}
+	}
+
+	log.G(ctx).WithFields(log.Fields{
+		"image":     targetRef.String(),
+		"signature": sigRef.String(),
+	}).Debug("uploaded push-time signature")
+	progress.Messagef(out, "", "%s: signed (signature: %s)", reference.FamiliarString(targetRef), sigRef.Tag())
+	return nil
+}
+
+// pushSignatureBlob uploads one piece of the signature image, treating
+// content the registry already has as success.
+func pushSignatureBlob(ctx context.Context, pusher remotes.Pusher, desc ocispec.Descriptor, data []byte) error {
+	w, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if cerrdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	defer w.Close()
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Commit(ctx, desc.Size, desc.Digest); err != nil && !cerrdefs.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
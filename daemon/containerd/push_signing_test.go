@@ -0,0 +1,187 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/remotes"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func writeTestSigningKey(t *testing.T) (string, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NilError(t, err)
+	path := filepath.Join(t.TempDir(), "push.key")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	assert.NilError(t, err)
+	return path, key
+}
+
+func TestLoadPushSigningKey(t *testing.T) {
+	path, key := writeTestSigningKey(t)
+	signer, err := LoadPushSigningKey(path)
+	assert.NilError(t, err)
+	assert.Check(t, signer.Public().(*ecdsa.PublicKey).Equal(key.Public()))
+
+	garbage := filepath.Join(t.TempDir(), "garbage.key")
+	assert.NilError(t, os.WriteFile(garbage, []byte("not a key"), 0o600))
+	_, err = LoadPushSigningKey(garbage)
+	assert.Check(t, is.ErrorContains(err, "no PEM block"))
+
+	_, err = LoadPushSigningKey(filepath.Join(t.TempDir(), "missing.key"))
+	assert.Check(t, err != nil)
+}
+
+func TestBuildSignatureManifest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	target := digest.FromString("the manifest")
+
+	contents, err := buildSignatureManifest(key, "docker.io/library/busybox", target)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(contents, 3))
+
+	// The manifest comes last, after the content it references.
+	manifestContent := contents[2]
+	assert.Check(t, is.Equal(manifestContent.desc.MediaType, ocispec.MediaTypeImageManifest))
+	assert.Check(t, is.Equal(manifestContent.desc.Digest, digest.FromBytes(manifestContent.data)))
+
+	var manifest ocispec.Manifest
+	assert.NilError(t, json.Unmarshal(manifestContent.data, &manifest))
+	assert.Assert(t, is.Len(manifest.Layers, 1))
+	assert.Check(t, is.Equal(manifest.Layers[0].MediaType, mediaTypeSimpleSigning))
+	assert.Check(t, is.Equal(manifest.Layers[0].Digest, contents[0].desc.Digest))
+	assert.Check(t, is.Equal(manifest.Config.Digest, contents[1].desc.Digest))
+
+	var payload signaturePayload
+	assert.NilError(t, json.Unmarshal(contents[0].data, &payload))
+	assert.Check(t, is.Equal(payload.Critical.Image.DockerManifestDigest, target))
+	assert.Check(t, is.Equal(payload.Critical.Identity.DockerReference, "docker.io/library/busybox"))
+
+	sig, err := base64.StdEncoding.DecodeString(manifest.Layers[0].Annotations[annotationSignature])
+	assert.NilError(t, err)
+	digested := sha256.Sum256(contents[0].data)
+	assert.Check(t, ecdsa.VerifyASN1(&key.PublicKey, digested[:], sig))
+}
+
+func TestSignatureTag(t *testing.T) {
+	dgst := digest.FromString("the manifest")
+	tag := signatureTag(dgst)
+	assert.Check(t, is.Equal(tag, "sha256-"+dgst.Encoded()+".sig"))
+	_, err := reference.WithTag(reference.TrimNamed(mustParseRef(t, "docker.io/library/busybox:latest")), tag)
+	assert.NilError(t, err)
+}
+
+func mustParseRef(t *testing.T, ref string) reference.Named {
+	t.Helper()
+	named, err := reference.ParseNormalizedNamed(ref)
+	assert.NilError(t, err)
+	return named
+}
+
+// memBlobWriter collects committed blobs into the pushed map.
+type memBlobWriter struct {
+	bytes.Buffer
+	desc   ocispec.Descriptor
+	pushed map[digest.Digest][]byte
+}
+
+func (w *memBlobWriter) Close() error { return nil }
+
+func (w *memBlobWriter) Digest() digest.Digest { return digest.FromBytes(w.Bytes()) }
+
+func (w *memBlobWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	w.pushed[w.desc.Digest] = append([]byte(nil), w.Bytes()...)
+	return nil
+}
+
+func (w *memBlobWriter) Status() (content.Status, error) { return content.Status{}, nil }
+
+func (w *memBlobWriter) Truncate(size int64) error { return nil }
+
+type fakeResolver struct {
+	pusher    remotes.Pusher
+	pushedRef string
+}
+
+func (r *fakeResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	return "", ocispec.Descriptor{}, nil
+}
+
+func (r *fakeResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return nil, nil
+}
+
+func (r *fakeResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	r.pushedRef = ref
+	return r.pusher, nil
+}
+
+func TestSignPushedManifest(t *testing.T) {
+	path, key := writeTestSigningKey(t)
+	signer, err := LoadPushSigningKey(path)
+	assert.NilError(t, err)
+	svc := &ImageService{pushSigningKey: signer}
+
+	pushed := map[digest.Digest][]byte{}
+	resolver := &fakeResolver{
+		pusher: pusherFunc(func(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+			return &memBlobWriter{desc: desc, pushed: pushed}, nil
+		}),
+	}
+
+	targetRef := mustParseRef(t, "docker.io/library/busybox:latest")
+	target := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("the manifest"),
+		Size:      12,
+	}
+	out := streamformatter.NewJSONProgressOutput(io.Discard, false)
+	err = svc.signPushedManifest(context.Background(), resolver, targetRef, target, out)
+	assert.NilError(t, err)
+
+	// The signature image was pushed under the cosign tag: payload, config
+	// and manifest.
+	assert.Check(t, is.Equal(resolver.pushedRef, "docker.io/library/busybox:"+signatureTag(target.Digest)))
+	assert.Check(t, is.Len(pushed, 3))
+
+	contents, err := buildSignatureManifest(signer, "docker.io/library/busybox", target.Digest)
+	assert.NilError(t, err)
+	payload, ok := pushed[contents[0].desc.Digest]
+	assert.Assert(t, ok, "signed payload was not pushed")
+	digested := sha256.Sum256(payload)
+
+	var manifest ocispec.Manifest
+	found := false
+	for _, data := range pushed {
+		if json.Unmarshal(data, &manifest) == nil && manifest.MediaType == ocispec.MediaTypeImageManifest && len(manifest.Layers) == 1 {
+			found = true
+			break
+		}
+	}
+	assert.Assert(t, found, "signature manifest was not pushed")
+	sig, err := base64.StdEncoding.DecodeString(manifest.Layers[0].Annotations[annotationSignature])
+	assert.NilError(t, err)
+	assert.Check(t, ecdsa.VerifyASN1(&key.PublicKey, digested[:], sig))
+}
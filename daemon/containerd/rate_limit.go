@@ -0,0 +1,95 @@
+package containerd
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/docker/distribution/registry/api/errcode"
+)
+
+// errRateLimited is returned when a registry rejects a request with 429 Too
+// Many Requests. It keeps the Retry-After hint and the RateLimit quota
+// headers Docker Hub sends, which the generic unexpected-status error from
+// the resolver drops: the pull retry loop bases its backoff on the hint, and
+// clients get the remaining quota in the error message.
+type errRateLimited struct {
+	host       string
+	retryAfter time.Duration
+	limit      string
+	remaining  string
+	source     string
+}
+
+// newRateLimitedError builds an errRateLimited from a 429 response.
+func newRateLimitedError(resp *http.Response) errRateLimited {
+	return errRateLimited{
+		host:       resp.Request.URL.Host,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		limit:      resp.Header.Get("RateLimit-Limit"),
+		remaining:  resp.Header.Get("RateLimit-Remaining"),
+		source:     resp.Header.Get("Docker-RateLimit-Source"),
+	}
+}
+
+func (e errRateLimited) Error() string {
+	msg := fmt.Sprintf("toomanyrequests: %s is rate limiting requests", e.host)
+	if e.source != "" {
+		msg += fmt.Sprintf(" for %s", e.source)
+	}
+	if e.limit != "" || e.remaining != "" {
+		msg += fmt.Sprintf(" (limit %q, remaining %q)", e.limit, e.remaining)
+	}
+	if e.retryAfter > 0 {
+		msg += fmt.Sprintf("; retry after %v", e.retryAfter)
+	}
+	return msg
+}
+
+// ErrorCode maps the error onto the registry error space, so the API reports
+// it with status 429 Too Many Requests.
+func (e errRateLimited) ErrorCode() errcode.ErrorCode {
+	return errcode.ErrorCodeTooManyRequests
+}
+
+// parseRetryAfter interprets a Retry-After header value, which is either a
+// number of seconds or an HTTP date. It returns 0 when the header is absent,
+// unparsable, or in the past.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if until := time.Until(at); until > 0 {
+			return until
+		}
+	}
+	return 0
+}
+
+// rateLimitTransport turns 429 responses into errRateLimited before the
+// resolver reduces them to a plain unexpected-status error without headers.
+type rateLimitTransport struct {
+	super http.RoundTripper
+}
+
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.super
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	rlErr := newRateLimitedError(resp)
+	resp.Body.Close()
+	return nil, rlErr
+}
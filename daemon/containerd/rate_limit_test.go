@@ -0,0 +1,71 @@
+package containerd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/distribution/registry/api/errcode"
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Check(t, is.Equal(parseRetryAfter(""), time.Duration(0)))
+	assert.Check(t, is.Equal(parseRetryAfter("30"), 30*time.Second))
+	assert.Check(t, is.Equal(parseRetryAfter("-1"), time.Duration(0)))
+	assert.Check(t, is.Equal(parseRetryAfter("not-a-delay"), time.Duration(0)))
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	assert.Check(t, parseRetryAfter(future) > 0)
+	past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+	assert.Check(t, is.Equal(parseRetryAfter(past), time.Duration(0)))
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Retry-After", "42")
+		w.Header().Set("RateLimit-Limit", "100;w=21600")
+		w.Header().Set("RateLimit-Remaining", "0;w=21600")
+		w.Header().Set("Docker-RateLimit-Source", "192.0.2.1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: rateLimitTransport{}}
+
+	resp, err := client.Get(srv.URL + "/ok")
+	assert.NilError(t, err)
+	resp.Body.Close()
+
+	_, err = client.Get(srv.URL + "/v2/library/busybox/manifests/latest")
+	var rlErr errRateLimited
+	assert.Assert(t, errors.As(err, &rlErr))
+	assert.Check(t, is.Equal(rlErr.retryAfter, 42*time.Second))
+	assert.Check(t, is.Equal(rlErr.remaining, "0;w=21600"))
+	assert.Check(t, is.Equal(rlErr.ErrorCode(), errcode.ErrorCodeTooManyRequests))
+	assert.Check(t, is.ErrorContains(err, "toomanyrequests:"))
+	assert.Check(t, is.ErrorContains(err, `remaining "0;w=21600"`))
+	assert.Check(t, is.ErrorContains(err, "192.0.2.1"))
+}
+
+func TestRetryDelaySeconds(t *testing.T) {
+	assert.Check(t, is.Equal(retryDelaySeconds(errors.New("transient"), 1), 5))
+	assert.Check(t, is.Equal(retryDelaySeconds(errors.New("transient"), 3), 15))
+
+	rated := errors.Wrap(errRateLimited{retryAfter: 42 * time.Second}, "failed to resolve")
+	assert.Check(t, is.Equal(retryDelaySeconds(rated, 1), 42))
+
+	// The Retry-After hint is capped so a retry can't stall arbitrarily long.
+	patient := errRateLimited{retryAfter: time.Hour}
+	assert.Check(t, is.Equal(retryDelaySeconds(patient, 1), 60))
+
+	// Without a hint, a rate-limited attempt backs off like any other.
+	assert.Check(t, is.Equal(retryDelaySeconds(errRateLimited{}, 2), 10))
+}
@@ -0,0 +1,268 @@
+package containerd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	containerdimages "github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// staleIngestAge is the age after which an ingest with no progress is
+// considered abandoned and is aborted by the reconciliation pass.
+const staleIngestAge = time.Hour
+
+// ReconcileStorage removes state leaked by previous daemon crashes: snapshots
+// that are not referenced by any image or container, and content ingests that
+// have not seen progress for a while. It also renames image records left
+// behind by older daemons whose names collide with the store's internal
+// naming schemes. It is called on startup and is safe to call again at any
+// time; everything it cleans up is logged.
+func (i *ImageService) ReconcileStorage(ctx context.Context) {
+	i.reconcileImageNames(ctx)
+	i.reconcileIngests(ctx)
+	i.reconcileSnapshots(ctx)
+}
+
+// reconcileImageNames converts image records whose names collide with the
+// naming schemes the daemon resolves internally — the dangling-image
+// repository and repositories named after the digest algorithm — into proper
+// dangling images. Their content stays referenced, but under a name that can
+// no longer be confused with an image ID or a bookkeeping record. New records
+// with such names are rejected by validateImageName; this handles stores
+// written before that validation existed.
+func (i *ImageService) reconcileImageNames(ctx context.Context) {
+	is := i.client.ImageService()
+	imgs, err := is.List(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list images during storage reconciliation")
+		return
+	}
+
+	for _, img := range imgs {
+		if isDanglingImage(img) || !conflictsWithInternalNames(img.Name) {
+			continue
+		}
+
+		replacement := img
+		replacement.Name = danglingImageName(img.Target.Digest)
+		if _, err := is.Create(ctx, replacement); err != nil && !cerrdefs.IsAlreadyExists(err) {
+			log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to rename conflicting image record")
+			continue
+		}
+		if err := is.Delete(ctx, img.Name); err != nil && !cerrdefs.IsNotFound(err) {
+			log.G(ctx).WithError(err).WithField("image", img.Name).Warn("failed to delete conflicting image record after renaming it")
+			continue
+		}
+		log.G(ctx).WithFields(log.Fields{
+			"image":   img.Name,
+			"renamed": replacement.Name,
+		}).Info("renamed image record conflicting with internal naming schemes")
+	}
+}
+
+// conflictsWithInternalNames reports whether a stored image name collides
+// with the naming schemes the daemon uses internally. Dangling records whose
+// name matches their target are filtered out by the caller, so any remaining
+// name in the dangling repository is a conflict.
+func conflictsWithInternalNames(name string) bool {
+	if strings.HasPrefix(name, danglingImageRepository+"@") || strings.HasPrefix(name, danglingImageRepository+":") {
+		return true
+	}
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return false
+	}
+	return validateImageName(named) != nil
+}
+
+// reconcileIngests aborts ingests which have not been written to for
+// staleIngestAge, releasing their partially-downloaded content.
+func (i *ImageService) reconcileIngests(ctx context.Context) {
+	cs := i.client.ContentStore()
+	statuses, err := cs.ListStatuses(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list ingests during storage reconciliation")
+		return
+	}
+
+	for _, status := range statuses {
+		if time.Since(status.UpdatedAt) < staleIngestAge {
+			continue
+		}
+		if err := cs.Abort(ctx, status.Ref); err != nil && !cerrdefs.IsNotFound(err) {
+			log.G(ctx).WithError(err).WithField("ref", status.Ref).Warn("failed to abort stale ingest")
+			continue
+		}
+		log.G(ctx).WithFields(log.Fields{
+			"ref":     status.Ref,
+			"updated": status.UpdatedAt,
+		}).Info("aborted stale ingest")
+	}
+}
+
+// reconcileSnapshots removes chain snapshots which are not referenced by any
+// image or container. Only snapshots whose name is a digest (the form the
+// daemon and containerd use for unpacked layer chains) or a daemon-generated
+// view are considered; snapshots created by other clients are left alone.
+// Snapshots protected by an active lease are also left alone: concurrent
+// work — in-flight pulls, the BuildKit containerd worker's base-image
+// chains — holds its snapshots behind leases before any image record
+// references them.
+func (i *ImageService) reconcileSnapshots(ctx context.Context) {
+	used, err := i.usedSnapshots(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to collect referenced snapshots during storage reconciliation")
+		return
+	}
+	leased, err := i.leasedSnapshots(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to collect leased snapshots during storage reconciliation")
+		return
+	}
+
+	sn := i.client.SnapshotService(i.snapshotter)
+
+	// Children must be removed before their parents, so repeat the walk
+	// until a pass removes nothing.
+	for {
+		var removed int
+		err := sn.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
+			if _, ok := used[info.Name]; ok {
+				return nil
+			}
+			if _, ok := leased[info.Name]; ok {
+				return nil
+			}
+			if !isReconcilableSnapshot(info.Name) {
+				return nil
+			}
+			if err := sn.Remove(ctx, info.Name); err != nil {
+				// Expected for snapshots which still have children;
+				// they are retried on the next pass if the children
+				// are removed.
+				log.G(ctx).WithError(err).WithField("key", info.Name).Debug("snapshot not removed during storage reconciliation")
+				return nil
+			}
+			removed++
+			log.G(ctx).WithFields(log.Fields{
+				"key":  info.Name,
+				"kind": info.Kind,
+			}).Info("removed leaked snapshot")
+			return nil
+		})
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("failed to walk snapshots during storage reconciliation")
+			return
+		}
+		if removed == 0 {
+			return
+		}
+	}
+}
+
+// leasedSnapshots returns the keys of snapshots referenced by active leases
+// in the daemon's namespace, across all snapshotters. Leases are how other
+// containerd clients (and the daemon's own in-flight operations) protect
+// snapshots that are not yet behind an image or container record.
+func (i *ImageService) leasedSnapshots(ctx context.Context) (map[string]struct{}, error) {
+	ls := i.client.LeasesService()
+	active, err := ls.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leased := map[string]struct{}{}
+	for _, lease := range active {
+		resources, err := ls.ListResources(ctx, lease)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			if strings.HasPrefix(r.Type, "snapshots/") {
+				leased[r.ID] = struct{}{}
+			}
+		}
+	}
+	return leased, nil
+}
+
+// usedSnapshots returns the set of snapshot keys referenced by the unpacked
+// images, the containers and the active image mounts, including all of their
+// ancestors.
+func (i *ImageService) usedSnapshots(ctx context.Context) (map[string]struct{}, error) {
+	used := map[string]struct{}{}
+
+	imgs, err := i.client.ImageService().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs := i.client.ContentStore()
+	for _, img := range imgs {
+		err := i.walkPresentChildren(ctx, img.Target, func(ctx context.Context, desc ocispec.Descriptor) error {
+			if !containerdimages.IsConfigType(desc.MediaType) {
+				return nil
+			}
+			diffIDs, err := containerdimages.RootFS(ctx, cs, desc)
+			if err != nil {
+				return nil
+			}
+			for c := 1; c <= len(diffIDs); c++ {
+				used[identity.ChainID(diffIDs[:c]).String()] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	markChain := func(sn snapshots.Snapshotter, key string) {
+		for key != "" {
+			if _, ok := used[key]; ok {
+				return
+			}
+			used[key] = struct{}{}
+			info, err := sn.Stat(ctx, key)
+			if err != nil {
+				return
+			}
+			key = info.Parent
+		}
+	}
+	defaultSn := i.client.SnapshotService(i.snapshotter)
+	for _, ctr := range i.containers.List() {
+		// Containers restored after a configuration change may live in a
+		// snapshotter other than the current default.
+		sn := defaultSn
+		if ctr.Driver != "" && ctr.Driver != i.snapshotter {
+			sn = i.client.SnapshotService(ctr.Driver)
+		}
+		markChain(sn, ctr.ID)
+	}
+
+	i.imageMountsMu.Lock()
+	for key := range i.imageMounts {
+		markChain(defaultSn, key)
+	}
+	i.imageMountsMu.Unlock()
+
+	return used, nil
+}
+
+// isReconcilableSnapshot reports whether the snapshot key is one the daemon
+// manages and may remove when unreferenced: an unpacked layer chain (named
+// by its chain ID) or a daemon-generated view.
+func isReconcilableSnapshot(key string) bool {
+	if _, err := digest.Parse(key); err == nil {
+		return true
+	}
+	return strings.HasPrefix(key, "dockermount-") || strings.HasPrefix(key, "flatten-view-")
+}
@@ -0,0 +1,206 @@
+package containerd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ServeRegistry starts serving a read-only Registry API backed by the
+// daemon's image store on addr. Co-located hosts (or kind/minikube clusters)
+// can configure this daemon as a registry mirror and pull the images it
+// already holds without going out to the origin registry.
+//
+// The server is shut down by Cleanup.
+func (i *ImageService) ServeRegistry(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(i.serveRegistryRequest)}
+	i.registryServer = srv
+	go func() {
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.G(context.TODO()).WithError(err).Error("local registry mirror server failed")
+		}
+	}()
+	log.G(context.TODO()).WithField("addr", addr).Info("serving read-only registry API backed by the image store")
+	return nil
+}
+
+func (i *ImageService) serveRegistryRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "the registry served by the daemon is read-only")
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if p == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch name, kind, ref := splitRegistryPath(p); {
+	case p == "":
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+		w.WriteHeader(http.StatusOK)
+	case strings.HasSuffix(p, "/tags/list"):
+		i.serveRegistryTags(w, r, strings.TrimSuffix(p, "/tags/list"))
+	case kind == "manifests":
+		i.serveRegistryManifest(w, r, name, ref)
+	case kind == "blobs":
+		i.serveRegistryBlob(w, r, ref)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// splitRegistryPath splits "<name>/(manifests|blobs)/<reference>"; the
+// repository name may itself contain slashes.
+func splitRegistryPath(p string) (name, kind, ref string) {
+	parts := strings.Split(p, "/")
+	if len(parts) < 3 {
+		return "", "", ""
+	}
+	return strings.Join(parts[:len(parts)-2], "/"), parts[len(parts)-2], parts[len(parts)-1]
+}
+
+func (i *ImageService) serveRegistryManifest(w http.ResponseWriter, r *http.Request, name, ref string) {
+	ctx := r.Context()
+
+	var desc ocispec.Descriptor
+	if dgst, err := digest.Parse(ref); err == nil {
+		info, err := i.client.ContentStore().Info(ctx, dgst)
+		if err != nil {
+			writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown to the daemon")
+			return
+		}
+		desc = ocispec.Descriptor{Digest: dgst, Size: info.Size}
+	} else {
+		named, err := reference.ParseNormalizedNamed(name)
+		if err == nil {
+			named, err = reference.WithTag(named, ref)
+		}
+		if err != nil {
+			writeRegistryError(w, http.StatusBadRequest, "NAME_INVALID", err.Error())
+			return
+		}
+		img, err := i.client.ImageService().Get(ctx, named.String())
+		if err != nil {
+			writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown to the daemon")
+			return
+		}
+		desc = img.Target
+	}
+
+	blob, err := content.ReadBlob(ctx, i.client.ContentStore(), desc)
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown to the daemon")
+		return
+	}
+
+	mt := desc.MediaType
+	if mt == "" {
+		// Image records carry the media type in their target descriptor;
+		// manifests requested by digest don't, but declare it in the body.
+		var partial struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(blob, &partial); err == nil && partial.MediaType != "" {
+			mt = partial.MediaType
+		} else {
+			mt = ocispec.MediaTypeImageManifest
+		}
+	}
+
+	w.Header().Set("Content-Type", mt)
+	w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+	w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(blob)
+}
+
+func (i *ImageService) serveRegistryBlob(w http.ResponseWriter, r *http.Request, ref string) {
+	dgst, err := digest.Parse(ref)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+		return
+	}
+
+	ra, err := i.client.ContentStore().ReaderAt(r.Context(), ocispec.Descriptor{Digest: dgst})
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown to the daemon")
+		return
+	}
+	defer ra.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", dgst.String())
+	// ServeContent takes care of HEAD and range requests, which clients use
+	// to resume interrupted downloads.
+	http.ServeContent(w, r, "", time.Time{}, io.NewSectionReader(ra, 0, ra.Size()))
+}
+
+func (i *ImageService) serveRegistryTags(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "NAME_INVALID", err.Error())
+		return
+	}
+
+	imgs, err := i.client.ImageService().List(ctx)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	var tags []string
+	for _, img := range imgs {
+		ref, err := reference.ParseNamed(img.Name)
+		if err != nil {
+			continue
+		}
+		tagged, ok := ref.(reference.Tagged)
+		if !ok || ref.Name() != named.Name() {
+			continue
+		}
+		tags = append(tags, tagged.Tag())
+	}
+	if len(tags) == 0 {
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to the daemon")
+		return
+	}
+	sort.Strings(tags)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"name": name,
+		"tags": tags,
+	})
+}
+
+func writeRegistryError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/tls"
 	"errors"
 	"net/http"
+	"net/url"
+	"path"
 
 	"github.com/containerd/containerd/log"
 	"github.com/containerd/containerd/remotes"
@@ -19,21 +21,27 @@ import (
 func (i *ImageService) newResolverFromAuthConfig(ctx context.Context, authConfig *registrytypes.AuthConfig) (remotes.Resolver, docker.StatusTracker) {
 	tracker := docker.NewInMemoryTracker()
 
-	hosts := hostsWrapper(i.registryHosts, authConfig, i.registryService)
+	hosts := hostsWrapper(i.registryHosts, i.acceleratorHosts(), authConfig, i.credentialHelpers, i.registryService)
 	headers := http.Header{}
 	headers.Set("User-Agent", dockerversion.DockerUserAgent(ctx, useragent.VersionInfo{Name: "containerd-client", Version: version.Version}, useragent.VersionInfo{Name: "storage-driver", Version: i.snapshotter}))
 
-	return docker.NewResolver(docker.ResolverOptions{
+	resolver := docker.NewResolver(docker.ResolverOptions{
 		Hosts:   hosts,
 		Tracker: tracker,
 		Headers: headers,
-	}), tracker
+	})
+	return i.withForeignURLsFetcher(resolver), tracker
 }
 
-func hostsWrapper(hostsFn docker.RegistryHosts, optAuthConfig *registrytypes.AuthConfig, regService RegistryConfigProvider) docker.RegistryHosts {
+func hostsWrapper(hostsFn docker.RegistryHosts, accelerators []docker.RegistryHost, optAuthConfig *registrytypes.AuthConfig, credHelpers *registry.CredentialHelpers, regService RegistryConfigProvider) docker.RegistryHosts {
 	var authorizer docker.Authorizer
 	if optAuthConfig != nil {
 		authorizer = docker.NewDockerAuthorizer(authorizationCredsFromAuthConfig(*optAuthConfig))
+	} else if credHelpers != nil {
+		// No client-provided credentials; fall back to the credential
+		// helpers configured on the daemon. Hosts without a configured
+		// helper resolve to empty credentials, as before.
+		authorizer = docker.NewDockerAuthorizer(docker.WithAuthCreds(credHelpers.Get))
 	}
 
 	return func(n string) ([]docker.RegistryHost, error) {
@@ -43,16 +51,57 @@ func hostsWrapper(hostsFn docker.RegistryHosts, optAuthConfig *registrytypes.Aut
 		}
 
 		for i := range hosts {
+			isInsecure := regService.IsInsecureRegistry(hosts[i].Host)
+			if proxy, ok := regService.ProxyFor(hosts[i].Host); ok {
+				transport, err := registry.NewProxiedTransport(hosts[i].Host, proxy, !isInsecure)
+				if err != nil {
+					return nil, err
+				}
+				hosts[i].Client = &http.Client{Transport: transport}
+			}
 			if hosts[i].Authorizer == nil {
 				hosts[i].Authorizer = authorizer
-				isInsecure := regService.IsInsecureRegistry(hosts[i].Host)
 				if hosts[i].Client.Transport != nil && isInsecure {
 					hosts[i].Client.Transport = httpFallback{super: hosts[i].Client.Transport}
 				}
 			}
+			// Surface 429 responses as a typed rate-limit error carrying
+			// the Retry-After and quota headers; see errRateLimited. The
+			// client is copied rather than mutated because the hosts
+			// callback may hand out the same client on every call.
+			client := *hosts[i].Client
+			client.Transport = rateLimitTransport{super: client.Transport}
+			hosts[i].Client = &client
+		}
+		// Accelerators go first so content is fetched from them when they
+		// have it; the fetcher falls through to the origin hosts when they
+		// don't.
+		return append(accelerators[:len(accelerators):len(accelerators)], hosts...), nil
+	}
+}
+
+// acceleratorHosts converts the configured distribution-accelerator URLs into
+// registry hosts that are consulted before the origin registry on pulls.
+// They only get the pull capability: manifests are still resolved against the
+// origin, and content the accelerator doesn't hold falls back to it, so a
+// missing or broken peer never changes what is pulled.
+func (i *ImageService) acceleratorHosts() []docker.RegistryHost {
+	hosts := make([]docker.RegistryHost, 0, len(i.distributionAccelerators))
+	for _, accelerator := range i.distributionAccelerators {
+		u, err := url.Parse(accelerator)
+		if err != nil || u.Host == "" {
+			log.G(context.TODO()).WithField("url", accelerator).Warn("ignoring invalid distribution accelerator")
+			continue
 		}
-		return hosts, nil
+		hosts = append(hosts, docker.RegistryHost{
+			Client:       http.DefaultClient,
+			Host:         u.Host,
+			Scheme:       u.Scheme,
+			Path:         path.Join(u.Path, "/v2"),
+			Capabilities: docker.HostCapabilityPull,
+		})
 	}
+	return hosts
 }
 
 func authorizationCredsFromAuthConfig(authConfig registrytypes.AuthConfig) docker.AuthorizerOpt {
@@ -0,0 +1,73 @@
+package containerd
+
+import (
+	"context"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/leases"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// RestoreContainer re-attaches a loaded container to its rootfs snapshot
+// after a daemon restart. Containers record the snapshotter their snapshot
+// was created in, which is not necessarily the daemon's current default, so
+// the snapshot is looked up there instead of requiring an exact match with
+// the default. Nothing is unpacked or copied: the existing snapshot is
+// reused as is, so live-restored containers keep running on their rootfs.
+func (i *ImageService) RestoreContainer(ctx context.Context, ctr *container.Container) error {
+	if ctr.Driver == "" {
+		// Containers created before the snapshotter was recorded can only
+		// live in the default one.
+		ctr.Driver = i.snapshotter
+	}
+	if _, err := i.client.SnapshotService(ctr.Driver).Stat(ctx, ctr.ID); err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return errdefs.NotFound(errors.Wrapf(err, "rootfs snapshot of container %s not found in snapshotter %s", ctr.ID, ctr.Driver))
+		}
+		return errdefs.System(err)
+	}
+	return nil
+}
+
+// EnsureContainerSnapshot recreates the container's active rootfs snapshot
+// from its image chain when it no longer exists. This is what a CRIU
+// checkpoint restore needs when the snapshot was cleaned up while the
+// container was checkpointed out: the fresh snapshot starts from the image
+// content, so filesystem changes made after the image was unpacked are not
+// part of it.
+func (i *ImageService) EnsureContainerSnapshot(ctx context.Context, ctr *container.Container) error {
+	if ctr.Driver == "" {
+		ctr.Driver = i.snapshotter
+	}
+	_, err := i.client.SnapshotService(ctr.Driver).Stat(ctx, ctr.ID)
+	if err == nil {
+		return nil
+	}
+	if !cerrdefs.IsNotFound(err) {
+		return errdefs.System(err)
+	}
+	if ctr.Driver != i.snapshotter {
+		return errdefs.Conflict(errors.Errorf("rootfs snapshot of container %s was removed and cannot be recreated in snapshotter %s", ctr.ID, ctr.Driver))
+	}
+	if ctr.ImageID == "" {
+		return errdefs.NotFound(errors.Errorf("rootfs snapshot of container %s was removed and the container has no image to recreate it from", ctr.ID))
+	}
+
+	// The lease taken for the original snapshot may have survived its
+	// removal; drop it so PrepareSnapshot can take it again.
+	if err := i.client.LeasesService().Delete(ctx, leases.Lease{ID: ctr.ID}); err != nil && !cerrdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("container", ctr.ID).Debug("failed to delete stale snapshot lease")
+	}
+
+	var platform *ocispec.Platform
+	if ctr.ImageManifest != nil && ctr.ImageManifest.Platform != nil {
+		platform = ctr.ImageManifest.Platform
+	}
+
+	log.G(ctx).WithField("container", ctr.ID).Info("recreating missing rootfs snapshot from image")
+	return i.PrepareSnapshot(ctx, ctr.ID, ctr.ImageID.String(), platform)
+}
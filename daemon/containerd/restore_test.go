@@ -0,0 +1,45 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestRestoreContainer(t *testing.T) {
+	ctx := context.Background()
+	service := fakeImageService(t)
+
+	_, err := service.client.SnapshotService("native").Prepare(ctx, "c1", "")
+	assert.NilError(t, err)
+
+	assert.NilError(t, service.RestoreContainer(ctx, &container.Container{ID: "c1", Driver: "native"}))
+
+	// An empty driver falls back to the default snapshotter and is
+	// recorded on the container.
+	ctr := &container.Container{ID: "c1"}
+	assert.NilError(t, service.RestoreContainer(ctx, ctr))
+	assert.Check(t, is.Equal(ctr.Driver, "native"))
+
+	err = service.RestoreContainer(ctx, &container.Container{ID: "gone", Driver: "native"})
+	assert.Check(t, is.ErrorType(err, errdefs.IsNotFound))
+}
+
+func TestEnsureContainerSnapshot(t *testing.T) {
+	ctx := context.Background()
+	service := fakeImageService(t)
+
+	_, err := service.client.SnapshotService("native").Prepare(ctx, "c1", "")
+	assert.NilError(t, err)
+
+	// An existing snapshot is reused as is.
+	assert.NilError(t, service.EnsureContainerSnapshot(ctx, &container.Container{ID: "c1", Driver: "native"}))
+
+	// Without an image there is nothing to recreate the snapshot from.
+	err = service.EnsureContainerSnapshot(ctx, &container.Container{ID: "gone", Driver: "native"})
+	assert.Check(t, is.ErrorType(err, errdefs.IsNotFound))
+}
@@ -1,17 +1,26 @@
+// Package containerd is the containerd-backed implementation of the
+// daemon.ImageService interface. It is the only containerd image store
+// backend; fixes and feature parity with the graph driver based store in
+// daemon/images are tracked here.
 package containerd
 
 import (
 	"context"
+	"crypto"
 	"fmt"
+	"net/http"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/containerd/containerd"
 	cerrdefs "github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/plugin"
 	"github.com/containerd/containerd/remotes/docker"
-	"github.com/containerd/containerd/snapshots"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types/system"
 	"github.com/docker/docker/container"
 	daemonevents "github.com/docker/docker/daemon/events"
 	"github.com/docker/docker/daemon/images"
@@ -19,9 +28,12 @@ import (
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/imagepolicy"
+	"github.com/docker/docker/pkg/imagescan"
 	"github.com/docker/docker/registry"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"resenje.org/singleflight"
 )
 
 // ImageService implements daemon.ImageService
@@ -34,11 +46,127 @@ type ImageService struct {
 	eventsService   *daemonevents.Events
 	pruneRunning    atomic.Bool
 	refCountMounter snapshotter.Mounter
+
+	// imageMounts tracks the mount point of images mounted with
+	// MountImage, keyed by their snapshot key.
+	imageMountsMu sync.Mutex
+	imageMounts   map[string]string
+
+	// maxPullSize limits the manifest-declared size of pulled images;
+	// registryMaxPullSize overrides it per registry host. Zero means no
+	// limit.
+	maxPullSize         int64
+	registryMaxPullSize map[string]int64
+
+	// registrySnapshotter selects the snapshotter that images pulled from
+	// a given registry host are unpacked into, unless the pull requests
+	// one explicitly.
+	registrySnapshotter map[string]string
+
+	// distributionAccelerators are URLs of peer-to-peer blob sources
+	// (e.g. Dragonfly, Spegel) consulted before the origin registry on
+	// pulls.
+	distributionAccelerators []string
+
+	// imagePolicyPlugins are consulted before a pull is admitted.
+	imagePolicyPlugins []imagepolicy.Plugin
+
+	// imageScanPlugin scans images for vulnerabilities on demand and,
+	// when scanOnPull is set, after every pull; see image_scan.go.
+	imageScanPlugin imagescan.Plugin
+	scanOnPull      bool
+
+	// maxConcurrentUnpacks bounds the number of platforms that are
+	// unpacked in parallel after a multi-platform pull.
+	maxConcurrentUnpacks int
+
+	// maxDownloadAttempts is the number of times a pull is attempted
+	// before giving up on a retryable download error; downloadRetryWait
+	// is the unit of the backoff between attempts.
+	maxDownloadAttempts int
+	downloadRetryWait   time.Duration
+
+	// pushChunkSize is the minimum size of layer blobs that are pushed
+	// with resumable chunked uploads; zero pushes every blob in one
+	// request.
+	pushChunkSize int64
+
+	// pushSigningKey, when set, signs every pushed manifest and uploads
+	// the signature next to it; see push_signing.go.
+	pushSigningKey crypto.Signer
+
+	// unpackSnapshotLabels are applied to every snapshot created during
+	// unpack, derived from the configured UnpackOptions.
+	unpackSnapshotLabels map[string]string
+
+	// protectedTags are patterns of tags that cannot be retagged, and can
+	// only be deleted with force; see image.IsProtectedTag.
+	protectedTags []string
+
+	// danglingPruneAge is the age after which unused dangling images are
+	// removed by the background prune loop; zero disables the loop.
+	danglingPruneAge time.Duration
+
+	// maxImageAge is the age after which an image counts as stale at
+	// container create; zero disables the check. maxImageAgeRefuse fails
+	// creation from stale images instead of logging a warning; see
+	// image_freshness.go.
+	maxImageAge       time.Duration
+	maxImageAgeRefuse bool
+
+	// credentialHelpers resolves registry credentials from the daemon's
+	// configured credential helpers when an operation carries no
+	// client-provided credentials; nil when none are configured.
+	credentialHelpers *registry.CredentialHelpers
+
+	// sharedContentStore is the path of a read-only content store whose
+	// blobs are copied locally instead of being downloaded during pulls;
+	// see shared_content.go.
+	sharedContentStore string
+
+	// configCache holds recently read image config blobs, shared by image
+	// listing, inspection and the build cache.
+	configCache *configCache
+
+	// selfEvents tracks image names and IDs the daemon recently logged
+	// events for, so watchContainerdEvents can tell its own operations
+	// apart from those of other containerd clients.
+	selfEventsMu sync.Mutex
+	selfEvents   map[string]time.Time
+
+	// imageCount caches the number of image records so /info does not
+	// have to list every image. It is valid while imageCountValid is set
+	// and invalidated from containerd image events; imageCountGen detects
+	// invalidations that race a concurrent recount. imageCountEventsLost
+	// disables caching once the event stream has failed.
+	imageCount           atomic.Int64
+	imageCountGen        atomic.Int64
+	imageCountValid      atomic.Bool
+	imageCountEventsLost atomic.Bool
+
+	// snapshotUsageCache holds the per-snapshotter usage maps collected by
+	// getSnapshotUsage, shared across the size calculations of one API
+	// call; snapshotUsageFlight coalesces concurrent walks.
+	snapshotUsageMu     sync.Mutex
+	snapshotUsageCache  map[string]*snapshotUsageEntry
+	snapshotUsageFlight singleflight.Group[string, *snapshotUsage]
+
+	// connectionHealthy reflects the last observed state of the containerd
+	// connection, maintained by monitorConnectionHealth.
+	connectionHealthy atomic.Bool
+	healthCancel      context.CancelFunc
+
+	// registryServer serves the read-only Registry API when the daemon is
+	// configured as a local registry mirror; see ServeRegistry.
+	registryServer *http.Server
 }
 
 type RegistryConfigProvider interface {
 	IsInsecureRegistry(host string) bool
+	AllowsNondistributableArtifacts(host string) bool
 	ResolveRepository(name reference.Named) (*registry.RepositoryInfo, error)
+	CheckRegistryIsAllowed(host string) error
+	ProxyFor(host string) (string, bool)
 }
 
 type ImageServiceConfig struct {
@@ -49,11 +177,78 @@ type ImageServiceConfig struct {
 	Registry        RegistryConfigProvider
 	EventsService   *daemonevents.Events
 	RefCountMounter snapshotter.Mounter
+
+	// MaxPullSize is the maximum manifest-declared size of an image that
+	// may be pulled; RegistryMaxPullSize overrides it per registry host.
+	MaxPullSize         int64
+	RegistryMaxPullSize map[string]int64
+
+	// RegistrySnapshotter selects the snapshotter that images pulled from
+	// a given registry host are unpacked into, unless the pull requests
+	// one explicitly.
+	RegistrySnapshotter map[string]string
+
+	// DistributionAccelerators are URLs of peer-to-peer blob sources
+	// (e.g. Dragonfly, Spegel) consulted before the origin registry on
+	// pulls.
+	DistributionAccelerators []string
+
+	// ImagePolicyPlugins are consulted before a pull is admitted.
+	ImagePolicyPlugins []imagepolicy.Plugin
+
+	// ImageScanPlugin scans images for vulnerabilities on demand and,
+	// when ScanOnPull is set, after every pull.
+	ImageScanPlugin imagescan.Plugin
+	ScanOnPull      bool
+
+	// MaxConcurrentUnpacks bounds the number of platforms that are
+	// unpacked in parallel after a multi-platform pull.
+	MaxConcurrentUnpacks int
+
+	// MaxDownloadAttempts is the number of times a pull is attempted
+	// before giving up on a retryable download error.
+	MaxDownloadAttempts int
+
+	// PushChunkSize is the minimum size of layer blobs that are pushed
+	// with resumable chunked uploads; zero pushes every blob in one
+	// request.
+	PushChunkSize int64
+
+	// PushSigningKey, when set, signs every pushed manifest and uploads
+	// the signature next to it.
+	PushSigningKey crypto.Signer
+
+	// UnpackOptions configures snapshotter-specific unpack behavior.
+	UnpackOptions UnpackOptions
+
+	// ProtectedTags are patterns of tags that cannot be retagged, and can
+	// only be deleted with force; see image.IsProtectedTag.
+	ProtectedTags []string
+
+	// DanglingPruneAge is the age after which unused dangling images are
+	// removed by the background prune loop; zero disables the loop.
+	DanglingPruneAge time.Duration
+
+	// MaxImageAge is the age after which an image counts as stale at
+	// container create; zero disables the check. MaxImageAgeRefuse fails
+	// creation from stale images instead of logging a warning.
+	MaxImageAge       time.Duration
+	MaxImageAgeRefuse bool
+
+	// CredentialHelpers resolves registry credentials from the daemon's
+	// configured credential helpers when an operation carries no
+	// client-provided credentials.
+	CredentialHelpers *registry.CredentialHelpers
+
+	// SharedContentStore is the path of a read-only containerd content
+	// store that is consulted for blobs before they are downloaded on
+	// pull, typically the host's store mounted into a nested daemon.
+	SharedContentStore string
 }
 
 // NewService creates a new ImageService.
 func NewService(config ImageServiceConfig) *ImageService {
-	return &ImageService{
+	i := &ImageService{
 		client:          config.Client,
 		containers:      config.Containers,
 		snapshotter:     config.Snapshotter,
@@ -61,7 +256,50 @@ func NewService(config ImageServiceConfig) *ImageService {
 		registryService: config.Registry,
 		eventsService:   config.EventsService,
 		refCountMounter: config.RefCountMounter,
+
+		maxPullSize:         config.MaxPullSize,
+		registryMaxPullSize: config.RegistryMaxPullSize,
+		registrySnapshotter: config.RegistrySnapshotter,
+
+		distributionAccelerators: config.DistributionAccelerators,
+		imagePolicyPlugins:       config.ImagePolicyPlugins,
+		imageScanPlugin:          config.ImageScanPlugin,
+		scanOnPull:               config.ScanOnPull,
+		maxConcurrentUnpacks:     config.MaxConcurrentUnpacks,
+		maxDownloadAttempts:      config.MaxDownloadAttempts,
+		downloadRetryWait:        time.Second,
+		pushChunkSize:            config.PushChunkSize,
+		pushSigningKey:           config.PushSigningKey,
+		unpackSnapshotLabels:     config.UnpackOptions.snapshotLabels(),
+		protectedTags:            config.ProtectedTags,
+		danglingPruneAge:         config.DanglingPruneAge,
+		maxImageAge:              config.MaxImageAge,
+		maxImageAgeRefuse:        config.MaxImageAgeRefuse,
+		credentialHelpers:        config.CredentialHelpers,
+		sharedContentStore:       config.SharedContentStore,
+
+		configCache: newConfigCache(),
+	}
+	if i.maxConcurrentUnpacks < 1 {
+		i.maxConcurrentUnpacks = 1
 	}
+	if i.maxDownloadAttempts < 1 {
+		i.maxDownloadAttempts = 1
+	}
+
+	// Assume the connection is healthy until the monitor observes otherwise,
+	// so operations issued right after startup aren't rejected.
+	i.connectionHealthy.Store(true)
+	healthCtx, cancel := context.WithCancel(context.Background())
+	i.healthCancel = cancel
+	go i.monitorConnectionHealth(healthCtx)
+	go i.watchContainerdEvents(healthCtx)
+	go i.cleanupTemporaryImages(healthCtx)
+	if i.danglingPruneAge > 0 {
+		go i.pruneDanglingLoop(healthCtx)
+	}
+
+	return i
 }
 
 // DistributionServices return services controlling daemon image storage.
@@ -69,30 +307,81 @@ func (i *ImageService) DistributionServices() images.DistributionServices {
 	return images.DistributionServices{}
 }
 
+// Capabilities returns the optional image-store features implemented by the
+// containerd-backed image service, advertised in the "Capabilities" section
+// of "/info".
+func (i *ImageService) Capabilities() []string {
+	return []string{
+		system.CapImageConvert,
+		system.CapImageFlatten,
+		system.CapImageMount,
+		system.CapImageMultiPlatform,
+		system.CapImageRecordLabels,
+		system.CapImageReferences,
+		system.CapImageRegistryMirror,
+		system.CapImageSnapshotterSelect,
+		system.CapImageVerify,
+	}
+}
+
 // CountImages returns the number of images stored by ImageService
 // called from info.go
-func (i *ImageService) CountImages() int {
-	imgs, err := i.client.ListImages(context.TODO())
+func (i *ImageService) CountImages(ctx context.Context) int {
+	if _, scoped := namespaces.Namespace(ctx); scoped {
+		// Requests scoped to a mapped image namespace see a different
+		// image store; the cached count belongs to the daemon's default
+		// namespace and must be neither served to them nor overwritten.
+		imgs, err := i.client.ImageService().List(ctx)
+		if err != nil {
+			return 0
+		}
+		return len(imgs)
+	}
+
+	if i.imageCountValid.Load() {
+		return int(i.imageCount.Load())
+	}
+
+	gen := i.imageCountGen.Load()
+	imgs, err := i.client.ImageService().List(ctx)
 	if err != nil {
 		return 0
 	}
+	// Only cache the count if no image event invalidated it while we were
+	// listing, and events are still being delivered to keep it fresh.
+	if !i.imageCountEventsLost.Load() && i.imageCountGen.Load() == gen {
+		i.imageCount.Store(int64(len(imgs)))
+		i.imageCountValid.Store(true)
+	}
 
 	return len(imgs)
 }
 
+// invalidateImageCount discards the cached image count after an image record
+// was created or deleted, by this daemon or any other containerd client.
+func (i *ImageService) invalidateImageCount() {
+	i.imageCountGen.Add(1)
+	i.imageCountValid.Store(false)
+}
+
 // CreateLayer creates a filesystem layer for a container.
 // called from create.go
 // TODO: accept an opt struct instead of container?
 func (i *ImageService) CreateLayer(container *container.Container, initFunc layer.MountInit) (layer.RWLayer, error) {
-	return nil, errdefs.NotImplemented(errdefs.NotImplemented(errors.New("not implemented")))
+	return nil, errdefs.NotImplemented(errors.New("not implemented"))
 }
 
 // LayerStoreStatus returns the status for each layer store
 // called from info.go
 func (i *ImageService) LayerStoreStatus() [][2]string {
+	containerdStatus := "ok"
+	if !i.connectionHealthy.Load() {
+		containerdStatus = "unavailable"
+	}
 	// TODO(thaJeztah) do we want to add more details about the driver here?
 	return [][2]string{
 		{"driver-type", string(plugin.SnapshotPlugin)},
+		{"containerd-connection", containerdStatus},
 	}
 }
 
@@ -106,6 +395,12 @@ func (i *ImageService) GetLayerMountID(cid string) (string, error) {
 // Cleanup resources before the process is shutdown.
 // called from daemon.go Daemon.Shutdown()
 func (i *ImageService) Cleanup() error {
+	if i.healthCancel != nil {
+		i.healthCancel()
+	}
+	if i.registryServer != nil {
+		i.registryServer.Close()
+	}
 	return nil
 }
 
@@ -124,25 +419,19 @@ func (i *ImageService) ReleaseLayer(rwlayer layer.RWLayer) error {
 // LayerDiskUsage returns the number of bytes used by layer stores
 // called from disk_usage.go
 func (i *ImageService) LayerDiskUsage(ctx context.Context) (int64, error) {
-	var allLayersSize int64
 	// TODO(thaJeztah): do we need to take multiple snapshotters into account? See https://github.com/moby/moby/issues/45273
-	snapshotter := i.client.SnapshotService(i.snapshotter)
-	snapshotter.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
-		usage, err := snapshotter.Usage(ctx, info.Name)
-		if err != nil {
-			return err
-		}
-		allLayersSize += usage.Size
-		return nil
-	})
-	return allLayersSize, nil
+	usageMap, err := i.getSnapshotUsage(ctx, i.snapshotter)
+	if err != nil {
+		return 0, err
+	}
+	return usageMap.allUsage().Size, nil
 }
 
 // UpdateConfig values
 //
 // called from reload.go
-func (i *ImageService) UpdateConfig(maxDownloads, maxUploads int) {
-	panic("not implemented")
+func (i *ImageService) UpdateConfig(ctx context.Context, maxDownloads, maxUploads int) {
+	log.G(ctx).Warn("max-concurrent-downloads and max-concurrent-uploads are not supported with the containerd image store; ignoring the updated values")
 }
 
 // GetLayerFolders returns the layer folders from an image RootFS.
@@ -157,16 +446,19 @@ func (i *ImageService) GetContainerLayerSize(ctx context.Context, containerID st
 		return 0, 0, nil
 	}
 
-	snapshotter := i.client.SnapshotService(ctr.Driver)
-	rwLayerUsage, err := snapshotter.Usage(ctx, containerID)
+	usageMap, err := i.getSnapshotUsage(ctx, ctr.Driver)
+	if err != nil {
+		return 0, 0, err
+	}
+	rwLayerUsage, err := usageMap.directUsage(containerID)
 	if err != nil {
 		if cerrdefs.IsNotFound(err) {
 			return 0, 0, errdefs.NotFound(fmt.Errorf("rw layer snapshot not found for container %s", containerID))
 		}
-		return 0, 0, errdefs.System(errors.Wrapf(err, "snapshotter.Usage failed for %s", containerID))
+		return 0, 0, err
 	}
 
-	unpackedUsage, err := calculateSnapshotParentUsage(ctx, snapshotter, containerID)
+	unpackedUsage, err := usageMap.parentUsage(containerID)
 	if err != nil {
 		if cerrdefs.IsNotFound(err) {
 			log.G(ctx).WithField("ctr", containerID).Warn("parent of container snapshot no longer present")
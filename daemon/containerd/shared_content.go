@@ -0,0 +1,62 @@
+package containerd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// sharedContentDir is the directory containerd keeps the content store in
+// under its root. It is accepted as part of a shared-content-store path so
+// the option can point directly at a host containerd root.
+const sharedContentDir = "io.containerd.content.v1.content"
+
+// preseedFromSharedStore wraps a pull handler so that every descriptor is
+// first looked up in the configured read-only shared content store; blobs
+// found there are copied into the daemon's own store, making the download
+// handler skip them. A nested daemon in CI with the host's store mounted
+// read-only thus only downloads the blobs the host doesn't already have.
+func (i *ImageService) preseedFromSharedStore(h images.Handler) images.Handler {
+	store := i.client.ContentStore()
+	return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if err := i.copySharedBlob(ctx, store, desc); err != nil && !cerrdefs.IsNotFound(err) {
+			// The blob will be downloaded instead; reusing shared
+			// content is an optimization, not a requirement.
+			log.G(ctx).WithError(err).WithField("digest", desc.Digest).Debug("failed to copy blob from the shared content store")
+		}
+		return h.Handle(ctx, desc)
+	})
+}
+
+// copySharedBlob copies the blob described by desc from the shared
+// read-only content store into ingester, verifying its digest and size on
+// the way. It returns a "not found" error when the shared store doesn't
+// hold the blob, and is a no-op when the local store already does.
+func (i *ImageService) copySharedBlob(ctx context.Context, ingester content.Ingester, desc ocispec.Descriptor) error {
+	blob := filepath.Join("blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+
+	f, err := os.Open(filepath.Join(i.sharedContentStore, blob))
+	if errors.Is(err, os.ErrNotExist) {
+		// Also accept a path pointing at a containerd root rather than
+		// at the content store inside it.
+		f, err = os.Open(filepath.Join(i.sharedContentStore, sharedContentDir, blob))
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.Wrapf(cerrdefs.ErrNotFound, "blob %s not present in the shared content store", desc.Digest)
+		}
+		return err
+	}
+	defer f.Close()
+
+	// WriteBlob verifies the content against the descriptor and returns
+	// nil when the blob already exists locally.
+	return content.WriteBlob(ctx, ingester, "shared-"+desc.Digest.String(), f, desc)
+}
@@ -0,0 +1,80 @@
+package containerd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestCopySharedBlob(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+	i.sharedContentStore = t.TempDir()
+
+	blob := []byte(`{"layers":[]}`)
+	dgst := digest.FromBytes(blob)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    dgst,
+		Size:      int64(len(blob)),
+	}
+
+	blobDir := filepath.Join(i.sharedContentStore, "blobs", dgst.Algorithm().String())
+	assert.NilError(t, os.MkdirAll(blobDir, 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(blobDir, dgst.Encoded()), blob, 0o644))
+
+	store := i.client.ContentStore()
+	assert.NilError(t, i.copySharedBlob(ctx, store, desc))
+
+	data, err := content.ReadBlob(ctx, store, desc)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(data, blob))
+
+	// Copying again is a no-op because the blob already exists locally.
+	assert.NilError(t, i.copySharedBlob(ctx, store, desc))
+
+	// Blobs the shared store doesn't hold report "not found" so the pull
+	// falls back to downloading them.
+	missing := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    digest.FromString("missing"),
+		Size:      7,
+	}
+	err = i.copySharedBlob(ctx, store, missing)
+	assert.Check(t, cerrdefs.IsNotFound(err), "expected a not found error, got: %v", err)
+}
+
+func TestCopySharedBlobFromContainerdRoot(t *testing.T) {
+	ctx := context.Background()
+	i := fakeImageService(t)
+	i.sharedContentStore = t.TempDir()
+
+	blob := []byte("layer data")
+	dgst := digest.FromBytes(blob)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    dgst,
+		Size:      int64(len(blob)),
+	}
+
+	// The option may point at a containerd root instead of the content
+	// store directory inside it.
+	blobDir := filepath.Join(i.sharedContentStore, sharedContentDir, "blobs", dgst.Algorithm().String())
+	assert.NilError(t, os.MkdirAll(blobDir, 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(blobDir, dgst.Encoded()), blob, 0o644))
+
+	store := i.client.ContentStore()
+	assert.NilError(t, i.copySharedBlob(ctx, store, desc))
+
+	data, err := content.ReadBlob(ctx, store, desc)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(data, blob))
+}
@@ -0,0 +1,137 @@
+package containerd
+
+import (
+	"context"
+	"time"
+
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// snapshotUsageValidity is how long a collected usage map is reused. It only
+// needs to span a single API call — disk usage lists images and then sizes
+// containers — while keeping freshly created snapshots from being missed for
+// long.
+const snapshotUsageValidity = 2 * time.Second
+
+// snapshotUsage holds the usage and parentage of every snapshot of one
+// snapshotter, collected in a single walk. Per-image size calculations
+// otherwise stat and size the same shared ancestor snapshots once per image,
+// which dominates the latency of docker images and docker system df.
+type snapshotUsage struct {
+	usage  map[string]snapshots.Usage
+	parent map[string]string
+}
+
+// collectSnapshotUsage walks the snapshotter once and records the usage and
+// parent of every snapshot.
+func collectSnapshotUsage(ctx context.Context, snapshotter snapshots.Snapshotter) (*snapshotUsage, error) {
+	m := &snapshotUsage{
+		usage:  map[string]snapshots.Usage{},
+		parent: map[string]string{},
+	}
+	err := snapshotter.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
+		usage, err := snapshotter.Usage(ctx, info.Name)
+		if err != nil {
+			return errors.Wrapf(err, "snapshotter.Usage failed for %s", info.Name)
+		}
+		m.usage[info.Name] = usage
+		m.parent[info.Name] = info.Parent
+		return nil
+	})
+	if err != nil {
+		return nil, errdefs.System(err)
+	}
+	return m, nil
+}
+
+// directUsage returns the usage of the named snapshot alone.
+func (m *snapshotUsage) directUsage(name string) (snapshots.Usage, error) {
+	usage, ok := m.usage[name]
+	if !ok {
+		return snapshots.Usage{}, errdefs.NotFound(errors.Errorf("snapshot %s not found", name))
+	}
+	return usage, nil
+}
+
+// totalUsage returns the usage of the named snapshot including all of its
+// ancestors, like calculateSnapshotTotalUsage but from the collected map.
+func (m *snapshotUsage) totalUsage(name string) (snapshots.Usage, error) {
+	var total snapshots.Usage
+	for next := name; next != ""; {
+		usage, ok := m.usage[next]
+		if !ok {
+			return total, errdefs.NotFound(errors.Errorf("non-existing ancestor %s of %s", next, name))
+		}
+		total.Size += usage.Size
+		total.Inodes += usage.Inodes
+		next = m.parent[next]
+	}
+	return total, nil
+}
+
+// parentUsage returns the usage of all ancestors of the named snapshot, not
+// including the snapshot itself.
+func (m *snapshotUsage) parentUsage(name string) (snapshots.Usage, error) {
+	if _, ok := m.usage[name]; !ok {
+		return snapshots.Usage{}, errdefs.NotFound(errors.Errorf("snapshot %s not found", name))
+	}
+	parent := m.parent[name]
+	if parent == "" {
+		return snapshots.Usage{}, errdefs.NotFound(errors.Errorf("snapshot %s has no parent", name))
+	}
+	return m.totalUsage(parent)
+}
+
+// allUsage returns the summed usage of every snapshot.
+func (m *snapshotUsage) allUsage() snapshots.Usage {
+	var total snapshots.Usage
+	for _, usage := range m.usage {
+		total.Size += usage.Size
+		total.Inodes += usage.Inodes
+	}
+	return total
+}
+
+// getSnapshotUsage returns the usage map for the given snapshotter,
+// collecting it at most once per validity window. Concurrent callers share a
+// single walk, so the image list and per-container size calculations of one
+// disk usage request do not each re-walk the snapshotter.
+func (i *ImageService) getSnapshotUsage(ctx context.Context, snapshotterName string) (*snapshotUsage, error) {
+	// Requests scoped to a mapped image namespace walk a different
+	// snapshot set; key their usage maps separately so they neither see
+	// nor poison the default namespace's cache.
+	key := snapshotterName
+	if ns, ok := namespaces.Namespace(ctx); ok {
+		key = ns + "/" + snapshotterName
+	}
+
+	i.snapshotUsageMu.Lock()
+	if e, ok := i.snapshotUsageCache[key]; ok && time.Since(e.collected) < snapshotUsageValidity {
+		i.snapshotUsageMu.Unlock()
+		return e.usage, nil
+	}
+	i.snapshotUsageMu.Unlock()
+
+	usage, _, err := i.snapshotUsageFlight.Do(ctx, key, func(ctx context.Context) (*snapshotUsage, error) {
+		usage, err := collectSnapshotUsage(ctx, i.client.SnapshotService(snapshotterName))
+		if err != nil {
+			return nil, err
+		}
+		i.snapshotUsageMu.Lock()
+		if i.snapshotUsageCache == nil {
+			i.snapshotUsageCache = map[string]*snapshotUsageEntry{}
+		}
+		i.snapshotUsageCache[key] = &snapshotUsageEntry{usage: usage, collected: time.Now()}
+		i.snapshotUsageMu.Unlock()
+		return usage, nil
+	})
+	return usage, err
+}
+
+type snapshotUsageEntry struct {
+	usage     *snapshotUsage
+	collected time.Time
+}
@@ -73,8 +73,13 @@ func (i *ImageService) ensureDanglingImage(ctx context.Context, from containerdi
 	return err
 }
 
+// danglingImageRepository is the repository the daemon uses for bookkeeping
+// records of untagged images. It is reserved; user references are not allowed
+// to use it.
+const danglingImageRepository = "moby-dangling"
+
 func danglingImageName(digest digest.Digest) string {
-	return "moby-dangling@" + digest.String()
+	return danglingImageRepository + "@" + digest.String()
 }
 
 func isDanglingImage(image containerdimages.Image) bool {
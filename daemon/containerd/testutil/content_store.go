@@ -0,0 +1,307 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/filters"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// contentStore is an in-memory content.Store.
+type contentStore struct {
+	mu      sync.RWMutex
+	blobs   map[digest.Digest][]byte
+	infos   map[digest.Digest]content.Info
+	ingests map[string]*contentWriter
+}
+
+// NewContentStore returns an empty in-memory content.Store.
+func NewContentStore() content.Store {
+	return &contentStore{
+		blobs:   map[digest.Digest][]byte{},
+		infos:   map[digest.Digest]content.Info{},
+		ingests: map[string]*contentWriter{},
+	}
+}
+
+func (s *contentStore) Info(ctx context.Context, dgst digest.Digest) (content.Info, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, ok := s.infos[dgst]
+	if !ok {
+		return content.Info{}, errors.Wrapf(cerrdefs.ErrNotFound, "content %v", dgst)
+	}
+	return info, nil
+}
+
+func (s *contentStore) Update(ctx context.Context, info content.Info, fieldpaths ...string) (content.Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, ok := s.infos[info.Digest]
+	if !ok {
+		return content.Info{}, errors.Wrapf(cerrdefs.ErrNotFound, "content %v", info.Digest)
+	}
+
+	if len(fieldpaths) > 0 {
+		for _, path := range fieldpaths {
+			if strings.HasPrefix(path, "labels.") {
+				if updated.Labels == nil {
+					updated.Labels = map[string]string{}
+				}
+				key := strings.TrimPrefix(path, "labels.")
+				updated.Labels[key] = info.Labels[key]
+				continue
+			}
+			if path == "labels" {
+				updated.Labels = info.Labels
+				continue
+			}
+			return content.Info{}, errors.Wrapf(cerrdefs.ErrInvalidArgument, "cannot update %q field on content %v", path, info.Digest)
+		}
+	} else {
+		updated.Labels = info.Labels
+	}
+
+	updated.UpdatedAt = time.Now().UTC()
+	s.infos[info.Digest] = updated
+	return updated, nil
+}
+
+func (s *contentStore) Walk(ctx context.Context, fn content.WalkFunc, fltrs ...string) error {
+	filter, err := filters.ParseAll(fltrs...)
+	if err != nil {
+		return errors.Wrap(cerrdefs.ErrInvalidArgument, err.Error())
+	}
+
+	s.mu.RLock()
+	infos := make([]content.Info, 0, len(s.infos))
+	for _, info := range s.infos {
+		if filter.Match(adaptContentInfo(info)) {
+			infos = append(infos, info)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *contentStore) Delete(ctx context.Context, dgst digest.Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blobs[dgst]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "content %v", dgst)
+	}
+	delete(s.blobs, dgst)
+	delete(s.infos, dgst)
+	return nil
+}
+
+func (s *contentStore) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blob, ok := s.blobs[desc.Digest]
+	if !ok {
+		return nil, errors.Wrapf(cerrdefs.ErrNotFound, "content %v", desc.Digest)
+	}
+	return &readerAt{Reader: bytes.NewReader(blob), size: int64(len(blob))}, nil
+}
+
+func (s *contentStore) Status(ctx context.Context, ref string) (content.Status, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, ok := s.ingests[ref]
+	if !ok {
+		return content.Status{}, errors.Wrapf(cerrdefs.ErrNotFound, "ingest %q", ref)
+	}
+	st, _ := w.Status()
+	return st, nil
+}
+
+func (s *contentStore) ListStatuses(ctx context.Context, fltrs ...string) ([]content.Status, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var statuses []content.Status
+	for _, w := range s.ingests {
+		st, _ := w.Status()
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+func (s *contentStore) Abort(ctx context.Context, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ingests[ref]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "ingest %q", ref)
+	}
+	delete(s.ingests, ref)
+	return nil
+}
+
+func (s *contentStore) Writer(ctx context.Context, opts ...content.WriterOpt) (content.Writer, error) {
+	var wOpts content.WriterOpts
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	if wOpts.Ref == "" {
+		return nil, errors.Wrap(cerrdefs.ErrInvalidArgument, "ref must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blobs[wOpts.Desc.Digest]; ok && wOpts.Desc.Digest != "" {
+		return nil, errors.Wrapf(cerrdefs.ErrAlreadyExists, "content %v", wOpts.Desc.Digest)
+	}
+	w := &contentWriter{
+		store:     s,
+		ref:       wOpts.Ref,
+		expected:  wOpts.Desc.Digest,
+		total:     wOpts.Desc.Size,
+		startedAt: time.Now().UTC(),
+	}
+	s.ingests[wOpts.Ref] = w
+	return w, nil
+}
+
+// contentWriter is an in-progress ingest into a contentStore.
+type contentWriter struct {
+	store     *contentStore
+	ref       string
+	expected  digest.Digest
+	total     int64
+	startedAt time.Time
+	updatedAt time.Time
+	buf       bytes.Buffer
+	committed digest.Digest
+}
+
+func (w *contentWriter) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	w.updatedAt = time.Now().UTC()
+	return n, err
+}
+
+func (w *contentWriter) Close() error {
+	return nil
+}
+
+func (w *contentWriter) Digest() digest.Digest {
+	if w.committed != "" {
+		return w.committed
+	}
+	return digest.FromBytes(w.buf.Bytes())
+}
+
+func (w *contentWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	data := w.buf.Bytes()
+	if size > 0 && size != int64(len(data)) {
+		return errors.Wrapf(cerrdefs.ErrFailedPrecondition, "unexpected commit size %d, expected %d", len(data), size)
+	}
+	dgst := digest.FromBytes(data)
+	if expected != "" && expected != dgst {
+		return errors.Wrapf(cerrdefs.ErrFailedPrecondition, "unexpected commit digest %v, expected %v", dgst, expected)
+	}
+
+	var info content.Info
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			return err
+		}
+	}
+
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+
+	delete(w.store.ingests, w.ref)
+	if _, ok := w.store.blobs[dgst]; ok {
+		return errors.Wrapf(cerrdefs.ErrAlreadyExists, "content %v", dgst)
+	}
+	now := time.Now().UTC()
+	w.store.blobs[dgst] = append([]byte(nil), data...)
+	w.store.infos[dgst] = content.Info{
+		Digest:    dgst,
+		Size:      int64(len(data)),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Labels:    info.Labels,
+	}
+	w.committed = dgst
+	return nil
+}
+
+func (w *contentWriter) Status() (content.Status, error) {
+	return content.Status{
+		Ref:       w.ref,
+		Offset:    int64(w.buf.Len()),
+		Total:     w.total,
+		Expected:  w.expected,
+		StartedAt: w.startedAt,
+		UpdatedAt: w.updatedAt,
+	}, nil
+}
+
+func (w *contentWriter) Truncate(size int64) error {
+	if size != 0 {
+		return errors.Wrap(cerrdefs.ErrInvalidArgument, "only truncate to zero is supported")
+	}
+	w.buf.Reset()
+	return nil
+}
+
+// readerAt adapts a bytes.Reader to content.ReaderAt.
+type readerAt struct {
+	*bytes.Reader
+	size int64
+}
+
+func (r *readerAt) Size() int64 {
+	return r.size
+}
+
+func (r *readerAt) Close() error {
+	return nil
+}
+
+func adaptContentInfo(info content.Info) filters.Adaptor {
+	return filters.AdapterFunc(func(fieldpath []string) (string, bool) {
+		if len(fieldpath) == 0 {
+			return "", false
+		}
+		switch fieldpath[0] {
+		case "digest":
+			return info.Digest.String(), len(info.Digest) > 0
+		case "size":
+			return "", false
+		case "labels":
+			if len(fieldpath) < 2 {
+				return "", false
+			}
+			v, ok := info.Labels[strings.Join(fieldpath[1:], ".")]
+			return v, ok
+		}
+		return "", false
+	})
+}
@@ -0,0 +1,146 @@
+package testutil
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/filters"
+	"github.com/containerd/containerd/images"
+	"github.com/pkg/errors"
+)
+
+// imageStore is an in-memory images.Store.
+type imageStore struct {
+	mu     sync.RWMutex
+	images map[string]images.Image
+}
+
+// NewImageStore returns an empty in-memory images.Store.
+func NewImageStore() images.Store {
+	return &imageStore{images: map[string]images.Image{}}
+}
+
+func (s *imageStore) Get(ctx context.Context, name string) (images.Image, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	img, ok := s.images[name]
+	if !ok {
+		return images.Image{}, errors.Wrapf(cerrdefs.ErrNotFound, "image %q", name)
+	}
+	return img, nil
+}
+
+func (s *imageStore) List(ctx context.Context, fltrs ...string) ([]images.Image, error) {
+	filter, err := filters.ParseAll(fltrs...)
+	if err != nil {
+		return nil, errors.Wrap(cerrdefs.ErrInvalidArgument, err.Error())
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var imgs []images.Image
+	for _, img := range s.images {
+		if filter.Match(adaptImage(img)) {
+			imgs = append(imgs, img)
+		}
+	}
+	return imgs, nil
+}
+
+func (s *imageStore) Create(ctx context.Context, image images.Image) (images.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.images[image.Name]; ok {
+		return images.Image{}, errors.Wrapf(cerrdefs.ErrAlreadyExists, "image %q", image.Name)
+	}
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = time.Now().UTC()
+	}
+	image.UpdatedAt = image.CreatedAt
+	s.images[image.Name] = image
+	return image, nil
+}
+
+func (s *imageStore) Update(ctx context.Context, image images.Image, fieldpaths ...string) (images.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, ok := s.images[image.Name]
+	if !ok {
+		return images.Image{}, errors.Wrapf(cerrdefs.ErrNotFound, "image %q", image.Name)
+	}
+
+	if len(fieldpaths) > 0 {
+		for _, path := range fieldpaths {
+			if strings.HasPrefix(path, "labels.") {
+				if updated.Labels == nil {
+					updated.Labels = map[string]string{}
+				}
+				key := strings.TrimPrefix(path, "labels.")
+				updated.Labels[key] = image.Labels[key]
+				continue
+			}
+			switch path {
+			case "labels":
+				updated.Labels = image.Labels
+			case "target":
+				updated.Target = image.Target
+			default:
+				return images.Image{}, errors.Wrapf(cerrdefs.ErrInvalidArgument, "cannot update %q field on image %q", path, image.Name)
+			}
+		}
+	} else {
+		updated = image
+		updated.CreatedAt = s.images[image.Name].CreatedAt
+	}
+
+	updated.UpdatedAt = time.Now().UTC()
+	s.images[image.Name] = updated
+	return updated, nil
+}
+
+func (s *imageStore) Delete(ctx context.Context, name string, opts ...images.DeleteOpt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.images[name]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "image %q", name)
+	}
+	delete(s.images, name)
+	return nil
+}
+
+func adaptImage(obj images.Image) filters.Adaptor {
+	return filters.AdapterFunc(func(fieldpath []string) (string, bool) {
+		if len(fieldpath) == 0 {
+			return "", false
+		}
+		switch fieldpath[0] {
+		case "name":
+			return obj.Name, len(obj.Name) > 0
+		case "target":
+			if len(fieldpath) < 2 {
+				return "", false
+			}
+			switch fieldpath[1] {
+			case "digest":
+				return obj.Target.Digest.String(), len(obj.Target.Digest) > 0
+			case "mediatype":
+				return obj.Target.MediaType, len(obj.Target.MediaType) > 0
+			}
+		case "labels":
+			if len(fieldpath) < 2 {
+				return "", false
+			}
+			v, ok := obj.Labels[strings.Join(fieldpath[1:], ".")]
+			return v, ok
+		}
+		return "", false
+	})
+}
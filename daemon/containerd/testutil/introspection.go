@@ -0,0 +1,35 @@
+package testutil
+
+import (
+	"context"
+
+	api "github.com/containerd/containerd/api/services/introspection/v1"
+	"github.com/containerd/containerd/plugin"
+	"github.com/containerd/containerd/services/introspection"
+	ptypes "github.com/gogo/protobuf/types"
+)
+
+// introspectionService is an in-memory introspection.Service reporting a
+// single snapshotter plugin, enough for callers that enumerate snapshotters.
+type introspectionService struct {
+	snapshotterName string
+}
+
+func newIntrospectionService(snapshotterName string) introspection.Service {
+	return &introspectionService{snapshotterName: snapshotterName}
+}
+
+func (s *introspectionService) Plugins(ctx context.Context, fltrs []string) (*api.PluginsResponse, error) {
+	return &api.PluginsResponse{
+		Plugins: []api.Plugin{{
+			Type: string(plugin.SnapshotPlugin),
+			ID:   s.snapshotterName,
+		}},
+	}, nil
+}
+
+func (s *introspectionService) Server(ctx context.Context, _ *ptypes.Empty) (*api.ServerResponse, error) {
+	return &api.ServerResponse{
+		UUID: "00000000-0000-0000-0000-000000000000",
+	}, nil
+}
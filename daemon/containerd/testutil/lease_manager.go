@@ -0,0 +1,114 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/leases"
+	"github.com/pkg/errors"
+)
+
+// leaseManager is an in-memory leases.Manager. Leases only track their
+// resources; nothing is garbage collected when a lease is deleted.
+type leaseManager struct {
+	mu        sync.Mutex
+	leases    map[string]leases.Lease
+	resources map[string][]leases.Resource
+}
+
+// NewLeaseManager returns an empty in-memory leases.Manager.
+func NewLeaseManager() leases.Manager {
+	return &leaseManager{
+		leases:    map[string]leases.Lease{},
+		resources: map[string][]leases.Resource{},
+	}
+}
+
+func (m *leaseManager) Create(ctx context.Context, opts ...leases.Opt) (leases.Lease, error) {
+	l := leases.Lease{CreatedAt: time.Now().UTC()}
+	for _, opt := range opts {
+		if err := opt(&l); err != nil {
+			return leases.Lease{}, err
+		}
+	}
+	if l.ID == "" {
+		return leases.Lease{}, errors.Wrap(cerrdefs.ErrInvalidArgument, "lease id must not be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.leases[l.ID]; ok {
+		return leases.Lease{}, errors.Wrapf(cerrdefs.ErrAlreadyExists, "lease %q", l.ID)
+	}
+	m.leases[l.ID] = l
+	return l, nil
+}
+
+func (m *leaseManager) Delete(ctx context.Context, l leases.Lease, opts ...leases.DeleteOpt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.leases[l.ID]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "lease %q", l.ID)
+	}
+	delete(m.leases, l.ID)
+	delete(m.resources, l.ID)
+	return nil
+}
+
+func (m *leaseManager) List(ctx context.Context, fltrs ...string) ([]leases.Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls := make([]leases.Lease, 0, len(m.leases))
+	for _, l := range m.leases {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+func (m *leaseManager) AddResource(ctx context.Context, l leases.Lease, r leases.Resource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.leases[l.ID]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "lease %q", l.ID)
+	}
+	for _, existing := range m.resources[l.ID] {
+		if existing == r {
+			return nil
+		}
+	}
+	m.resources[l.ID] = append(m.resources[l.ID], r)
+	return nil
+}
+
+func (m *leaseManager) DeleteResource(ctx context.Context, l leases.Lease, r leases.Resource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.leases[l.ID]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "lease %q", l.ID)
+	}
+	rs := m.resources[l.ID]
+	for i, existing := range rs {
+		if existing == r {
+			m.resources[l.ID] = append(rs[:i], rs[i+1:]...)
+			return nil
+		}
+	}
+	return errors.Wrapf(cerrdefs.ErrNotFound, "resource %v on lease %q", r, l.ID)
+}
+
+func (m *leaseManager) ListResources(ctx context.Context, l leases.Lease) ([]leases.Resource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.leases[l.ID]; !ok {
+		return nil, errors.Wrapf(cerrdefs.ErrNotFound, "lease %q", l.ID)
+	}
+	return append([]leases.Resource(nil), m.resources[l.ID]...), nil
+}
@@ -0,0 +1,230 @@
+package testutil
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/filters"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/pkg/errors"
+)
+
+// snapshotter is an in-memory snapshots.Snapshotter. It records the snapshot
+// tree and labels but does not materialize any filesystem; Mounts returns a
+// placeholder bind mount.
+type snapshotter struct {
+	mu        sync.RWMutex
+	snapshots map[string]snapshots.Info
+}
+
+// NewSnapshotter returns an empty in-memory snapshots.Snapshotter.
+func NewSnapshotter() snapshots.Snapshotter {
+	return &snapshotter{snapshots: map[string]snapshots.Info{}}
+}
+
+func (s *snapshotter) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, ok := s.snapshots[key]
+	if !ok {
+		return snapshots.Info{}, errors.Wrapf(cerrdefs.ErrNotFound, "snapshot %q", key)
+	}
+	return info, nil
+}
+
+func (s *snapshotter) Update(ctx context.Context, info snapshots.Info, fieldpaths ...string) (snapshots.Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, ok := s.snapshots[info.Name]
+	if !ok {
+		return snapshots.Info{}, errors.Wrapf(cerrdefs.ErrNotFound, "snapshot %q", info.Name)
+	}
+
+	if len(fieldpaths) > 0 {
+		for _, path := range fieldpaths {
+			if strings.HasPrefix(path, "labels.") {
+				if updated.Labels == nil {
+					updated.Labels = map[string]string{}
+				}
+				key := strings.TrimPrefix(path, "labels.")
+				updated.Labels[key] = info.Labels[key]
+				continue
+			}
+			if path == "labels" {
+				updated.Labels = info.Labels
+				continue
+			}
+			return snapshots.Info{}, errors.Wrapf(cerrdefs.ErrInvalidArgument, "cannot update %q field on snapshot %q", path, info.Name)
+		}
+	} else {
+		updated.Labels = info.Labels
+	}
+
+	updated.Updated = time.Now().UTC()
+	s.snapshots[info.Name] = updated
+	return updated, nil
+}
+
+func (s *snapshotter) Usage(ctx context.Context, key string) (snapshots.Usage, error) {
+	if _, err := s.Stat(ctx, key); err != nil {
+		return snapshots.Usage{}, err
+	}
+	return snapshots.Usage{}, nil
+}
+
+func (s *snapshotter) Mounts(ctx context.Context, key string) ([]mount.Mount, error) {
+	if _, err := s.Stat(ctx, key); err != nil {
+		return nil, err
+	}
+	return placeholderMounts(key), nil
+}
+
+func (s *snapshotter) Prepare(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return s.create(snapshots.KindActive, key, parent, opts...)
+}
+
+func (s *snapshotter) View(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return s.create(snapshots.KindView, key, parent, opts...)
+}
+
+func (s *snapshotter) create(kind snapshots.Kind, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	info := snapshots.Info{
+		Kind:    kind,
+		Name:    key,
+		Parent:  parent,
+		Created: time.Now().UTC(),
+	}
+	info.Updated = info.Created
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.snapshots[key]; ok {
+		return nil, errors.Wrapf(cerrdefs.ErrAlreadyExists, "snapshot %q", key)
+	}
+	if parent != "" {
+		p, ok := s.snapshots[parent]
+		if !ok {
+			return nil, errors.Wrapf(cerrdefs.ErrNotFound, "parent snapshot %q", parent)
+		}
+		if p.Kind != snapshots.KindCommitted {
+			return nil, errors.Wrapf(cerrdefs.ErrInvalidArgument, "parent snapshot %q is not committed", parent)
+		}
+	}
+	s.snapshots[key] = info
+	return placeholderMounts(key), nil
+}
+
+func (s *snapshotter) Commit(ctx context.Context, name, key string, opts ...snapshots.Opt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active, ok := s.snapshots[key]
+	if !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "snapshot %q", key)
+	}
+	if active.Kind != snapshots.KindActive {
+		return errors.Wrapf(cerrdefs.ErrFailedPrecondition, "snapshot %q is not active", key)
+	}
+	if _, ok := s.snapshots[name]; ok {
+		return errors.Wrapf(cerrdefs.ErrAlreadyExists, "snapshot %q", name)
+	}
+
+	committed := active
+	committed.Kind = snapshots.KindCommitted
+	committed.Name = name
+	committed.Updated = time.Now().UTC()
+	for _, opt := range opts {
+		if err := opt(&committed); err != nil {
+			return err
+		}
+	}
+	delete(s.snapshots, key)
+	s.snapshots[name] = committed
+	return nil
+}
+
+func (s *snapshotter) Remove(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.snapshots[key]; !ok {
+		return errors.Wrapf(cerrdefs.ErrNotFound, "snapshot %q", key)
+	}
+	for _, info := range s.snapshots {
+		if info.Parent == key {
+			return errors.Wrapf(cerrdefs.ErrFailedPrecondition, "snapshot %q has children", key)
+		}
+	}
+	delete(s.snapshots, key)
+	return nil
+}
+
+func (s *snapshotter) Walk(ctx context.Context, fn snapshots.WalkFunc, fltrs ...string) error {
+	filter, err := filters.ParseAll(fltrs...)
+	if err != nil {
+		return errors.Wrap(cerrdefs.ErrInvalidArgument, err.Error())
+	}
+
+	s.mu.RLock()
+	infos := make([]snapshots.Info, 0, len(s.snapshots))
+	for _, info := range s.snapshots {
+		if filter.Match(adaptSnapshotInfo(info)) {
+			infos = append(infos, info)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, info := range infos {
+		if err := fn(ctx, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *snapshotter) Close() error {
+	return nil
+}
+
+func placeholderMounts(key string) []mount.Mount {
+	return []mount.Mount{{
+		Type:    "bind",
+		Source:  "/fake/snapshots/" + key,
+		Options: []string{"rbind", "rw"},
+	}}
+}
+
+func adaptSnapshotInfo(info snapshots.Info) filters.Adaptor {
+	return filters.AdapterFunc(func(fieldpath []string) (string, bool) {
+		if len(fieldpath) == 0 {
+			return "", false
+		}
+		switch fieldpath[0] {
+		case "name":
+			return info.Name, len(info.Name) > 0
+		case "parent":
+			return info.Parent, len(info.Parent) > 0
+		case "kind":
+			return info.Kind.String(), true
+		case "labels":
+			if len(fieldpath) < 2 {
+				return "", false
+			}
+			v, ok := info.Labels[strings.Join(fieldpath[1:], ".")]
+			return v, ok
+		}
+		return "", false
+	})
+}
@@ -0,0 +1,26 @@
+// Package testutil provides in-memory implementations of the containerd
+// services used by the image service, so that its logic can be unit tested
+// without a running containerd daemon.
+package testutil
+
+import (
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/snapshots"
+)
+
+// NewClient returns a containerd client backed entirely by in-memory
+// services: an image store, a content store, a snapshotter registered under
+// snapshotterName, a lease manager and an introspection service. The client
+// never opens a connection, so it can be used on platforms where containerd
+// itself does not run.
+func NewClient(snapshotterName string) (*containerd.Client, error) {
+	return containerd.New("", containerd.WithServices(
+		containerd.WithImageStore(NewImageStore()),
+		containerd.WithContentStore(NewContentStore()),
+		containerd.WithSnapshotters(map[string]snapshots.Snapshotter{
+			snapshotterName: NewSnapshotter(),
+		}),
+		containerd.WithLeasesService(NewLeaseManager()),
+		containerd.WithIntrospectionService(newIntrospectionService(snapshotterName)),
+	))
+}
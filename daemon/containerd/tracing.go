@@ -0,0 +1,55 @@
+package containerd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/containerd/containerd/log"
+	"github.com/moby/buildkit/util/tracing/detect"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tracerOnce sync.Once
+	tracer     trace.Tracer
+)
+
+// imageTracer returns the tracer used to instrument image operations.
+// The tracer provider is detected once on first use; if none is configured
+// a no-op tracer is returned, so callers don't need to special-case the
+// tracing-disabled path.
+func imageTracer() trace.Tracer {
+	tracerOnce.Do(func() {
+		tp, err := detect.TracerProvider()
+		if err != nil {
+			log.G(context.TODO()).WithError(err).Warn("failed to detect trace provider for image service")
+			tracer = trace.NewNoopTracerProvider().Tracer("")
+			return
+		}
+		tracer = tp.Tracer("github.com/docker/docker/daemon/containerd")
+	})
+	return tracer
+}
+
+// startSpan starts a span for an image operation. The returned context
+// carries the span and should be passed down to containerd client calls so
+// the trace propagates over the gRPC connection.
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return imageTracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// completeSpan records err (if any) on the span and ends it. It is intended
+// to be called via defer with a pointer to the named error return of the
+// instrumented function.
+func completeSpan(span trace.Span, retErr *error) {
+	if retErr != nil && *retErr != nil {
+		span.RecordError(*retErr)
+		span.SetStatus(codes.Error, (*retErr).Error())
+	}
+	span.End()
+}
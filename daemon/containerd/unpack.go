@@ -0,0 +1,137 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/pkg/idtools"
+)
+
+// Labels in the "containerd.io/snapshot/" namespace are passed through to the
+// snapshotter when a snapshot is created during unpack.
+const (
+	// labelSnapshotVolatile asks the overlayfs snapshotter to mount extract
+	// snapshots with the volatile option, skipping sync on layer apply.
+	labelSnapshotVolatile = "containerd.io/snapshot/overlay.volatile"
+
+	// labelSnapshotUIDMapping and labelSnapshotGIDMapping carry the user
+	// namespace mapping ("containerID:hostID:size", comma-separated) for
+	// snapshotters that remap ownership with idmapped mounts.
+	labelSnapshotUIDMapping = "containerd.io/snapshot/uidmapping"
+	labelSnapshotGIDMapping = "containerd.io/snapshot/gidmapping"
+)
+
+// UnpackOptions configures snapshotter-specific behavior for unpacking image
+// layers into the snapshotter.
+type UnpackOptions struct {
+	// OverlayVolatile requests volatile overlayfs snapshots during unpack.
+	OverlayVolatile bool
+
+	// IDMappedMounts remaps layer ownership with idmapped mounts according
+	// to IDMapping, instead of chowning a copy of each layer.
+	IDMappedMounts bool
+
+	// IDMapping is the daemon's user-namespace remapping, used when
+	// IDMappedMounts is set.
+	IDMapping idtools.IdentityMapping
+
+	// SnapshotLabels are applied to every snapshot created during unpack,
+	// for snapshotter-specific tuning that has no dedicated option.
+	SnapshotLabels map[string]string
+}
+
+// snapshotLabels flattens the options into the labels handed to the
+// snapshotter when an extract snapshot is prepared.
+func (o UnpackOptions) snapshotLabels() map[string]string {
+	labels := make(map[string]string, len(o.SnapshotLabels)+3)
+	for k, v := range o.SnapshotLabels {
+		labels[k] = v
+	}
+	if o.OverlayVolatile {
+		labels[labelSnapshotVolatile] = "true"
+	}
+	if o.IDMappedMounts && !o.IDMapping.Empty() {
+		labels[labelSnapshotUIDMapping] = formatIDMap(o.IDMapping.UIDMaps)
+		labels[labelSnapshotGIDMapping] = formatIDMap(o.IDMapping.GIDMaps)
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+func formatIDMap(idMap []idtools.IDMap) string {
+	entries := make([]string, 0, len(idMap))
+	for _, m := range idMap {
+		entries = append(entries, fmt.Sprintf("%d:%d:%d", m.ContainerID, m.HostID, m.Size))
+	}
+	return strings.Join(entries, ",")
+}
+
+// pullSnapshotter returns the snapshotter a pull of ref should unpack into:
+// the one requested for the pull if any, otherwise the one configured for the
+// registry host, otherwise the daemon's default.
+func (i *ImageService) pullSnapshotter(ref reference.Named, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	if sn, ok := i.registrySnapshotter[reference.Domain(ref)]; ok {
+		return sn
+	}
+	return i.snapshotter
+}
+
+// snapshotterPlugins returns the IDs of the snapshotter plugins loaded in
+// containerd, sorted alphabetically.
+func (i *ImageService) snapshotterPlugins(ctx context.Context) []string {
+	resp, err := i.client.IntrospectionService().Plugins(ctx, []string{`type=="io.containerd.snapshotter.v1"`})
+	if err != nil {
+		log.G(ctx).WithError(err).Debug("failed to list snapshotter plugins")
+		return nil
+	}
+	var ids []string
+	for _, p := range resp.Plugins {
+		if p.InitErr == nil {
+			ids = append(ids, p.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// imageSnapshotters returns the names of the snapshotters that hold an
+// unpacked copy of the image for the host platform, sorted alphabetically.
+func (i *ImageService) imageSnapshotters(ctx context.Context, img images.Image) []string {
+	var unpacked []string
+	c8dImg := containerd.NewImage(i.client, img)
+	for _, sn := range i.snapshotterPlugins(ctx) {
+		ok, err := c8dImg.IsUnpacked(ctx, sn)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("snapshotter", sn).Debug("failed to check if image is unpacked")
+			continue
+		}
+		if ok {
+			unpacked = append(unpacked, sn)
+		}
+	}
+	return unpacked
+}
+
+// unpackOpts returns the unpack options applied to every unpack performed by
+// the image service, derived from the daemon configuration.
+func (i *ImageService) unpackOpts() []containerd.UnpackOpt {
+	if len(i.unpackSnapshotLabels) == 0 {
+		return nil
+	}
+	return []containerd.UnpackOpt{func(_ context.Context, uc *containerd.UnpackConfig) error {
+		uc.SnapshotOpts = append(uc.SnapshotOpts, snapshots.WithLabels(i.unpackSnapshotLabels))
+		return nil
+	}}
+}
@@ -0,0 +1,58 @@
+package daemon // import "github.com/docker/docker/daemon"
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/pkg/dialer"
+	"github.com/docker/docker/daemon/config"
+	"github.com/docker/go-connections/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// containerdDialTimeout is how long to wait for the initial connection to
+// containerd before giving up.
+const containerdDialTimeout = 60 * time.Second
+
+// newContainerdClient connects to the containerd instance at addr, scoped to
+// the given namespace. Local socket addresses are dialed through the
+// containerd dialer as usual. Addresses with a tcp:// scheme are dialed
+// directly, optionally with mutual TLS, so the daemon can use a containerd
+// instance running on another host or in another container.
+func newContainerdClient(addr, namespace string, tlsOpts *config.TLSOptions, baseOpts []grpc.DialOption) (*containerd.Client, error) {
+	if !strings.HasPrefix(addr, "tcp://") {
+		gopts := append([]grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(dialer.ContextDialer),
+		}, baseOpts...)
+		return containerd.New(addr, containerd.WithDefaultNamespace(namespace), containerd.WithDialOpts(gopts), containerd.WithTimeout(containerdDialTimeout))
+	}
+
+	// The containerd client assumes a local socket address and mangles
+	// anything else, so dial the connection ourselves and hand it over.
+	creds := insecure.NewCredentials()
+	if tlsOpts != nil {
+		tlsCfg, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:   tlsOpts.CAFile,
+			CertFile: tlsOpts.CertFile,
+			KeyFile:  tlsOpts.KeyFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
+	gopts := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, baseOpts...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerdDialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, strings.TrimPrefix(addr, "tcp://"), gopts...)
+	if err != nil {
+		return nil, err
+	}
+	return containerd.NewWithConn(conn, containerd.WithDefaultNamespace(namespace), containerd.WithTimeout(containerdDialTimeout))
+}
@@ -52,9 +52,28 @@ type containerFSView struct {
 	done chan error
 }
 
+// roFSMounter may be implemented by ImageService to mount a read-only view
+// of a container's filesystem without touching its writable rootfs mount.
+type roFSMounter interface {
+	MountReadOnly(ctx context.Context, c *container.Container) error
+}
+
 // openContainerFS opens a new view of the container's filesystem.
-func (daemon *Daemon) openContainerFS(container *container.Container) (_ *containerFSView, err error) {
-	if err := daemon.Mount(container); err != nil {
+func (daemon *Daemon) openContainerFS(container *container.Container) (*containerFSView, error) {
+	return daemon.openContainerFSView(container, false)
+}
+
+// openContainerFSReadOnly opens a new view of the container's filesystem for
+// operations which only read from it. When the image service can provide a
+// temporary read-only mount of the rootfs, the container is not running and
+// nothing else has to be bound into the view, the writable rootfs mount is
+// left alone; otherwise it behaves like openContainerFS.
+func (daemon *Daemon) openContainerFSReadOnly(container *container.Container) (*containerFSView, error) {
+	return daemon.openContainerFSView(container, true)
+}
+
+func (daemon *Daemon) openContainerFSView(container *container.Container, readOnly bool) (_ *containerFSView, err error) {
+	if err := daemon.mountContainerFS(container, readOnly); err != nil {
 		return nil, err
 	}
 	defer func() {
@@ -171,6 +190,28 @@ func (daemon *Daemon) openContainerFS(container *container.Container) (_ *contai
 	return vw, nil
 }
 
+// mountContainerFS mounts the container rootfs and sets its BaseFS. The
+// read-only fast path is only taken when nothing would have to be created
+// inside the rootfs for the view: volumes, tmpfs, secrets and configs all
+// bind-mount over directories that may be missing from a stopped container.
+// When the read-only mount fails the regular writable mount is used, so a
+// copy never fails only because the fast path is unavailable.
+func (daemon *Daemon) mountContainerFS(container *container.Container, readOnly bool) error {
+	if readOnly && !container.IsRunning() &&
+		len(container.MountPoints) == 0 && len(container.HostConfig.Tmpfs) == 0 &&
+		len(container.SecretReferences) == 0 && len(container.ConfigReferences) == 0 {
+		if ro, ok := daemon.imageService.(roFSMounter); ok {
+			err := ro.MountReadOnly(context.TODO(), container)
+			if err == nil {
+				return nil
+			}
+			log.G(context.TODO()).WithError(err).WithField("container", container.ID).
+				Debug("failed to mount container read-only, falling back to the writable mount")
+		}
+	}
+	return daemon.Mount(container)
+}
+
 // RunInFS synchronously runs fn in the context of the container filesytem and
 // passes through its return value.
 //
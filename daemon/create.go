@@ -78,20 +78,31 @@ func (daemon *Daemon) containerCreate(ctx context.Context, daemonCfg *configStor
 		return containertypes.CreateResponse{Warnings: warnings}, errdefs.InvalidParameter(err)
 	}
 
-	if opts.params.Platform == nil && opts.params.Config.Image != "" {
+	if opts.params.Config.Image != "" {
 		img, err := daemon.imageService.GetImage(ctx, opts.params.Config.Image, imagetypes.GetImageOpts{Platform: opts.params.Platform})
 		if err != nil {
-			return containertypes.CreateResponse{}, err
+			// The image may legitimately be absent when the pull policy
+			// allows pulling it during create; it is then fetched (and the
+			// platform check revisited) when the container is created below.
+			if !errdefs.IsNotFound(err) || !pullsOnCreate(opts.params.Pull) {
+				return containertypes.CreateResponse{}, err
+			}
 		}
 		if img != nil {
-			p := maximumSpec()
 			imgPlat := ocispec.Platform{
 				OS:           img.OS,
 				Architecture: img.Architecture,
 				Variant:      img.Variant,
 			}
 
-			if !images.OnlyPlatformWithFallback(p).Match(imgPlat) {
+			if p := opts.params.Platform; p != nil {
+				// The image store may not hold the requested platform and
+				// hand back the closest image it has; surface the mismatch
+				// instead of silently running the wrong platform.
+				if !images.OnlyPlatformWithFallback(*p).Match(imgPlat) {
+					warnings = append(warnings, fmt.Sprintf("The requested image's platform (%s) does not match the requested platform (%s)", platforms.Format(imgPlat), platforms.Format(*p)))
+				}
+			} else if p := maximumSpec(); !images.OnlyPlatformWithFallback(p).Match(imgPlat) {
 				warnings = append(warnings, fmt.Sprintf("The requested image's platform (%s) does not match the detected host platform (%s) and no specific platform was requested", platforms.Format(imgPlat), platforms.Format(p)))
 			}
 		}
@@ -135,7 +146,7 @@ func (daemon *Daemon) create(ctx context.Context, daemonCfg *config.Config, opts
 	)
 
 	if opts.params.Config.Image != "" {
-		img, err = daemon.imageService.GetImage(ctx, opts.params.Config.Image, imagetypes.GetImageOpts{Platform: opts.params.Platform})
+		img, err = daemon.imageService.EnsureImage(ctx, opts.params.Config.Image, opts.params.Pull, opts.params.Platform, opts.params.AuthConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -355,6 +366,12 @@ func verifyNetworkingConfig(nwConfig *networktypes.NetworkingConfig) error {
 	return nil
 }
 
+// pullsOnCreate reports whether the given pull policy may pull the image as
+// part of creating the container.
+func pullsOnCreate(policy containertypes.PullPolicy) bool {
+	return policy == containertypes.PullPolicyMissing || policy == containertypes.PullPolicyAlways
+}
+
 // maximumSpec returns the distribution platform with maximum compatibility for the current node.
 func maximumSpec() ocispec.Platform {
 	p := platforms.DefaultSpec()
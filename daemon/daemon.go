@@ -22,7 +22,6 @@ import (
 	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/defaults"
 	"github.com/containerd/containerd/log"
-	"github.com/containerd/containerd/pkg/dialer"
 	"github.com/containerd/containerd/pkg/userns"
 	"github.com/containerd/containerd/remotes/docker"
 	dist "github.com/docker/distribution"
@@ -36,19 +35,15 @@ import (
 	"github.com/docker/docker/container"
 	executorpkg "github.com/docker/docker/daemon/cluster/executor"
 	"github.com/docker/docker/daemon/config"
-	ctrd "github.com/docker/docker/daemon/containerd"
 	"github.com/docker/docker/daemon/events"
 	_ "github.com/docker/docker/daemon/graphdriver/register" // register graph drivers
 	"github.com/docker/docker/daemon/images"
 	dlogger "github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/daemon/network"
-	"github.com/docker/docker/daemon/snapshotter"
 	"github.com/docker/docker/daemon/stats"
 	"github.com/docker/docker/distribution"
-	dmetadata "github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/errdefs"
-	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
 	libcontainerdtypes "github.com/docker/docker/libcontainerd/types"
 	"github.com/docker/docker/libnetwork"
@@ -57,6 +52,8 @@ import (
 	"github.com/docker/docker/pkg/authorization"
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/imagepolicy"
+	"github.com/docker/docker/pkg/imagescan"
 	"github.com/docker/docker/pkg/plugingetter"
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/system"
@@ -68,13 +65,15 @@ import (
 	volumesservice "github.com/docker/docker/volume/service"
 	"github.com/moby/buildkit/util/resolver"
 	resolverconfig "github.com/moby/buildkit/util/resolver/config"
+	"github.com/moby/buildkit/util/tracing/detect"
 	"github.com/moby/locker"
 	"github.com/pkg/errors"
 	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/propagation"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
-	"google.golang.org/grpc/credentials/insecure"
 	"resenje.org/singleflight"
 )
 
@@ -140,6 +139,10 @@ type Daemon struct {
 	mdDB *bbolt.DB
 
 	usesSnapshotter bool
+
+	// imagePreloader tracks the startup preloading of the images listed in
+	// the preload-images configuration; nil when none are configured.
+	imagePreloader *imagePreloader
 }
 
 // ID returns the daemon id
@@ -213,6 +216,14 @@ func (daemon *Daemon) RegistryHosts(host string) ([]docker.RegistryHost, error)
 		m[host] = c
 	}
 
+	// Client certificates configured in the daemon configuration are applied
+	// in addition to any key pairs picked up from certs.d below.
+	for k, cert := range daemon.registryService.ClientCertificates() {
+		c := m[k]
+		c.KeyPairs = append(c.KeyPairs, resolverconfig.TLSKeyPair{Key: cert.KeyFile, Certificate: cert.CertFile})
+		m[k] = c
+	}
+
 	for k, v := range m {
 		v.TLSConfigDir = []string{registry.HostCertsDir(k)}
 		m[k] = v
@@ -237,6 +248,12 @@ type layerAccessor interface {
 	GetLayerByID(cid string) (layer.RWLayer, error)
 }
 
+// snapshotRestorer may be implemented by ImageService to re-attach loaded
+// containers to their rootfs snapshots on daemon restart.
+type snapshotRestorer interface {
+	RestoreContainer(ctx context.Context, c *container.Container) error
+}
+
 func (daemon *Daemon) restore(cfg *configStore) error {
 	var mapLock sync.Mutex
 	containers := make(map[string]*container.Container)
@@ -273,7 +290,15 @@ func (daemon *Daemon) restore(cfg *configStore) error {
 				logger.WithError(err).Error("failed to load container")
 				return
 			}
-			if c.Driver != daemon.imageService.StorageDriver() {
+			if restorer, ok := daemon.imageService.(snapshotRestorer); ok {
+				// Snapshotter-backed containers record which snapshotter
+				// holds their rootfs snapshot; re-attach to it instead of
+				// requiring the daemon's current default to match.
+				if err := restorer.RestoreContainer(context.TODO(), c); err != nil {
+					logger.WithError(err).Error("failed to restore container snapshot")
+					return
+				}
+			} else if c.Driver != daemon.imageService.StorageDriver() {
 				// Ignore the container if it wasn't created with the current storage-driver
 				logger.Debugf("not restoring container because it was created with another storage driver (%s)", c.Driver)
 				return
@@ -834,6 +859,8 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 	// TEST_INTEGRATION_USE_SNAPSHOTTER is used for integration tests only.
 	if os.Getenv("TEST_INTEGRATION_USE_SNAPSHOTTER") != "" {
 		d.usesSnapshotter = true
+	} else if config.ImageStoreBackend != "" {
+		d.usesSnapshotter = config.ImageStoreBackend == imageBackendContainerd
 	} else {
 		d.usesSnapshotter = config.Features["containerd-snapshotter"]
 	}
@@ -897,6 +924,8 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 
 	d.registryService = registryService
 	dlogger.RegisterPluginGetter(d.PluginStore)
+	imagepolicy.SetPluginGetter(d.PluginStore)
+	imagescan.SetPluginGetter(d.PluginStore)
 
 	metricsSockPath, err := d.listenMetricsSock(&cfgStore.Config)
 	if err != nil {
@@ -928,17 +957,28 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		// It is not harm to add WithBlock for containerd connection.
 		grpc.WithBlock(),
 
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithConnectParams(connParams),
-		grpc.WithContextDialer(dialer.ContextDialer),
 
 		// TODO(stevvooe): We may need to allow configuration of this on the client.
 		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(defaults.DefaultMaxRecvMsgSize)),
 		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(defaults.DefaultMaxSendMsgSize)),
 	}
 
+	if tp, err := detect.TracerProvider(); err == nil && tp != nil {
+		// Propagate trace contexts from API requests into the containerd
+		// gRPC calls made on their behalf, so image operations can be
+		// traced end-to-end through containerd.
+		propagators := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+		gopts = append(gopts,
+			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor(otelgrpc.WithTracerProvider(tp), otelgrpc.WithPropagators(propagators))),
+			grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor(otelgrpc.WithTracerProvider(tp), otelgrpc.WithPropagators(propagators))),
+		)
+	} else if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to detect trace provider for containerd client")
+	}
+
 	if cfgStore.ContainerdAddr != "" {
-		d.containerdClient, err = containerd.New(cfgStore.ContainerdAddr, containerd.WithDefaultNamespace(cfgStore.ContainerdNamespace), containerd.WithDialOpts(gopts), containerd.WithTimeout(60*time.Second))
+		d.containerdClient, err = newContainerdClient(cfgStore.ContainerdAddr, cfgStore.ContainerdNamespace, cfgStore.ContainerdTLS, gopts)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to dial %q", cfgStore.ContainerdAddr)
 		}
@@ -948,7 +988,7 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		var pluginCli *containerd.Client
 
 		if cfgStore.ContainerdAddr != "" {
-			pluginCli, err = containerd.New(cfgStore.ContainerdAddr, containerd.WithDefaultNamespace(cfgStore.ContainerdPluginNamespace), containerd.WithDialOpts(gopts), containerd.WithTimeout(60*time.Second))
+			pluginCli, err = newContainerdClient(cfgStore.ContainerdAddr, cfgStore.ContainerdPluginNamespace, cfgStore.ContainerdTLS, gopts)
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed to dial %q", cfgStore.ContainerdAddr)
 			}
@@ -1036,123 +1076,28 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		driverName = cfgStore.GraphDriver
 	}
 
-	if d.UsesSnapshotter() {
-		if os.Getenv("TEST_INTEGRATION_USE_SNAPSHOTTER") != "" {
-			log.G(ctx).Warn("Enabling containerd snapshotter through the $TEST_INTEGRATION_USE_SNAPSHOTTER environment variable. This should only be used for testing.")
-		}
-		log.G(ctx).Info("Starting daemon with containerd snapshotter integration enabled")
-
-		// FIXME(thaJeztah): implement automatic snapshotter-selection similar to graph-driver selection; see https://github.com/moby/moby/issues/44076
-		if driverName == "" {
-			driverName = containerd.DefaultSnapshotter
-		}
-
-		// Configure and validate the kernels security support. Note this is a Linux/FreeBSD
-		// operation only, so it is safe to pass *just* the runtime OS graphdriver.
-		if err := configureKernelSecuritySupport(&cfgStore.Config, driverName); err != nil {
-			return nil, err
-		}
-		d.imageService = ctrd.NewService(ctrd.ImageServiceConfig{
-			Client:          d.containerdClient,
-			Containers:      d.containers,
-			Snapshotter:     driverName,
-			RegistryHosts:   d.RegistryHosts,
-			Registry:        d.registryService,
-			EventsService:   d.EventsService,
-			RefCountMounter: snapshotter.NewMounter(config.Root, driverName, idMapping),
-		})
-	} else {
-		layerStore, err := layer.NewStoreFromOptions(layer.StoreOptions{
-			Root:                      cfgStore.Root,
-			MetadataStorePathTemplate: filepath.Join(cfgStore.Root, "image", "%s", "layerdb"),
-			GraphDriver:               driverName,
-			GraphDriverOptions:        cfgStore.GraphOptions,
-			IDMapping:                 idMapping,
-			PluginGetter:              d.PluginStore,
-			ExperimentalEnabled:       cfgStore.Experimental,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		// Configure and validate the kernels security support. Note this is a Linux/FreeBSD
-		// operation only, so it is safe to pass *just* the runtime OS graphdriver.
-		if err := configureKernelSecuritySupport(&cfgStore.Config, layerStore.DriverName()); err != nil {
-			return nil, err
-		}
-
-		imageRoot := filepath.Join(cfgStore.Root, "image", layerStore.DriverName())
-		ifs, err := image.NewFSStoreBackend(filepath.Join(imageRoot, "imagedb"))
-		if err != nil {
-			return nil, err
-		}
-
-		// We have a single tag/reference store for the daemon globally. However, it's
-		// stored under the graphdriver. On host platforms which only support a single
-		// container OS, but multiple selectable graphdrivers, this means depending on which
-		// graphdriver is chosen, the global reference store is under there. For
-		// platforms which support multiple container operating systems, this is slightly
-		// more problematic as where does the global ref store get located? Fortunately,
-		// for Windows, which is currently the only daemon supporting multiple container
-		// operating systems, the list of graphdrivers available isn't user configurable.
-		// For backwards compatibility, we just put it under the windowsfilter
-		// directory regardless.
-		refStoreLocation := filepath.Join(imageRoot, `repositories.json`)
-		rs, err := refstore.NewReferenceStore(refStoreLocation)
-		if err != nil {
-			return nil, fmt.Errorf("Couldn't create reference store repository: %s", err)
-		}
-		d.ReferenceStore = rs
-
-		imageStore, err := image.NewImageStore(ifs, layerStore)
-		if err != nil {
-			return nil, err
-		}
-
-		distributionMetadataStore, err := dmetadata.NewFSMetadataStore(filepath.Join(imageRoot, "distribution"))
-		if err != nil {
-			return nil, err
-		}
-
-		imgSvcConfig := images.ImageServiceConfig{
-			ContainerStore:            d.containers,
-			DistributionMetadataStore: distributionMetadataStore,
-			EventsService:             d.EventsService,
-			ImageStore:                imageStore,
-			LayerStore:                layerStore,
-			MaxConcurrentDownloads:    config.MaxConcurrentDownloads,
-			MaxConcurrentUploads:      config.MaxConcurrentUploads,
-			MaxDownloadAttempts:       config.MaxDownloadAttempts,
-			ReferenceStore:            rs,
-			RegistryService:           registryService,
-			ContentNamespace:          config.ContainerdNamespace,
-		}
-
-		// containerd is not currently supported with Windows.
-		// So sometimes d.containerdCli will be nil
-		// In that case we'll create a local content store... but otherwise we'll use containerd
-		if d.containerdClient != nil {
-			imgSvcConfig.Leases = d.containerdClient.LeasesService()
-			imgSvcConfig.ContentStore = d.containerdClient.ContentStore()
+	backendName := cfgStore.ImageStoreBackend
+	if backendName == "" {
+		if d.UsesSnapshotter() {
+			backendName = imageBackendContainerd
 		} else {
-			imgSvcConfig.ContentStore, imgSvcConfig.Leases, err = d.configureLocalContentStore(config.ContainerdNamespace)
-			if err != nil {
-				return nil, err
-			}
+			backendName = imageBackendGraphDriver
 		}
-
-		// TODO: imageStore, distributionMetadataStore, and ReferenceStore are only
-		// used above to run migration. They could be initialized in ImageService
-		// if migration is called from daemon/images. layerStore might move as well.
-		d.imageService = images.NewImageService(imgSvcConfig)
-
-		log.G(ctx).Debugf("Max Concurrent Downloads: %d", imgSvcConfig.MaxConcurrentDownloads)
-		log.G(ctx).Debugf("Max Concurrent Uploads: %d", imgSvcConfig.MaxConcurrentUploads)
-		log.G(ctx).Debugf("Max Download Attempts: %d", imgSvcConfig.MaxDownloadAttempts)
+	}
+	backendInit, err := getImageBackend(backendName)
+	if err != nil {
+		return nil, err
+	}
+	if err := backendInit(ctx, d, &cfgStore.Config, driverName); err != nil {
+		return nil, err
 	}
 
 	go d.execCommandGC()
 
+	d.startImageGC(&cfgStore.Config)
+
+	d.startImagePreload(&cfgStore.Config)
+
 	if err := d.initLibcontainerd(ctx, &cfgStore.Config); err != nil {
 		return nil, err
 	}
@@ -1162,7 +1107,14 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 	}
 	close(d.startupDone)
 
-	info := d.SystemInfo()
+	// Clean up snapshots and ingests leaked by previous daemon crashes.
+	// This must run after containers are restored so that their snapshots
+	// are known to be in use.
+	if is, ok := d.imageService.(interface{ ReconcileStorage(context.Context) }); ok {
+		go is.ReconcileStorage(context.Background())
+	}
+
+	info := d.SystemInfo(ctx)
 	for _, w := range info.Warnings {
 		log.G(ctx).Warn(w)
 	}
@@ -0,0 +1,264 @@
+package daemon // import "github.com/docker/docker/daemon"
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/daemon/config"
+	ctrd "github.com/docker/docker/daemon/containerd"
+	"github.com/docker/docker/daemon/images"
+	"github.com/docker/docker/daemon/snapshotter"
+	dmetadata "github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/imagepolicy"
+	"github.com/docker/docker/pkg/imagescan"
+	refstore "github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+	units "github.com/docker/go-units"
+	"github.com/pkg/errors"
+)
+
+// Names of the built-in image-store backends.
+const (
+	imageBackendGraphDriver = "graphdriver"
+	imageBackendContainerd  = "containerd"
+)
+
+// ImageBackendFactory initializes the image service of one image-store
+// backend. It is called during daemon startup, after the registry service,
+// containerd client, identity mapping and container store have been set up,
+// and must set d.imageService before returning. driverName is the resolved
+// graph driver or snapshotter name.
+type ImageBackendFactory func(ctx context.Context, d *Daemon, cfg *config.Config, driverName string) error
+
+var imageBackends = map[string]ImageBackendFactory{
+	imageBackendGraphDriver: initGraphDriverImageBackend,
+	imageBackendContainerd:  initContainerdImageBackend,
+}
+
+// RegisterImageBackend makes an image-store backend selectable through the
+// "image-store-backend" daemon configuration option. It is meant to be
+// called from an init function, before the daemon is created.
+func RegisterImageBackend(name string, factory ImageBackendFactory) error {
+	if _, exists := imageBackends[name]; exists {
+		return errors.Errorf("image backend already registered %s", name)
+	}
+	imageBackends[name] = factory
+	return nil
+}
+
+// getImageBackend returns the registered factory for the given backend name.
+func getImageBackend(name string) (ImageBackendFactory, error) {
+	factory, exists := imageBackends[name]
+	if !exists {
+		return nil, errors.Errorf("unknown image store backend %q", name)
+	}
+	return factory, nil
+}
+
+// initContainerdImageBackend wires up the containerd-backed image service
+// (daemon/containerd).
+func initContainerdImageBackend(ctx context.Context, d *Daemon, cfg *config.Config, driverName string) error {
+	if os.Getenv("TEST_INTEGRATION_USE_SNAPSHOTTER") != "" {
+		log.G(ctx).Warn("Enabling containerd snapshotter through the $TEST_INTEGRATION_USE_SNAPSHOTTER environment variable. This should only be used for testing.")
+	}
+	log.G(ctx).Info("Starting daemon with containerd snapshotter integration enabled")
+
+	// FIXME(thaJeztah): implement automatic snapshotter-selection similar to graph-driver selection; see https://github.com/moby/moby/issues/44076
+	if driverName == "" {
+		driverName = containerd.DefaultSnapshotter
+	}
+
+	// Configure and validate the kernels security support. Note this is a Linux/FreeBSD
+	// operation only, so it is safe to pass *just* the runtime OS graphdriver.
+	if err := configureKernelSecuritySupport(cfg, driverName); err != nil {
+		return err
+	}
+	// Pull-size limits were validated when the configuration was loaded.
+	var maxPullSize int64
+	if cfg.MaxImagePullSize != "" {
+		maxPullSize, _ = units.RAMInBytes(cfg.MaxImagePullSize)
+	}
+	registryMaxPullSize := make(map[string]int64, len(cfg.RegistryMaxPullSize))
+	for host, size := range cfg.RegistryMaxPullSize {
+		registryMaxPullSize[host], _ = units.RAMInBytes(size)
+	}
+	var pushChunkSize int64
+	if cfg.PushChunkSize != "" {
+		pushChunkSize, _ = units.RAMInBytes(cfg.PushChunkSize)
+	}
+	var pushSigningKey crypto.Signer
+	if cfg.PushSigningKey != "" {
+		var err error
+		pushSigningKey, err = ctrd.LoadPushSigningKey(cfg.PushSigningKey)
+		if err != nil {
+			return errors.Wrap(err, "failed to load push-signing-key")
+		}
+	}
+	var danglingPruneAge time.Duration
+	if cfg.DanglingImagesPruneAge != "" {
+		danglingPruneAge, _ = time.ParseDuration(cfg.DanglingImagesPruneAge)
+	}
+	var scanPlugin imagescan.Plugin
+	if cfg.ImageScanPlugin != "" {
+		scanPlugin = imagescan.NewPlugin(cfg.ImageScanPlugin)
+	}
+	var maxImageAge time.Duration
+	if cfg.MaxImageAge != "" {
+		maxImageAge, _ = time.ParseDuration(cfg.MaxImageAge)
+	}
+
+	imgSvc := ctrd.NewService(ctrd.ImageServiceConfig{
+		Client:                   d.containerdClient,
+		Containers:               d.containers,
+		Snapshotter:              driverName,
+		RegistryHosts:            d.RegistryHosts,
+		Registry:                 d.registryService,
+		EventsService:            d.EventsService,
+		RefCountMounter:          snapshotter.NewMounter(cfg.Root, driverName, d.idMapping),
+		MaxPullSize:              maxPullSize,
+		RegistryMaxPullSize:      registryMaxPullSize,
+		RegistrySnapshotter:      cfg.RegistrySnapshotter,
+		DistributionAccelerators: cfg.DistributionAccelerators,
+		ImagePolicyPlugins:       imagepolicy.NewPlugins(cfg.ImagePolicyPlugins),
+		ImageScanPlugin:          scanPlugin,
+		ScanOnPull:               cfg.ScanOnPull,
+		MaxConcurrentUnpacks:     cfg.MaxConcurrentUnpacks,
+		MaxDownloadAttempts:      cfg.MaxDownloadAttempts,
+		PushChunkSize:            pushChunkSize,
+		PushSigningKey:           pushSigningKey,
+		UnpackOptions: ctrd.UnpackOptions{
+			OverlayVolatile: cfg.SnapshotterOpts.OverlayVolatile,
+			IDMappedMounts:  cfg.SnapshotterOpts.IDMappedMounts,
+			IDMapping:       d.idMapping,
+			SnapshotLabels:  cfg.SnapshotterOpts.Labels,
+		},
+		ProtectedTags:      cfg.ProtectedTags,
+		DanglingPruneAge:   danglingPruneAge,
+		MaxImageAge:        maxImageAge,
+		MaxImageAgeRefuse:  cfg.MaxImageAgeAction == "refuse",
+		CredentialHelpers:  registry.NewCredentialHelpers(cfg.RegistryCredentialHelpers),
+		SharedContentStore: cfg.SharedContentStore,
+	})
+	d.imageService = imgSvc
+
+	if cfg.RegistryMirrorAddr != "" {
+		if err := imgSvc.ServeRegistry(cfg.RegistryMirrorAddr); err != nil {
+			return errors.Wrap(err, "failed to serve the local registry mirror")
+		}
+	}
+	return nil
+}
+
+// initGraphDriverImageBackend wires up the graph driver based image service
+// (daemon/images).
+func initGraphDriverImageBackend(ctx context.Context, d *Daemon, cfg *config.Config, driverName string) error {
+	if cfg.RegistryMirrorAddr != "" {
+		log.G(ctx).Warn("registry-mirror-addr requires the containerd image store; not serving the registry API")
+	}
+	if cfg.DanglingImagesPruneAge != "" {
+		log.G(ctx).Warn("dangling-images-prune-age requires the containerd image store; dangling images will not be pruned automatically")
+	}
+	if cfg.MaxImageAge != "" {
+		log.G(ctx).Warn("max-image-age requires the containerd image store; image freshness will not be checked")
+	}
+	if cfg.SharedContentStore != "" {
+		log.G(ctx).Warn("shared-content-store requires the containerd image store; pulls will not reuse shared blobs")
+	}
+	layerStore, err := layer.NewStoreFromOptions(layer.StoreOptions{
+		Root:                      cfg.Root,
+		MetadataStorePathTemplate: filepath.Join(cfg.Root, "image", "%s", "layerdb"),
+		GraphDriver:               driverName,
+		GraphDriverOptions:        cfg.GraphOptions,
+		IDMapping:                 d.idMapping,
+		PluginGetter:              d.PluginStore,
+		ExperimentalEnabled:       cfg.Experimental,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Configure and validate the kernels security support. Note this is a Linux/FreeBSD
+	// operation only, so it is safe to pass *just* the runtime OS graphdriver.
+	if err := configureKernelSecuritySupport(cfg, layerStore.DriverName()); err != nil {
+		return err
+	}
+
+	imageRoot := filepath.Join(cfg.Root, "image", layerStore.DriverName())
+	ifs, err := image.NewFSStoreBackend(filepath.Join(imageRoot, "imagedb"))
+	if err != nil {
+		return err
+	}
+
+	// We have a single tag/reference store for the daemon globally. However, it's
+	// stored under the graphdriver. On host platforms which only support a single
+	// container OS, but multiple selectable graphdrivers, this means depending on which
+	// graphdriver is chosen, the global reference store is under there. For
+	// platforms which support multiple container operating systems, this is slightly
+	// more problematic as where does the global ref store get located? Fortunately,
+	// for Windows, which is currently the only daemon supporting multiple container
+	// operating systems, the list of graphdrivers available isn't user configurable.
+	// For backwards compatibility, we just put it under the windowsfilter
+	// directory regardless.
+	refStoreLocation := filepath.Join(imageRoot, `repositories.json`)
+	rs, err := refstore.NewReferenceStore(refStoreLocation)
+	if err != nil {
+		return fmt.Errorf("Couldn't create reference store repository: %s", err)
+	}
+	d.ReferenceStore = rs
+
+	imageStore, err := image.NewImageStore(ifs, layerStore)
+	if err != nil {
+		return err
+	}
+
+	distributionMetadataStore, err := dmetadata.NewFSMetadataStore(filepath.Join(imageRoot, "distribution"))
+	if err != nil {
+		return err
+	}
+
+	imgSvcConfig := images.ImageServiceConfig{
+		ContainerStore:            d.containers,
+		DistributionMetadataStore: distributionMetadataStore,
+		EventsService:             d.EventsService,
+		ImageStore:                imageStore,
+		LayerStore:                layerStore,
+		MaxConcurrentDownloads:    cfg.MaxConcurrentDownloads,
+		MaxConcurrentUploads:      cfg.MaxConcurrentUploads,
+		MaxDownloadAttempts:       cfg.MaxDownloadAttempts,
+		ReferenceStore:            rs,
+		RegistryService:           d.registryService,
+		ContentNamespace:          cfg.ContainerdNamespace,
+		ProtectedTags:             cfg.ProtectedTags,
+	}
+
+	// containerd is not currently supported with Windows.
+	// So sometimes d.containerdCli will be nil
+	// In that case we'll create a local content store... but otherwise we'll use containerd
+	if d.containerdClient != nil {
+		imgSvcConfig.Leases = d.containerdClient.LeasesService()
+		imgSvcConfig.ContentStore = d.containerdClient.ContentStore()
+	} else {
+		imgSvcConfig.ContentStore, imgSvcConfig.Leases, err = d.configureLocalContentStore(cfg.ContainerdNamespace)
+		if err != nil {
+			return err
+		}
+	}
+
+	// TODO: imageStore, distributionMetadataStore, and ReferenceStore are only
+	// used above to run migration. They could be initialized in ImageService
+	// if migration is called from daemon/images. layerStore might move as well.
+	d.imageService = images.NewImageService(imgSvcConfig)
+
+	log.G(ctx).Debugf("Max Concurrent Downloads: %d", imgSvcConfig.MaxConcurrentDownloads)
+	log.G(ctx).Debugf("Max Concurrent Uploads: %d", imgSvcConfig.MaxConcurrentUploads)
+	log.G(ctx).Debugf("Max Download Attempts: %d", imgSvcConfig.MaxDownloadAttempts)
+	return nil
+}
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/daemon/config"
+	units "github.com/docker/go-units"
+)
+
+// defaultImageGCInterval is how often the image garbage collector runs when
+// no interval is configured.
+const defaultImageGCInterval = time.Hour
+
+// startImageGC launches the periodic image garbage collector when enabled in
+// the daemon configuration. Mirroring the builder's GC policies, each run
+// prunes unused images matching the configured filters, optionally only while
+// the layer disk usage exceeds the configured threshold.
+func (daemon *Daemon) startImageGC(cfg *config.Config) {
+	if !cfg.ImageGC.Enabled {
+		return
+	}
+	if !daemon.UsesSnapshotter() {
+		log.G(context.TODO()).Warn("image-gc is only supported with the containerd image store enabled; automatic image garbage collection is disabled")
+		return
+	}
+
+	interval := defaultImageGCInterval
+	if cfg.ImageGC.Interval != "" {
+		// Validated when the configuration was loaded.
+		interval, _ = time.ParseDuration(cfg.ImageGC.Interval)
+	}
+	var threshold, keepStorage int64
+	if cfg.ImageGC.DiskUsageThreshold != "" {
+		threshold, _ = units.RAMInBytes(cfg.ImageGC.DiskUsageThreshold)
+	}
+	if cfg.ImageGC.KeepStorage != "" {
+		keepStorage, _ = units.RAMInBytes(cfg.ImageGC.KeepStorage)
+	}
+
+	go daemon.imageGCLoop(interval, threshold, keepStorage, cfg.ImageGC.Filters)
+}
+
+// imageGCFilters builds the prune filters for a collection run. Prune
+// mutates the filter arguments it is given, so a fresh copy is built for
+// every run.
+func imageGCFilters(configured []string) filters.Args {
+	fltrs := filters.NewArgs()
+	for _, f := range configured {
+		name, value, _ := strings.Cut(f, "=")
+		fltrs.Add(name, value)
+	}
+	if !fltrs.Contains("dangling") {
+		// Only collect dangling images unless explicitly configured
+		// otherwise, matching the default of "docker image prune".
+		fltrs.Add("dangling", "true")
+	}
+	return fltrs
+}
+
+func (daemon *Daemon) imageGCLoop(interval time.Duration, threshold, keepStorage int64, configuredFilters []string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if daemon.IsShuttingDown() {
+			return
+		}
+		ctx := context.Background()
+
+		if threshold > 0 {
+			usage, err := daemon.imageService.LayerDiskUsage(ctx)
+			if err != nil {
+				log.G(ctx).WithError(err).Warn("image-gc: failed to determine layer disk usage")
+				continue
+			}
+			if usage < threshold {
+				log.G(ctx).WithFields(log.Fields{
+					"usage":     usage,
+					"threshold": threshold,
+				}).Debug("image-gc: disk usage below threshold, skipping collection")
+				continue
+			}
+		}
+
+		report, err := daemon.imageService.ImagesPrune(ctx, imageGCFilters(configuredFilters), nil)
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("image-gc: collection failed")
+			continue
+		}
+		if len(report.ImagesDeleted) > 0 {
+			log.G(ctx).WithFields(log.Fields{
+				"deleted":        len(report.ImagesDeleted),
+				"reclaimedBytes": report.SpaceReclaimed,
+			}).Info("image-gc: collection finished")
+		}
+
+		if threshold > 0 && keepStorage > 0 {
+			daemon.evictImages(ctx, keepStorage)
+		}
+	}
+}
+
+// evictImages removes unreferenced images, oldest first, until the layer disk
+// usage falls below the configured keep-storage target. Images used by a
+// container (running or stopped) and images that could not be deleted without
+// force are left alone; deletion events are emitted by the image service as
+// for any other removal.
+func (daemon *Daemon) evictImages(ctx context.Context, keepStorage int64) {
+	usage, err := daemon.imageService.LayerDiskUsage(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("image-gc: failed to determine layer disk usage")
+		return
+	}
+	if usage <= keepStorage {
+		return
+	}
+
+	images, err := daemon.imageService.Images(ctx, types.ImageListOptions{All: true})
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("image-gc: failed to list images for eviction")
+		return
+	}
+
+	inUse := make(map[string]struct{})
+	for _, ctr := range daemon.List() {
+		inUse[ctr.ImageID.String()] = struct{}{}
+	}
+
+	// Evict the least recently created images first, as an approximation
+	// of least recently used.
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Created < images[j].Created
+	})
+
+	for _, img := range images {
+		if usage <= keepStorage {
+			break
+		}
+		if _, used := inUse[img.ID]; used {
+			continue
+		}
+		if _, err := daemon.imageService.ImageDelete(ctx, img.ID, false, true); err != nil {
+			log.G(ctx).WithError(err).WithField("image", img.ID).Debug("image-gc: cannot evict image")
+			continue
+		}
+		log.G(ctx).WithField("image", img.ID).Info("image-gc: evicted image")
+		if updated, err := daemon.imageService.LayerDiskUsage(ctx); err == nil {
+			usage = updated
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	containertype "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	imagetype "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
@@ -26,23 +27,41 @@ import (
 type ImageService interface {
 	// Images
 
-	PullImage(ctx context.Context, name, tag string, platform *ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
-	PushImage(ctx context.Context, ref reference.Named, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
+	PullImage(ctx context.Context, name, tag string, options imagetype.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
+	PullImageBundle(ctx context.Context, refs []string, options imagetype.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error
+	PushImage(ctx context.Context, ref reference.Named, platforms []ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer, withNondistributable bool) error
 	CreateImage(ctx context.Context, config []byte, parent string, contentStoreDigest digest.Digest) (builder.Image, error)
 	ImageDelete(ctx context.Context, imageRef string, force, prune bool) ([]types.ImageDeleteResponseItem, error)
-	ExportImage(ctx context.Context, names []string, outStream io.Writer) error
+	ExportImage(ctx context.Context, names []string, outStream io.Writer, options imagetype.SaveOptions) error
 	PerformWithBaseFS(ctx context.Context, c *container.Container, fn func(string) error) error
-	LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, quiet bool) error
+	LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, options imagetype.LoadOptions) error
 	Images(ctx context.Context, opts types.ImageListOptions) ([]*types.ImageSummary, error)
 	LogImageEvent(imageID, refName, action string)
-	CountImages() int
-	ImagesPrune(ctx context.Context, pruneFilters filters.Args) (*types.ImagesPruneReport, error)
+	CountImages(ctx context.Context) int
+	ImagesPrune(ctx context.Context, pruneFilters filters.Args, outStream io.Writer) (*types.ImagesPruneReport, error)
 	ImportImage(ctx context.Context, ref reference.Named, platform *ocispec.Platform, msg string, layerReader io.Reader, changes []string) (image.ID, error)
 	TagImage(ctx context.Context, imageID image.ID, newTag reference.Named) error
 	GetImage(ctx context.Context, refOrID string, options imagetype.GetImageOpts) (*image.Image, error)
-	ImageHistory(ctx context.Context, name string) ([]*imagetype.HistoryResponseItem, error)
+	EnsureImage(ctx context.Context, refOrID string, policy containertype.PullPolicy, platform *ocispec.Platform, authConfig *registry.AuthConfig) (*image.Image, error)
+	ImageHistory(ctx context.Context, name string, platform *ocispec.Platform) ([]*imagetype.HistoryResponseItem, error)
+	ImageTags(ctx context.Context, refOrID string) ([]string, error)
+	ExportReferences(ctx context.Context) (map[string]string, error)
+	ImportReferences(ctx context.Context, refs map[string]string) (*imagetype.ReferencesRestoreReport, error)
+	UpdateImageLabels(ctx context.Context, refOrID string, update imagetype.LabelsUpdate) (image.ID, error)
 	CommitImage(ctx context.Context, c backend.CommitConfig) (image.ID, error)
-	SquashImage(id, parent string) (string, error)
+	SquashImage(ctx context.Context, id, parent string) (string, error)
+	MountImage(ctx context.Context, refOrID string, platform *ocispec.Platform) (string, error)
+	UnmountImage(ctx context.Context, refOrID string) error
+	PinImage(ctx context.Context, refOrID string) error
+	UnpinImage(ctx context.Context, refOrID string) error
+	ConvertImage(ctx context.Context, refOrID string, targetRef reference.Named, options imagetype.ConvertOptions) error
+	FlattenImage(ctx context.Context, refOrID string, targetRef reference.Named, outStream io.Writer) error
+	VerifyImages(ctx context.Context, options imagetype.VerifyOptions) (*imagetype.VerifyReport, error)
+	ReadBlob(ctx context.Context, dgst digest.Digest) (io.ReadCloser, int64, error)
+	GenerateImageSBOM(ctx context.Context, refOrID string, options imagetype.SBOMOptions) (*imagetype.SBOMReport, error)
+	ImageReferrers(ctx context.Context, refOrID string) ([]ocispec.Descriptor, error)
+	ScanImage(ctx context.Context, refOrID string) (*imagetype.ScanReport, error)
+	ImageScanStatus(ctx context.Context, refOrID string) (*imagetype.ScanReport, error)
 
 	// Containerd related methods
 
@@ -77,5 +96,6 @@ type ImageService interface {
 	Children(ctx context.Context, id image.ID) ([]image.ID, error)
 	Cleanup() error
 	StorageDriver() string
-	UpdateConfig(maxDownloads, maxUploads int)
+	Capabilities() []string
+	UpdateConfig(ctx context.Context, maxDownloads, maxUploads int)
 }
@@ -0,0 +1,16 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// ReadBlob is not implemented by the legacy image store; reading content
+// blobs requires the containerd image store to be enabled.
+func (i *ImageService) ReadBlob(ctx context.Context, dgst digest.Digest) (io.ReadCloser, int64, error) {
+	return nil, 0, errdefs.NotImplemented(errors.New("reading content blobs is only supported with the containerd image store enabled"))
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/containerd/containerd/log"
 	"github.com/containerd/containerd/platforms"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
 	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
@@ -157,6 +158,25 @@ func (i *ImageService) manifestMatchesPlatform(ctx context.Context, img *image.I
 }
 
 // GetImage returns an image corresponding to the image referred to by refOrID.
+// imageUsage counts the containers on this daemon that use the given image.
+// Swarm services and build-cache records aren't tracked by the image
+// service, so those counts are reported as unknown.
+func (i *ImageService) imageUsage(id image.ID) *types.ImageUsage {
+	usage := &types.ImageUsage{Services: -1, BuildCache: -1}
+	for _, ctr := range i.containers.List() {
+		if ctr.ImageID != id {
+			continue
+		}
+		usage.Containers++
+		if ctr.IsRunning() {
+			usage.RunningContainers++
+		} else {
+			usage.StoppedContainers++
+		}
+	}
+	return usage
+}
+
 func (i *ImageService) GetImage(ctx context.Context, refOrID string, options imagetypes.GetImageOpts) (*image.Image, error) {
 	img, err := i.getImage(ctx, refOrID, options)
 	if err != nil {
@@ -189,6 +209,7 @@ func (i *ImageService) GetImage(ctx context.Context, refOrID string, options ima
 			Metadata:    layerMetadata,
 			Driver:      i.layerStore.DriverName(),
 			LastUpdated: lastUpdated,
+			Usage:       i.imageUsage(img.ID()),
 		}
 	}
 	return img, nil
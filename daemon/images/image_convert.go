@@ -0,0 +1,16 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// ConvertImage is not implemented by the legacy image store; converting
+// images requires the containerd image store to be enabled.
+func (i *ImageService) ConvertImage(ctx context.Context, refOrID string, targetRef reference.Named, options imagetypes.ConvertOptions) error {
+	return errdefs.NotImplemented(errors.New("converting images is only supported with the containerd image store enabled"))
+}
@@ -72,6 +72,12 @@ func (i *ImageService) ImageDelete(ctx context.Context, imageRef string, force,
 	imgID := img.ID()
 	repoRefs := i.referenceStore.References(imgID.Digest())
 
+	if !force {
+		if err := i.checkProtectedTags(imageRef, imgID, repoRefs); err != nil {
+			return nil, err
+		}
+	}
+
 	using := func(c *container.Container) bool {
 		return c.ImageID == imgID
 	}
@@ -207,6 +213,31 @@ func isSingleReference(repoRefs []reference.Named) bool {
 	return len(canonicalRefs) == 1 && ok
 }
 
+// checkProtectedTags returns a Forbidden error when the delete, requested
+// without force, would remove a tag matching one of the configured protected
+// tag patterns. Deleting by ID (prefix) removes every reference to the image,
+// so in that case all of its references are checked.
+func (i *ImageService) checkProtectedTags(imageRef string, imgID image.ID, repoRefs []reference.Named) error {
+	if isImageIDPrefix(imgID.String(), imageRef) {
+		for _, repoRef := range repoRefs {
+			if image.IsProtectedTag(i.protectedTags, repoRef) {
+				return errdefs.Forbidden(errors.Errorf("tag %s is protected and can only be deleted with force", reference.FamiliarString(repoRef)))
+			}
+		}
+		return nil
+	}
+
+	parsedRef, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return err
+	}
+	parsedRef = reference.TagNameOnly(parsedRef)
+	if image.IsProtectedTag(i.protectedTags, parsedRef) {
+		return errdefs.Forbidden(errors.Errorf("tag %s is protected and can only be deleted with force", reference.FamiliarString(parsedRef)))
+	}
+	return nil
+}
+
 // isImageIDPrefix returns whether the given possiblePrefix is a prefix of the
 // given imageID.
 func isImageIDPrefix(imageID, possiblePrefix string) bool {
@@ -0,0 +1,78 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
+	containertypes "github.com/docker/docker/api/types/container"
+	imagetypes "github.com/docker/docker/api/types/image"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// EnsureImage applies the given pull policy before a container is created
+// and returns the image the container will run. An empty policy keeps the
+// historic create behavior: the image is resolved locally and never pulled.
+//
+// The graphdriver image store records a single image per name, so "missing"
+// and "never" decide per name here; the per-platform semantics require the
+// containerd image store. "always" delegates the digest comparison to the
+// distribution pull, which only fetches content when the tag moved.
+func (i *ImageService) EnsureImage(ctx context.Context, refOrID string, policy containertypes.PullPolicy, platform *ocispec.Platform, authConfig *registrytypes.AuthConfig) (*image.Image, error) {
+	opts := imagetypes.GetImageOpts{Platform: platform}
+	switch policy {
+	case "", containertypes.PullPolicyNever:
+		return i.GetImage(ctx, refOrID, opts)
+	case containertypes.PullPolicyMissing, containertypes.PullPolicyAlways:
+	default:
+		return nil, errdefs.InvalidParameter(errors.Errorf("invalid pull policy: %q", policy))
+	}
+
+	pullRef, pinned := pullReference(refOrID)
+
+	img, err := i.GetImage(ctx, refOrID, opts)
+	if err == nil {
+		if policy == containertypes.PullPolicyMissing || pinned {
+			// Content-addressed references cannot become out of date.
+			return img, nil
+		}
+	} else if !errdefs.IsNotFound(err) || pullRef == nil {
+		return nil, err
+	}
+
+	log.G(ctx).WithFields(log.Fields{
+		"image":  reference.FamiliarString(pullRef),
+		"policy": policy,
+	}).Debug("pulling image for container create")
+
+	if err := i.pullImageWithReference(ctx, pullRef, platform, nil, authConfig, io.Discard); err != nil {
+		return nil, err
+	}
+
+	return i.GetImage(ctx, refOrID, opts)
+}
+
+// pullReference derives the reference to pull when a pull policy requires
+// fetching refOrID. Image IDs and bare digests cannot be pulled, so ref is
+// nil for those. pinned is true when the reference is content-addressed (an
+// ID or a digested reference) and so cannot become out of date.
+func pullReference(refOrID string) (ref reference.Named, pinned bool) {
+	parsed, err := reference.ParseAnyReference(refOrID)
+	if err != nil {
+		return nil, true
+	}
+	named, ok := parsed.(reference.Named)
+	if !ok {
+		// An image ID or a bare digest.
+		return nil, true
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return named, true
+	}
+	return reference.TagNameOnly(named), false
+}
@@ -5,8 +5,11 @@ import (
 	"io"
 
 	"github.com/containerd/containerd/log"
+	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image/tarexport"
+	"github.com/pkg/errors"
 )
 
 // ExportImage exports a list of images to the given output stream. The
@@ -14,7 +17,15 @@ import (
 // stream. All images with the given tag and all versions containing
 // the same tag are exported. names is the set of tags to export, and
 // outStream is the writer which the images are written to.
-func (i *ImageService) ExportImage(ctx context.Context, names []string, outStream io.Writer) error {
+//
+// The graphdriver store always keeps the data of foreign layers locally, and
+// the tar exporter includes it unconditionally, so NondistributableArtifacts
+// changes nothing here. The OCI format is only implemented by the containerd
+// image store.
+func (i *ImageService) ExportImage(ctx context.Context, names []string, outStream io.Writer, options imagetypes.SaveOptions) error {
+	if options.Format == imagetypes.SaveFormatOCI {
+		return errdefs.NotImplemented(errors.New("exporting in OCI format is only supported with the containerd image store"))
+	}
 	imageExporter := tarexport.NewTarExporter(i.imageStore, i.layerStore, i.referenceStore, i)
 	return imageExporter.Save(names, outStream)
 }
@@ -44,7 +55,10 @@ func (i *ImageService) PerformWithBaseFS(ctx context.Context, c *container.Conta
 // LoadImage uploads a set of images into the repository. This is the
 // complement of ExportImage.  The input stream is an uncompressed tar
 // ball containing images and metadata.
-func (i *ImageService) LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, quiet bool) error {
+func (i *ImageService) LoadImage(ctx context.Context, inTar io.ReadCloser, outStream io.Writer, options imagetypes.LoadOptions) error {
+	if len(options.Platforms) > 0 {
+		return errdefs.NotImplemented(errors.New("selecting platforms to load is only supported with the containerd image store"))
+	}
 	imageExporter := tarexport.NewTarExporter(i.imageStore, i.layerStore, i.referenceStore, i)
-	return imageExporter.Load(inTar, outStream, quiet)
+	return imageExporter.Load(inTar, outStream, options.Quiet)
 }
@@ -0,0 +1,39 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/streamformatter"
+)
+
+// FlattenImage creates a new single-layer image from the image's filesystem,
+// preserving its config, and stores it under targetRef. It is implemented on
+// top of SquashImage with an empty parent. The source image is left
+// untouched.
+func (i *ImageService) FlattenImage(ctx context.Context, refOrID string, targetRef reference.Named, outStream io.Writer) error {
+	out := streamformatter.NewJSONProgressOutput(outStream, false)
+
+	img, err := i.GetImage(ctx, refOrID, imagetypes.GetImageOpts{})
+	if err != nil {
+		return err
+	}
+
+	progress.Messagef(out, "", "Flattening %d layers of %s", len(img.RootFS.DiffIDs), reference.FamiliarString(targetRef))
+
+	newID, err := i.SquashImage(ctx, img.ID().String(), "")
+	if err != nil {
+		return err
+	}
+
+	if err := i.TagImage(ctx, image.ID(newID), targetRef); err != nil {
+		return err
+	}
+
+	progress.Messagef(out, "", "Flattened %s: digest: %s", reference.FamiliarString(targetRef), newID)
+	return nil
+}
@@ -0,0 +1,87 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/image"
+	"github.com/pkg/errors"
+)
+
+// UpdateImageLabels adds the given labels to an existing image without
+// rebuilding it, by deriving a new image config with the labels added. The
+// new image shares all layers with the original, and when a tag reference was
+// given it is moved to the derived image. Record mode is a containerd image
+// store concept and is not supported by the graph driver based store.
+func (i *ImageService) UpdateImageLabels(ctx context.Context, refOrID string, update imagetypes.LabelsUpdate) (image.ID, error) {
+	if len(update.Labels) == 0 {
+		return "", errdefs.InvalidParameter(errors.New("no labels provided"))
+	}
+
+	switch update.Mode {
+	case imagetypes.LabelsModeConfig, "":
+	case imagetypes.LabelsModeRecord:
+		return "", errdefs.NotImplemented(errors.New("record label mode is only supported with the containerd image store enabled"))
+	default:
+		return "", errdefs.InvalidParameter(errors.Errorf("invalid label update mode %q", update.Mode))
+	}
+
+	img, err := i.GetImage(ctx, refOrID, imagetypes.GetImageOpts{})
+	if err != nil {
+		return "", err
+	}
+
+	if img.Config == nil {
+		return "", errdefs.InvalidParameter(errors.Errorf("image %s has no config", refOrID))
+	}
+
+	newImage := *img
+	config := *img.Config
+	newImage.Config = &config
+
+	labels := make(map[string]string)
+	for k, v := range img.Config.Labels {
+		labels[k] = v
+	}
+	for k, v := range update.Labels {
+		labels[k] = v
+	}
+	newImage.Config.Labels = labels
+	now := time.Now()
+	newImage.Created = &now
+
+	b, err := json.Marshal(&newImage)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshalling image config")
+	}
+
+	newID, err := i.imageStore.Create(b)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating derived image")
+	}
+	if parent, err := i.imageStore.GetParent(img.ID()); err == nil && parent != "" {
+		if err := i.imageStore.SetParent(newID, parent); err != nil {
+			return "", errors.Wrap(err, "error setting parent of derived image")
+		}
+	}
+
+	// When a tag reference was given, move it to the derived image so the
+	// name keeps resolving to the labelled variant.
+	if ref, err := reference.ParseNormalizedNamed(refOrID); err == nil {
+		ref = reference.TagNameOnly(ref)
+		if _, isCanonical := ref.(reference.Canonical); !isCanonical {
+			if existing, err := i.referenceStore.Get(ref); err == nil && existing == img.ID().Digest() {
+				if err := i.referenceStore.AddTag(ref, newID.Digest(), true); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	i.LogImageEvent(newID.String(), newID.String(), "label")
+	return newID, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker/api/types"
 	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
 	"github.com/docker/docker/pkg/system"
@@ -22,6 +23,7 @@ var acceptedImageFilterTags = map[string]bool{
 	"before":    true,
 	"since":     true,
 	"reference": true,
+	"internal":  true,
 }
 
 // byCreated is a temporary type used to sort a list of images by creation
@@ -43,6 +45,16 @@ func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions)
 		return nil, err
 	}
 
+	// The graphdriver image store does not track internal helper images, so
+	// an internal=true listing is always empty.
+	internalOnly, err := opts.Filters.GetBoolOrDefault("internal", false)
+	if err != nil {
+		return nil, err
+	}
+	if internalOnly {
+		return []*types.ImageSummary{}, nil
+	}
+
 	var beforeFilter, sinceFilter time.Time
 	err = opts.Filters.WalkValues("before", func(value string) error {
 		img, err := i.GetImage(ctx, value, imagetypes.GetImageOpts{})
@@ -250,6 +262,25 @@ func (i *ImageService) Images(ctx context.Context, opts types.ImageListOptions)
 
 	sort.Sort(sort.Reverse(byCreated(summaries)))
 
+	// The graph store has no cheap incremental listing, so the page is cut
+	// out of the full sorted result.
+	if opts.Cursor != "" {
+		next := -1
+		for n, summary := range summaries {
+			if summary.ID == opts.Cursor {
+				next = n + 1
+				break
+			}
+		}
+		if next < 0 {
+			return nil, errdefs.InvalidParameter(fmt.Errorf("unknown cursor %q", opts.Cursor))
+		}
+		summaries = summaries[next:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(summaries) {
+		summaries = summaries[:opts.Limit]
+	}
+
 	return summaries, nil
 }
 
@@ -0,0 +1,21 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	"github.com/docker/docker/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// MountImage is not implemented by the legacy image store; mounting images
+// requires the containerd image store to be enabled.
+func (i *ImageService) MountImage(ctx context.Context, refOrID string, platform *ocispec.Platform) (string, error) {
+	return "", errdefs.NotImplemented(errors.New("mounting images is only supported with the containerd image store enabled"))
+}
+
+// UnmountImage is not implemented by the legacy image store; mounting images
+// requires the containerd image store to be enabled.
+func (i *ImageService) UnmountImage(ctx context.Context, refOrID string) error {
+	return errdefs.NotImplemented(errors.New("mounting images is only supported with the containerd image store enabled"))
+}
@@ -0,0 +1,20 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// PinImage is not implemented by the legacy image store; pinning images
+// requires the containerd image store to be enabled.
+func (i *ImageService) PinImage(ctx context.Context, refOrID string) error {
+	return errdefs.NotImplemented(errors.New("pinning images is only supported with the containerd image store enabled"))
+}
+
+// UnpinImage is not implemented by the legacy image store; pinning images
+// requires the containerd image store to be enabled.
+func (i *ImageService) UnpinImage(ctx context.Context, refOrID string) error {
+	return errdefs.NotImplemented(errors.New("pinning images is only supported with the containerd image store enabled"))
+}
@@ -3,6 +3,7 @@ package images // import "github.com/docker/docker/daemon/images"
 import (
 	"context"
 	"fmt"
+	"io"
 	"strconv"
 	"sync/atomic"
 	"time"
@@ -16,6 +17,8 @@ import (
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 )
@@ -31,8 +34,10 @@ var imagesAcceptedFilters = map[string]bool{
 // one is in progress
 var errPruneRunning = errdefs.Conflict(errors.New("a prune operation is already running"))
 
-// ImagesPrune removes unused images
-func (i *ImageService) ImagesPrune(ctx context.Context, pruneFilters filters.Args) (*types.ImagesPruneReport, error) {
+// ImagesPrune removes unused images. When outStream is non-nil, untagged
+// and deleted records are streamed to it as they happen instead of only
+// being collected in the final report.
+func (i *ImageService) ImagesPrune(ctx context.Context, pruneFilters filters.Args, outStream io.Writer) (*types.ImagesPruneReport, error) {
 	if !atomic.CompareAndSwapInt32(&i.pruneRunning, 0, 1) {
 		return nil, errPruneRunning
 	}
@@ -44,7 +49,12 @@ func (i *ImageService) ImagesPrune(ctx context.Context, pruneFilters filters.Arg
 		return nil, err
 	}
 
-	rep := &types.ImagesPruneReport{}
+	rep := &types.ImagesPruneReport{ImagesSkipped: map[string]uint64{}}
+
+	var out progress.Output
+	if outStream != nil {
+		out = streamformatter.NewJSONProgressOutput(outStream, false)
+	}
 
 	danglingOnly, err := pruneFilters.GetBoolOrDefault("dangling", true)
 	if err != nil {
@@ -76,9 +86,11 @@ func (i *ImageService) ImagesPrune(ctx context.Context, pruneFilters filters.Arg
 				continue
 			}
 			if !until.IsZero() && (img.Created == nil || img.Created.After(until)) {
+				rep.ImagesSkipped["filter mismatch"]++
 				continue
 			}
 			if img.Config != nil && !matchLabels(pruneFilters, img.Config.Labels) {
+				rep.ImagesSkipped["filter mismatch"]++
 				continue
 			}
 			topImages[id] = img
@@ -117,20 +129,35 @@ deleteImagesLoop:
 				for _, ref := range refs {
 					imgDel, err := i.ImageDelete(ctx, ref.String(), false, true)
 					if imageDeleteFailed(ref.String(), err) {
+						recordPruneFailure(rep, ref.String(), err)
 						continue
 					}
 					deletedImages = append(deletedImages, imgDel...)
 				}
+			} else {
+				rep.ImagesSkipped["filter mismatch"]++
 			}
 		} else {
 			hex := id.Digest().Encoded()
 			imgDel, err := i.ImageDelete(ctx, hex, false, true)
 			if imageDeleteFailed(hex, err) {
+				if errdefs.IsConflict(err) {
+					rep.ImagesSkipped["in use"]++
+				}
 				continue
 			}
 			deletedImages = append(deletedImages, imgDel...)
 		}
 
+		if out != nil {
+			for _, d := range deletedImages {
+				if d.Untagged != "" {
+					progress.Messagef(out, "", "Untagged: %s", d.Untagged)
+				} else {
+					progress.Messagef(out, "", "Deleted: %s", d.Deleted)
+				}
+			}
+		}
 		rep.ImagesDeleted = append(rep.ImagesDeleted, deletedImages...)
 	}
 
@@ -144,6 +171,9 @@ deleteImagesLoop:
 		}
 	}
 
+	if len(rep.ImagesSkipped) == 0 {
+		rep.ImagesSkipped = nil
+	}
 	if canceled {
 		log.G(ctx).Debugf("ImagesPrune operation cancelled: %#v", *rep)
 	}
@@ -155,6 +185,23 @@ deleteImagesLoop:
 	return rep, nil
 }
 
+// recordPruneFailure files a failed deletion under the report: images that
+// are in use are counted as skipped, everything else becomes a structured
+// per-image failure so callers can tell the categories apart.
+func recordPruneFailure(rep *types.ImagesPruneReport, ref string, err error) {
+	switch {
+	case errdefs.IsConflict(err):
+		rep.ImagesSkipped["in use"]++
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+	default:
+		rep.Failures = append(rep.Failures, types.ImagePruneFailure{
+			Image:  ref,
+			Reason: "delete failed",
+			Error:  err.Error(),
+		})
+	}
+}
+
 func imageDeleteFailed(ref string, err error) bool {
 	switch {
 	case err == nil:
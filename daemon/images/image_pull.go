@@ -24,7 +24,21 @@ import (
 
 // PullImage initiates a pull operation. image is the repository name to pull, and
 // tag may be either empty, or indicate a specific tag to pull.
-func (i *ImageService) PullImage(ctx context.Context, image, tag string, platform *ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
+func (i *ImageService) PullImage(ctx context.Context, image, tag string, options imagetypes.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
+	if options.AllPlatforms || len(options.Platforms) > 1 {
+		return errdefs.NotImplemented(errors.New("pulling multiple platforms is not supported by the legacy image store; enable the containerd image store to use this feature"))
+	}
+	if options.ContentOnly {
+		return errdefs.NotImplemented(errors.New("pulling without unpacking is not supported by the legacy image store; enable the containerd image store to use this feature"))
+	}
+	if options.Snapshotter != "" {
+		return errdefs.NotImplemented(errors.New("selecting a snapshotter is not supported by the legacy image store; enable the containerd image store to use this feature"))
+	}
+	var platform *ocispec.Platform
+	if len(options.Platforms) == 1 {
+		platform = &options.Platforms[0]
+	}
+
 	start := time.Now()
 	// Special case: "pull -a" may send an image name with a
 	// trailing :. This is ugly, but let's not break API
@@ -79,6 +93,28 @@ func (i *ImageService) PullImage(ctx context.Context, image, tag string, platfor
 	return nil
 }
 
+// PullImageBundle pulls every image in refs sequentially onto the same
+// progress stream. The first failing pull aborts the bundle; images pulled
+// before the failure are kept.
+func (i *ImageService) PullImageBundle(ctx context.Context, refs []string, options imagetypes.PullOptions, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
+	for _, name := range refs {
+		ref, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return errdefs.InvalidParameter(errors.Wrapf(err, "invalid reference %q", name))
+		}
+		tagOrDigest := ""
+		if tagged, ok := ref.(reference.Tagged); ok {
+			tagOrDigest = tagged.Tag()
+		} else if digested, ok := ref.(reference.Digested); ok {
+			tagOrDigest = digested.Digest().String()
+		}
+		if err := i.PullImage(ctx, ref.Name(), tagOrDigest, options, metaHeaders, authConfig, outStream); err != nil {
+			return errors.Wrapf(err, "failed to pull %s", reference.FamiliarString(ref))
+		}
+	}
+	return nil
+}
+
 func (i *ImageService) pullImageWithReference(ctx context.Context, ref reference.Named, platform *ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
 	// Include a buffer so that slow client connections don't affect
 	// transfer performance.
@@ -10,11 +10,20 @@ import (
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/distribution"
 	progressutils "github.com/docker/docker/distribution/utils"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
 )
 
 // PushImage initiates a push operation on the repository named localName.
-func (i *ImageService) PushImage(ctx context.Context, ref reference.Named, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer) error {
+// Nondistributable (foreign) layers are skipped unless withNondistributable
+// is set or the endpoint is configured to allow them through the
+// allow-nondistributable-artifacts daemon option.
+func (i *ImageService) PushImage(ctx context.Context, ref reference.Named, platforms []ocispec.Platform, metaHeaders map[string][]string, authConfig *registry.AuthConfig, outStream io.Writer, withNondistributable bool) error {
+	if len(platforms) > 0 {
+		return errdefs.NotImplemented(errors.New("pushing a specific platform is only supported with the containerd image store"))
+	}
 	start := time.Now()
 	// Include a buffer so that slow client connections don't affect
 	// transfer performance.
@@ -40,9 +49,10 @@ func (i *ImageService) PushImage(ctx context.Context, ref reference.Named, metaH
 			ImageStore:       distribution.NewImageConfigStoreFromStore(i.imageStore),
 			ReferenceStore:   i.referenceStore,
 		},
-		ConfigMediaType: schema2.MediaTypeImageConfig,
-		LayerStores:     distribution.NewLayerProvidersFromStore(i.layerStore),
-		UploadManager:   i.uploadManager,
+		ConfigMediaType:                schema2.MediaTypeImageConfig,
+		LayerStores:                    distribution.NewLayerProvidersFromStore(i.layerStore),
+		UploadManager:                  i.uploadManager,
+		AllowNondistributableArtifacts: withNondistributable,
 	}
 
 	err := distribution.Push(ctx, ref, imagePushConfig)
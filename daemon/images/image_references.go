@@ -0,0 +1,22 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// ExportReferences is not implemented by the legacy image store, which
+// persists its reference mapping in repositories.json; dumping and restoring
+// references requires the containerd image store to be enabled.
+func (i *ImageService) ExportReferences(ctx context.Context) (map[string]string, error) {
+	return nil, errdefs.NotImplemented(errors.New("exporting references is only supported with the containerd image store enabled"))
+}
+
+// ImportReferences is not implemented by the legacy image store; see
+// ExportReferences.
+func (i *ImageService) ImportReferences(ctx context.Context, refs map[string]string) (*imagetypes.ReferencesRestoreReport, error) {
+	return nil, errdefs.NotImplemented(errors.New("importing references is only supported with the containerd image store enabled"))
+}
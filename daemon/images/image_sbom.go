@@ -0,0 +1,22 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	imagetype "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// GenerateImageSBOM is not implemented by the legacy image store; SBOM
+// generation requires the containerd image store to be enabled.
+func (i *ImageService) GenerateImageSBOM(ctx context.Context, refOrID string, options imagetype.SBOMOptions) (*imagetype.SBOMReport, error) {
+	return nil, errdefs.NotImplemented(errors.New("SBOM generation is only supported with the containerd image store enabled"))
+}
+
+// ImageReferrers is not implemented by the legacy image store; referrer
+// artifacts require the containerd image store to be enabled.
+func (i *ImageService) ImageReferrers(ctx context.Context, refOrID string) ([]ocispec.Descriptor, error) {
+	return nil, errdefs.NotImplemented(errors.New("referrer artifacts are only supported with the containerd image store enabled"))
+}
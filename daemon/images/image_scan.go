@@ -0,0 +1,21 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	imagetype "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// ScanImage is not implemented by the legacy image store; image scanning
+// requires the containerd image store to be enabled.
+func (i *ImageService) ScanImage(ctx context.Context, refOrID string) (*imagetype.ScanReport, error) {
+	return nil, errdefs.NotImplemented(errors.New("image scanning is only supported with the containerd image store enabled"))
+}
+
+// ImageScanStatus is not implemented by the legacy image store; image
+// scanning requires the containerd image store to be enabled.
+func (i *ImageService) ImageScanStatus(ctx context.Context, refOrID string) (*imagetype.ScanReport, error) {
+	return nil, errdefs.NotImplemented(errors.New("image scanning is only supported with the containerd image store enabled"))
+}
@@ -4,11 +4,21 @@ import (
 	"context"
 
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
+	"github.com/pkg/errors"
 )
 
 // TagImage adds the given reference to the image ID provided.
 func (i *ImageService) TagImage(ctx context.Context, imageID image.ID, newTag reference.Named) error {
+	// Refuse to move a protected tag that currently points at a different
+	// image; it has to be deleted (with force) first.
+	if existing, err := i.referenceStore.Get(newTag); err == nil && existing != imageID.Digest() {
+		if image.IsProtectedTag(i.protectedTags, newTag) {
+			return errdefs.Forbidden(errors.Errorf("tag %s is protected and cannot be moved; delete it with force first", reference.FamiliarString(newTag)))
+		}
+	}
+
 	if err := i.referenceStore.AddTag(newTag, imageID.Digest(), true); err != nil {
 		return err
 	}
@@ -0,0 +1,27 @@
+package images
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+)
+
+// ImageTags returns every reference pointing at the same image ID as the
+// given image, as familiar strings. The image itself may be referred to by
+// any reference or ID prefix.
+func (i *ImageService) ImageTags(ctx context.Context, refOrID string) ([]string, error) {
+	img, err := i.GetImage(ctx, refOrID, imagetypes.GetImageOpts{})
+	if err != nil {
+		return nil, err
+	}
+
+	references := i.referenceStore.References(img.ID().Digest())
+	refs := make([]string, 0, len(references))
+	for _, ref := range references {
+		refs = append(refs, reference.FamiliarString(ref))
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
@@ -0,0 +1,15 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// VerifyImages is not implemented by the legacy image store; verifying image
+// content requires the containerd image store to be enabled.
+func (i *ImageService) VerifyImages(ctx context.Context, options imagetypes.VerifyOptions) (*imagetypes.VerifyReport, error) {
+	return nil, errdefs.NotImplemented(errors.New("verifying image content is only supported with the containerd image store enabled"))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/leases"
+	"github.com/docker/docker/api/types/system"
 	"github.com/docker/docker/container"
 	daemonevents "github.com/docker/docker/daemon/events"
 	"github.com/docker/docker/distribution"
@@ -43,6 +44,10 @@ type ImageServiceConfig struct {
 	ContentStore              content.Store
 	Leases                    leases.Manager
 	ContentNamespace          string
+
+	// ProtectedTags are patterns of tags that cannot be retagged, and can
+	// only be deleted with force; see image.IsProtectedTag.
+	ProtectedTags []string
 }
 
 // NewImageService returns a new ImageService from a configuration
@@ -60,6 +65,7 @@ func NewImageService(config ImageServiceConfig) *ImageService {
 		leases:                    config.Leases,
 		content:                   config.ContentStore,
 		contentNamespace:          config.ContentNamespace,
+		protectedTags:             config.ProtectedTags,
 	}
 }
 
@@ -78,6 +84,7 @@ type ImageService struct {
 	leases                    leases.Manager
 	content                   content.Store
 	contentNamespace          string
+	protectedTags             []string
 }
 
 // DistributionServices provides daemon image storage services
@@ -100,9 +107,19 @@ func (i *ImageService) DistributionServices() DistributionServices {
 	}
 }
 
+// Capabilities returns the optional image-store features implemented by the
+// graph driver based image service, advertised in the "Capabilities" section
+// of "/info".
+func (i *ImageService) Capabilities() []string {
+	return []string{
+		system.CapImageFlatten,
+		system.CapImageSquash,
+	}
+}
+
 // CountImages returns the number of images stored by ImageService
 // called from info.go
-func (i *ImageService) CountImages() int {
+func (i *ImageService) CountImages(ctx context.Context) int {
 	return i.imageStore.Len()
 }
 
@@ -224,7 +241,7 @@ func (i *ImageService) getLayerRefs() map[layer.ChainID]int {
 // UpdateConfig values
 //
 // called from reload.go
-func (i *ImageService) UpdateConfig(maxDownloads, maxUploads int) {
+func (i *ImageService) UpdateConfig(ctx context.Context, maxDownloads, maxUploads int) {
 	if i.downloadManager != nil && maxDownloads != 0 {
 		i.downloadManager.SetConcurrency(maxDownloads)
 	}
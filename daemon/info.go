@@ -28,7 +28,7 @@ import (
 )
 
 // SystemInfo returns information about the host server the daemon is running on.
-func (daemon *Daemon) SystemInfo() *system.Info {
+func (daemon *Daemon) SystemInfo(ctx context.Context) *system.Info {
 	defer metrics.StartTimer(hostInfoFunctions.WithValues("system_info"))()
 
 	sysInfo := daemon.RawSysInfo()
@@ -36,7 +36,7 @@ func (daemon *Daemon) SystemInfo() *system.Info {
 
 	v := &system.Info{
 		ID:                 daemon.id,
-		Images:             daemon.imageService.CountImages(),
+		Images:             daemon.imageService.CountImages(ctx),
 		IPv4Forwarding:     !sysInfo.IPv4ForwardingDisabled,
 		BridgeNfIptables:   !sysInfo.BridgeNFCallIPTablesDisabled,
 		BridgeNfIP6tables:  !sysInfo.BridgeNFCallIP6TablesDisabled,
@@ -63,6 +63,10 @@ func (daemon *Daemon) SystemInfo() *system.Info {
 		LiveRestoreEnabled: cfg.LiveRestoreEnabled,
 		Isolation:          daemon.defaultIsolation,
 		CDISpecDirs:        promoteNil(cfg.CDISpecDirs),
+		Capabilities: &system.Capabilities{
+			ImageStore: daemon.imageService.Capabilities(),
+		},
+		PreloadImages: daemon.imagePreloader.snapshot(),
 	}
 
 	daemon.fillContainerStates(v)
@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/reference"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/daemon/config"
+)
+
+// imagePreloader tracks the status of the images listed in the daemon's
+// preload-images configuration while they are checked and pulled in the
+// background.
+type imagePreloader struct {
+	mu       sync.Mutex
+	statuses []system.ImagePreloadStatus
+}
+
+func (p *imagePreloader) set(index int, status, errMsg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[index].Status = status
+	p.statuses[index].Error = errMsg
+}
+
+// snapshot returns a copy of the statuses for the info endpoint.
+func (p *imagePreloader) snapshot() []system.ImagePreloadStatus {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]system.ImagePreloadStatus, len(p.statuses))
+	copy(out, p.statuses)
+	return out
+}
+
+// startImagePreload ensures the images listed in the preload-images
+// configuration are present, pulling the missing ones in the background.
+// References that include a digest are resolved by it, so a present image
+// whose content does not match the digest is treated as missing and
+// re-pulled.
+func (daemon *Daemon) startImagePreload(cfg *config.Config) {
+	if len(cfg.PreloadImages) == 0 {
+		return
+	}
+	p := &imagePreloader{
+		statuses: make([]system.ImagePreloadStatus, len(cfg.PreloadImages)),
+	}
+	for n, img := range cfg.PreloadImages {
+		p.statuses[n] = system.ImagePreloadStatus{Image: img, Status: system.PreloadStatusPending}
+	}
+	daemon.imagePreloader = p
+
+	go daemon.preloadImages(context.Background(), cfg.PreloadImages)
+}
+
+func (daemon *Daemon) preloadImages(ctx context.Context, images []string) {
+	for n, name := range images {
+		logger := log.G(ctx).WithField("image", name)
+
+		// Validated when the configuration was loaded.
+		ref, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			daemon.imagePreloader.set(n, system.PreloadStatusFailed, err.Error())
+			continue
+		}
+
+		if _, err := daemon.imageService.GetImage(ctx, ref.String(), imagetypes.GetImageOpts{}); err == nil {
+			daemon.imagePreloader.set(n, system.PreloadStatusPresent, "")
+			continue
+		}
+
+		logger.Info("preloading image")
+		tagOrDigest := ""
+		if tagged, ok := ref.(reference.Tagged); ok {
+			tagOrDigest = tagged.Tag()
+		} else if digested, ok := ref.(reference.Digested); ok {
+			tagOrDigest = digested.Digest().String()
+		}
+		if err := daemon.imageService.PullImage(ctx, ref.Name(), tagOrDigest, imagetypes.PullOptions{}, nil, nil, io.Discard); err != nil {
+			logger.WithError(err).Error("failed to preload image")
+			daemon.imagePreloader.set(n, system.PreloadStatusFailed, err.Error())
+			continue
+		}
+		daemon.imagePreloader.set(n, system.PreloadStatusPulled, "")
+	}
+}
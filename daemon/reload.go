@@ -170,6 +170,7 @@ func (daemon *Daemon) reloadMaxConcurrentDownloadsAndUploads(txn *reloadTxn, new
 	txn.OnCommit(func() error {
 		if daemon.imageService != nil {
 			daemon.imageService.UpdateConfig(
+				context.TODO(),
 				newCfg.MaxConcurrentDownloads,
 				newCfg.MaxConcurrentUploads,
 			)
@@ -101,6 +101,12 @@ func (daemon *Daemon) ContainerStart(ctx context.Context, name string, hostConfi
 	return daemon.containerStart(ctx, daemonCfg, ctr, checkpoint, checkpointDir, true)
 }
 
+// snapshotEnsurer may be implemented by ImageService to recreate a
+// container's rootfs snapshot from its image before a checkpoint restore.
+type snapshotEnsurer interface {
+	EnsureContainerSnapshot(ctx context.Context, c *container.Container) error
+}
+
 // containerStart prepares the container to run by setting up everything the
 // container needs, such as storage and networking, as well as links
 // between containers. The container is left waiting for a signal to
@@ -149,6 +155,17 @@ func (daemon *Daemon) containerStart(ctx context.Context, daemonCfg *configStore
 		}
 	}()
 
+	if checkpoint != "" {
+		// The container's rootfs snapshot may have been cleaned up while
+		// it was checkpointed out; recreate it from the image chain so the
+		// restored process has a filesystem to run on.
+		if ensurer, ok := daemon.imageService.(snapshotEnsurer); ok {
+			if err := ensurer.EnsureContainerSnapshot(ctx, container); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err := daemon.conditionalMountOnStart(container); err != nil {
 		return err
 	}
@@ -74,6 +74,10 @@ type ImagePushConfig struct {
 	LayerStores PushLayerProvider
 	// UploadManager dispatches uploads.
 	UploadManager *xfer.LayerUploadManager
+	// AllowNondistributableArtifacts uploads nondistributable (foreign)
+	// layers even when the endpoint is not configured to allow them in the
+	// daemon's registry configuration.
+	AllowNondistributableArtifacts bool
 }
 
 // RegistryResolver is used for TLS configuration and endpoint lookup.
@@ -54,6 +54,10 @@ func Push(ctx context.Context, ref reference.Named, config *ImagePushConfig) err
 			}
 		}
 
+		if config.AllowNondistributableArtifacts {
+			endpoint.AllowNondistributableArtifacts = true
+		}
+
 		log.G(ctx).Debugf("Trying to push %s to %s", repoInfo.Name.Name(), endpoint.URL)
 
 		if err := newPusher(ref, endpoint, repoInfo, config).push(ctx); err != nil {
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/layer"
@@ -127,6 +128,40 @@ type Details struct {
 	Metadata    map[string]string
 	Driver      string
 	LastUpdated time.Time
+
+	// ArtifactType is the media type of the artifact when the manifest
+	// describes an OCI artifact instead of a runnable image.
+	ArtifactType string
+
+	// DistributionSources lists the registry repositories the image is
+	// known to have been pulled from, as "host/repository" strings.
+	DistributionSources []string
+
+	// Snapshotters lists the snapshotters that hold an unpacked copy of
+	// the image for the host platform.
+	Snapshotters []string
+
+	// Usage counts the resources on the daemon that reference the image,
+	// such as containers using it.
+	Usage *types.ImageUsage
+
+	// Platforms describes the local state of every platform present in
+	// the image's index: whether its content is complete and which
+	// snapshotters hold an unpacked copy of it.
+	Platforms []types.ImagePlatformStatus
+
+	// Pinned reports whether the image was pinned, protecting it from
+	// image prune and from the automatic image garbage collector.
+	Pinned bool
+
+	// LastPulled is when the image was last pulled, or last validated at
+	// the registry by the "always" pull policy. Zero for images that were
+	// built or imported locally.
+	LastPulled time.Time
+
+	// LastPushed is when the image was last pushed to a registry by this
+	// daemon. Zero when it never was.
+	LastPushed time.Time
 }
 
 // RawJSON returns the immutable JSON associated with the image.
@@ -0,0 +1,38 @@
+package image // import "github.com/docker/docker/image"
+
+import (
+	"path"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// IsProtectedTag reports whether ref matches any of the given protected tag
+// patterns. Patterns are shell globs; a pattern containing a "/" or ":" is
+// matched against the whole familiar reference (e.g.
+// "registry.example.com/app:*"), any other pattern against the tag alone
+// (e.g. "prod-*").
+func IsProtectedTag(patterns []string, ref reference.Named) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	var tag string
+	if tagged, ok := ref.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+	familiar := reference.FamiliarString(ref)
+
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "/:") {
+			if matched, _ := path.Match(pattern, familiar); matched {
+				return true
+			}
+		} else if tag != "" {
+			if matched, _ := path.Match(pattern, tag); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
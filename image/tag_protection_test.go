@@ -0,0 +1,33 @@
+package image // import "github.com/docker/docker/image"
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/reference"
+	"gotest.tools/v3/assert"
+)
+
+func TestIsProtectedTag(t *testing.T) {
+	mustParse := func(s string) reference.Named {
+		ref, err := reference.ParseNormalizedNamed(s)
+		assert.NilError(t, err)
+		return ref
+	}
+
+	for _, tc := range []struct {
+		patterns  []string
+		ref       string
+		protected bool
+	}{
+		{nil, "app:prod-1", false},
+		{[]string{"prod-*"}, "app:prod-1", true},
+		{[]string{"prod-*"}, "app:dev-1", false},
+		{[]string{"prod-*"}, "app@sha256:8b2bc225ecf0a26dc81c9d809afcee86c42accd6d1701c0be30b12c4c4a2f528", false},
+		{[]string{"example.com/app:*"}, "example.com/app:latest", true},
+		{[]string{"example.com/app:*"}, "example.com/other:latest", false},
+		{[]string{"dev-*", "prod-*"}, "app:prod-1", true},
+	} {
+		assert.Equal(t, IsProtectedTag(tc.patterns, mustParse(tc.ref)), tc.protected,
+			"patterns=%v ref=%s", tc.patterns, tc.ref)
+	}
+}
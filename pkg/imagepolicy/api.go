@@ -0,0 +1,39 @@
+package imagepolicy // import "github.com/docker/docker/pkg/imagepolicy"
+
+const (
+	// AdmitPullRequest is the url for pull admission requests
+	AdmitPullRequest = "ImagePolicyPlugin.AdmitPull"
+
+	// ImagePolicyApiImplements is the name of the interface all image policy plugins implement
+	ImagePolicyApiImplements = "imagepolicy"
+)
+
+// PullRequest holds data required for image policy plugins to decide whether
+// a pull may proceed. It is sent after the reference has been resolved at the
+// registry, but before any content is downloaded.
+type PullRequest struct {
+	// Reference is the fully-qualified image reference being pulled
+	Reference string `json:"Reference"`
+
+	// Digest is the digest of the resolved manifest or index
+	Digest string `json:"Digest"`
+
+	// MediaType is the media type of the resolved manifest or index
+	MediaType string `json:"MediaType,omitempty"`
+
+	// Platforms holds the requested platforms in "os/arch[/variant]"
+	// format. Empty means the host platform; ["all"] means all platforms.
+	Platforms []string `json:"Platforms,omitempty"`
+}
+
+// Response represents a plugin admission response
+type Response struct {
+	// Allow indicates whether the pull is admitted
+	Allow bool `json:"Allow"`
+
+	// Msg contains the reason for denying the pull
+	Msg string `json:"Msg,omitempty"`
+
+	// Err contains a plugin-internal error message
+	Err string `json:"Err,omitempty"`
+}
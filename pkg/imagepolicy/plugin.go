@@ -0,0 +1,99 @@
+package imagepolicy // import "github.com/docker/docker/pkg/imagepolicy"
+
+import (
+	"sync"
+
+	"github.com/docker/docker/pkg/plugingetter"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// Plugin allows third party plugins to admit or reject image pulls before
+// any content is downloaded by the daemon
+type Plugin interface {
+	// Name returns the registered plugin name
+	Name() string
+
+	// AdmitPull asks the plugin to admit or reject a pull
+	AdmitPull(*PullRequest) (*Response, error)
+}
+
+// NewPlugins constructs and initializes the image policy plugins based on plugin names
+func NewPlugins(names []string) []Plugin {
+	plugins := []Plugin{}
+	pluginsMap := make(map[string]struct{})
+	for _, name := range names {
+		if _, ok := pluginsMap[name]; ok {
+			continue
+		}
+		pluginsMap[name] = struct{}{}
+		plugins = append(plugins, newImagePolicyPlugin(name))
+	}
+	return plugins
+}
+
+var getter plugingetter.PluginGetter
+
+// SetPluginGetter sets the plugingetter
+func SetPluginGetter(pg plugingetter.PluginGetter) {
+	getter = pg
+}
+
+// GetPluginGetter gets the plugingetter
+func GetPluginGetter() plugingetter.PluginGetter {
+	return getter
+}
+
+// imagePolicyPlugin is an internal adapter to docker plugin system
+type imagePolicyPlugin struct {
+	initErr error
+	plugin  *plugins.Client
+	name    string
+	once    sync.Once
+}
+
+func newImagePolicyPlugin(name string) Plugin {
+	return &imagePolicyPlugin{name: name}
+}
+
+func (a *imagePolicyPlugin) Name() string {
+	return a.name
+}
+
+func (a *imagePolicyPlugin) AdmitPull(req *PullRequest) (*Response, error) {
+	if err := a.initPlugin(); err != nil {
+		return nil, err
+	}
+
+	res := &Response{}
+	if err := a.plugin.Call(AdmitPullRequest, req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// initPlugin initializes the image policy plugin if needed
+func (a *imagePolicyPlugin) initPlugin() error {
+	// Lazy loading of plugins
+	a.once.Do(func() {
+		if a.plugin == nil {
+			var plugin plugingetter.CompatPlugin
+			var e error
+
+			if pg := GetPluginGetter(); pg != nil {
+				plugin, e = pg.Get(a.name, ImagePolicyApiImplements, plugingetter.Lookup)
+				if e == nil {
+					a.name = plugin.Name()
+				}
+			} else {
+				plugin, e = plugins.Get(a.name, ImagePolicyApiImplements)
+			}
+			if e != nil {
+				a.initErr = e
+				return
+			}
+			a.plugin = plugin.Client()
+		}
+	})
+	return a.initErr
+}
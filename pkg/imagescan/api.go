@@ -0,0 +1,48 @@
+package imagescan // import "github.com/docker/docker/pkg/imagescan"
+
+import "encoding/json"
+
+const (
+	// ScanRequestCall is the url for image scan requests
+	ScanRequestCall = "ImageScanPlugin.Scan"
+
+	// ImageScanApiImplements is the name of the interface all image scan plugins implement
+	ImageScanApiImplements = "imagescan"
+)
+
+// ScanRequest holds the data handed to an image scan plugin. The plugin gets
+// a read-only view of the image's filesystem and, when one has been
+// generated, the digest of the image's SBOM artifact.
+type ScanRequest struct {
+	// Reference is the image reference or ID the scan was requested for
+	Reference string `json:"Reference"`
+
+	// Digest is the digest of the image's manifest or index
+	Digest string `json:"Digest"`
+
+	// RootFS is the path of a read-only mount of the image's filesystem.
+	// The mount only exists for the duration of the call.
+	RootFS string `json:"RootFS"`
+
+	// SBOM is the digest of the artifact manifest holding the image's SBOM
+	// document, when one has been generated. Empty otherwise.
+	SBOM string `json:"SBOM,omitempty"`
+}
+
+// ScanResponse represents a plugin scan response
+type ScanResponse struct {
+	// Status is the plugin-defined outcome of the scan, for example
+	// "clean" or "vulnerable". It is stored as a label on the image.
+	Status string `json:"Status"`
+
+	// Summary is a short human-readable description of the outcome, for
+	// example a vulnerability count breakdown.
+	Summary string `json:"Summary,omitempty"`
+
+	// Results is the full scan report in the plugin's format. When
+	// non-empty it is stored as a referrer artifact of the image.
+	Results json.RawMessage `json:"Results,omitempty"`
+
+	// Err contains a plugin-internal error message
+	Err string `json:"Err,omitempty"`
+}
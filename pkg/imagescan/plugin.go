@@ -0,0 +1,87 @@
+package imagescan // import "github.com/docker/docker/pkg/imagescan"
+
+import (
+	"sync"
+
+	"github.com/docker/docker/pkg/plugingetter"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// Plugin allows third party plugins to scan images for vulnerabilities on
+// behalf of the daemon
+type Plugin interface {
+	// Name returns the registered plugin name
+	Name() string
+
+	// Scan asks the plugin to scan a mounted image view
+	Scan(*ScanRequest) (*ScanResponse, error)
+}
+
+// NewPlugin constructs an image scan plugin with the given name. The plugin
+// is looked up lazily on first use.
+func NewPlugin(name string) Plugin {
+	return &imageScanPlugin{name: name}
+}
+
+var getter plugingetter.PluginGetter
+
+// SetPluginGetter sets the plugingetter
+func SetPluginGetter(pg plugingetter.PluginGetter) {
+	getter = pg
+}
+
+// GetPluginGetter gets the plugingetter
+func GetPluginGetter() plugingetter.PluginGetter {
+	return getter
+}
+
+// imageScanPlugin is an internal adapter to docker plugin system
+type imageScanPlugin struct {
+	initErr error
+	plugin  *plugins.Client
+	name    string
+	once    sync.Once
+}
+
+func (a *imageScanPlugin) Name() string {
+	return a.name
+}
+
+func (a *imageScanPlugin) Scan(req *ScanRequest) (*ScanResponse, error) {
+	if err := a.initPlugin(); err != nil {
+		return nil, err
+	}
+
+	res := &ScanResponse{}
+	if err := a.plugin.Call(ScanRequestCall, req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// initPlugin initializes the image scan plugin if needed
+func (a *imageScanPlugin) initPlugin() error {
+	// Lazy loading of plugins
+	a.once.Do(func() {
+		if a.plugin == nil {
+			var plugin plugingetter.CompatPlugin
+			var e error
+
+			if pg := GetPluginGetter(); pg != nil {
+				plugin, e = pg.Get(a.name, ImageScanApiImplements, plugingetter.Lookup)
+				if e == nil {
+					a.name = plugin.Name()
+				}
+			} else {
+				plugin, e = plugins.Get(a.name, ImageScanApiImplements)
+			}
+			if e != nil {
+				a.initErr = e
+				return
+			}
+			a.plugin = plugin.Client()
+		}
+	})
+	return a.initErr
+}
@@ -5,6 +5,54 @@ import (
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+// Parse parses a platform specifier of the form os[/arch[/variant]] into its
+// canonical form: alternative spellings like "aarch64" or "arm64/v8" become
+// "arm64", and "armhf" becomes "arm/v7". Every API platform parameter goes
+// through this helper, so all endpoints accept the same spellings.
+func Parse(specifier string) (ocispec.Platform, error) {
+	p, err := cplatforms.Parse(specifier)
+	if err != nil {
+		return ocispec.Platform{}, err
+	}
+	return Normalize(p), nil
+}
+
+// Normalize returns the canonical form of p; see Parse.
+func Normalize(p ocispec.Platform) ocispec.Platform {
+	return cplatforms.Normalize(p)
+}
+
+// Only returns a matcher for the given platform that also accepts compatible
+// lower CPU variants (an armv6 image matches an armv7 platform). The platform
+// is normalized first, so "arm64/v8" and "arm64" produce the same matcher.
+// Use it when selecting an image to run.
+func Only(p ocispec.Platform) cplatforms.MatchComparer {
+	return cplatforms.Only(Normalize(p))
+}
+
+// OnlyStrict is like Only but without the variant fallback: the platform has
+// to match exactly after normalization. Use it when selecting content to
+// push, save or convert, where a near-miss would silently process the wrong
+// manifest.
+func OnlyStrict(p ocispec.Platform) cplatforms.MatchComparer {
+	return cplatforms.OnlyStrict(Normalize(p))
+}
+
+// Format returns the canonical os[/arch[/variant]] string for p.
+func Format(p ocispec.Platform) string {
+	return cplatforms.Format(Normalize(p))
+}
+
+// Any returns a matcher that matches any of the given platforms after
+// normalization.
+func Any(ps ...ocispec.Platform) cplatforms.MatchComparer {
+	normalized := make([]ocispec.Platform, len(ps))
+	for n, p := range ps {
+		normalized[n] = Normalize(p)
+	}
+	return cplatforms.Any(normalized...)
+}
+
 type allPlatformsWithPreferenceMatcher struct {
 	preferred cplatforms.MatchComparer
 }
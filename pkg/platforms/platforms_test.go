@@ -0,0 +1,76 @@
+package platforms
+
+import (
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestParseNormalizes(t *testing.T) {
+	for _, tc := range []struct {
+		specifier string
+		expected  ocispec.Platform
+	}{
+		{"linux/amd64", ocispec.Platform{OS: "linux", Architecture: "amd64"}},
+		{"linux/x86_64", ocispec.Platform{OS: "linux", Architecture: "amd64"}},
+		{"linux/arm64", ocispec.Platform{OS: "linux", Architecture: "arm64"}},
+		{"linux/arm64/v8", ocispec.Platform{OS: "linux", Architecture: "arm64"}},
+		{"linux/aarch64", ocispec.Platform{OS: "linux", Architecture: "arm64"}},
+		{"linux/armhf", ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{"linux/arm/v6", ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+		{"macos/amd64", ocispec.Platform{OS: "darwin", Architecture: "amd64"}},
+	} {
+		tc := tc
+		t.Run(tc.specifier, func(t *testing.T) {
+			p, err := Parse(tc.specifier)
+			assert.NilError(t, err)
+			assert.Check(t, is.DeepEqual(p, tc.expected))
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse("linux/amd64/")
+	assert.Check(t, err != nil)
+}
+
+func TestOnlyVariantEquivalence(t *testing.T) {
+	arm64 := ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	arm64v8 := ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+
+	// arm64 and arm64/v8 are the same platform, whichever side of the
+	// comparison either spelling ends up on.
+	assert.Check(t, Only(arm64).Match(arm64v8))
+	assert.Check(t, Only(arm64v8).Match(arm64))
+	assert.Check(t, OnlyStrict(arm64v8).Match(arm64))
+	assert.Check(t, OnlyStrict(arm64).Match(arm64v8))
+}
+
+func TestOnlyArmVariantFallback(t *testing.T) {
+	armv7 := ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+	armv6 := ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}
+
+	// An armv6 image runs on an armv7 CPU, but only the fuzzy matcher may
+	// say so; the strict matcher is for selecting content, where a
+	// near-miss would silently pick the wrong manifest.
+	assert.Check(t, Only(armv7).Match(armv6))
+	assert.Check(t, !OnlyStrict(armv7).Match(armv6))
+	assert.Check(t, !Only(armv6).Match(armv7))
+}
+
+func TestAnyNormalizes(t *testing.T) {
+	m := Any(
+		ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+		ocispec.Platform{OS: "linux", Architecture: "x86_64"},
+	)
+	assert.Check(t, m.Match(ocispec.Platform{OS: "linux", Architecture: "arm64"}))
+	assert.Check(t, m.Match(ocispec.Platform{OS: "linux", Architecture: "amd64"}))
+	assert.Check(t, !m.Match(ocispec.Platform{OS: "linux", Architecture: "riscv64"}))
+}
+
+func TestFormat(t *testing.T) {
+	assert.Check(t, is.Equal(Format(ocispec.Platform{OS: "linux", Architecture: "aarch64"}), "linux/arm64"))
+	assert.Check(t, is.Equal(Format(ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}), "linux/arm/v6"))
+}
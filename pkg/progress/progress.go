@@ -16,6 +16,10 @@ type Progress struct {
 	Current int64
 	Total   int64
 
+	// Start is the time the action started, in Unix seconds. When set,
+	// clients can derive the transfer rate and an ETA from Current/Total.
+	Start int64
+
 	// If true, don't show xB/yB
 	HideCounts bool
 	// If not empty, use units instead of bytes for counts
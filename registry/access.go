@@ -0,0 +1,66 @@
+package registry // import "github.com/docker/docker/registry"
+
+// normalizeRegistryHost maps the hostnames used for Docker Hub to the
+// canonical index name, so allowlist and denylist entries like "docker.io"
+// match references using any of the legacy domains.
+func normalizeRegistryHost(host string) string {
+	switch host {
+	case DefaultRegistryHost, IndexHostname, IndexName:
+		return IndexName
+	}
+	return host
+}
+
+// loadRegistryAccessPolicy validates the configured allowlist and denylist
+// and returns them as lookup sets keyed by normalized hostname.
+func loadRegistryAccessPolicy(allowed, blocked []string) (allowedSet, blockedSet map[string]struct{}, err error) {
+	load := func(option string, registries []string) (map[string]struct{}, error) {
+		if len(registries) == 0 {
+			return nil, nil
+		}
+		set := make(map[string]struct{}, len(registries))
+		for _, r := range registries {
+			if hasScheme(r) {
+				return nil, invalidParamf("%s registry %s should not contain '://'", option, r)
+			}
+			if _, err := ValidateIndexName(r); err != nil {
+				return nil, err
+			}
+			if r != IndexName {
+				if err := validateHostPort(r); err != nil {
+					return nil, invalidParamWrapf(err, "%s registry %s is not valid", option, r)
+				}
+			}
+			set[normalizeRegistryHost(r)] = struct{}{}
+		}
+		return set, nil
+	}
+
+	if allowedSet, err = load("allowed", allowed); err != nil {
+		return nil, nil, err
+	}
+	if blockedSet, err = load("blocked", blocked); err != nil {
+		return nil, nil, err
+	}
+	return allowedSet, blockedSet, nil
+}
+
+// CheckRegistryIsAllowed returns an error when the daemon's registry access
+// policy forbids using the registry at the given host. The denylist is
+// consulted first; when an allowlist is configured, any host not on it is
+// rejected.
+func (s *Service) CheckRegistryIsAllowed(host string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	host = normalizeRegistryHost(host)
+	if _, denied := s.blockedRegistries[host]; denied {
+		return forbiddenf("registry %s is blocked by the daemon configuration", host)
+	}
+	if len(s.allowedRegistries) > 0 {
+		if _, ok := s.allowedRegistries[host]; !ok {
+			return forbiddenf("registry %s is not in the list of allowed registries", host)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckRegistryIsAllowed(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     ServiceOptions
+		host        string
+		expectError bool
+	}{
+		{
+			name: "no policy allows everything",
+			host: "registry.example.com",
+		},
+		{
+			name:    "allowlist admits listed host",
+			options: ServiceOptions{AllowedRegistries: []string{"registry.example.com"}},
+			host:    "registry.example.com",
+		},
+		{
+			name:        "allowlist rejects unlisted host",
+			options:     ServiceOptions{AllowedRegistries: []string{"registry.example.com"}},
+			host:        "other.example.com",
+			expectError: true,
+		},
+		{
+			name:        "denylist rejects listed host",
+			options:     ServiceOptions{BlockedRegistries: []string{"registry.example.com"}},
+			host:        "registry.example.com",
+			expectError: true,
+		},
+		{
+			name:        "denylist wins over allowlist",
+			options:     ServiceOptions{AllowedRegistries: []string{"registry.example.com"}, BlockedRegistries: []string{"registry.example.com"}},
+			host:        "registry.example.com",
+			expectError: true,
+		},
+		{
+			name:        "docker.io entry matches the registry host",
+			options:     ServiceOptions{BlockedRegistries: []string{"docker.io"}},
+			host:        DefaultRegistryHost,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			svc, err := NewService(tc.options)
+			assert.NilError(t, err)
+			err = svc.CheckRegistryIsAllowed(tc.host)
+			if tc.expectError {
+				assert.Check(t, err != nil)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadRegistryAccessPolicyInvalid(t *testing.T) {
+	for _, invalid := range []string{"https://registry.example.com", "registryex!ample.com"} {
+		_, _, err := loadRegistryAccessPolicy([]string{invalid}, nil)
+		assert.Check(t, err != nil, "expected an error for %q", invalid)
+	}
+}
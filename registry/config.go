@@ -18,6 +18,48 @@ type ServiceOptions struct {
 	AllowNondistributableArtifacts []string `json:"allow-nondistributable-artifacts,omitempty"`
 	Mirrors                        []string `json:"registry-mirrors,omitempty"`
 	InsecureRegistries             []string `json:"insecure-registries,omitempty"`
+
+	// SearchEndpoints configures the search backend per registry host, so
+	// "docker search" can work against registries other than Docker Hub.
+	SearchEndpoints map[string]SearchEndpointConfig `json:"search-endpoints,omitempty"`
+
+	// AllowedRegistries restricts pulls and pushes to the listed registry
+	// hosts when non-empty.
+	AllowedRegistries []string `json:"allowed-registries,omitempty"`
+
+	// BlockedRegistries rejects pulls from and pushes to the listed
+	// registry hosts.
+	BlockedRegistries []string `json:"blocked-registries,omitempty"`
+
+	// Proxies configures the proxy to use per registry host, keyed by
+	// hostname. Values are proxy URLs with an http, https or socks5
+	// scheme, or "direct" to bypass proxies from the environment for
+	// that host.
+	Proxies map[string]string `json:"registry-proxies,omitempty"`
+
+	// ClientCertificates configures client TLS key pairs per registry
+	// host for registries that require mutual TLS, in addition to any
+	// key pairs picked up from the certs.d directory.
+	ClientCertificates map[string]ClientCertificateConfig `json:"registry-client-certs,omitempty"`
+}
+
+// ClientCertificateConfig holds the paths to a client certificate and the
+// corresponding private key used for mutual TLS with a registry.
+type ClientCertificateConfig struct {
+	CertFile string `json:"cert"`
+	KeyFile  string `json:"key"`
+}
+
+// loadClientCertificates validates the configured per-registry client
+// certificates. The certificate and key files must both be configured; they
+// are loaded when a connection to the registry is made.
+func loadClientCertificates(certs map[string]ClientCertificateConfig) error {
+	for host, cert := range certs {
+		if cert.CertFile == "" || cert.KeyFile == "" {
+			return invalidParamf("client certificate for registry %s requires both a cert and a key file", host)
+		}
+	}
+	return nil
 }
 
 // serviceConfig holds daemon configuration for the registry service.
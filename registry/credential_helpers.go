@@ -0,0 +1,94 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/containerd/containerd/log"
+	"github.com/pkg/errors"
+)
+
+// tokenUsername is the username credential helpers report when the secret
+// they return is an identity token rather than a password.
+const tokenUsername = "<token>"
+
+// CredentialHelpers resolves registry credentials from docker credential
+// helper programs configured on the daemon, keyed by registry host. Helpers
+// follow the docker-credential-helpers protocol: a "docker-credential-<name>"
+// program is run with the "get" action and the registry URL on stdin, and
+// answers with a JSON document holding the username and secret.
+//
+// A nil *CredentialHelpers is valid and resolves no credentials.
+type CredentialHelpers struct {
+	helpers map[string]string
+}
+
+// NewCredentialHelpers returns a credential resolver for the given host to
+// helper-name mapping. It returns nil when no helpers are configured.
+func NewCredentialHelpers(helpers map[string]string) *CredentialHelpers {
+	if len(helpers) == 0 {
+		return nil
+	}
+	return &CredentialHelpers{helpers: helpers}
+}
+
+// Configured reports whether a credential helper is configured for host.
+func (ch *CredentialHelpers) Configured(host string) bool {
+	if ch == nil {
+		return false
+	}
+	_, ok := ch.helpers[host]
+	return ok
+}
+
+// Get resolves credentials for host through its configured helper. It
+// returns empty credentials, without error, when no helper is configured
+// for the host or the helper holds no credentials for it. When the helper
+// returns an identity token instead of a password, the username is empty
+// and the secret is the token.
+func (ch *CredentialHelpers) Get(host string) (string, string, error) {
+	if ch == nil {
+		return "", "", nil
+	}
+	helper, ok := ch.helpers[host]
+	if !ok {
+		return "", "", nil
+	}
+
+	program := "docker-credential-" + helper
+	cmd := exec.Command(program, "get")
+	cmd.Stdin = strings.NewReader("https://" + host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Helpers report a missing entry as a plain-text message on
+		// stdout and a non-zero exit status; that is not an error, the
+		// daemon simply has no credentials for this host.
+		if msg := strings.TrimSpace(stdout.String()); strings.Contains(msg, "credentials not found") {
+			return "", "", nil
+		}
+		log.G(context.TODO()).WithFields(log.Fields{
+			"host":   host,
+			"helper": program,
+			"stderr": strings.TrimSpace(stderr.String()),
+		}).WithError(err).Error("credential helper failed")
+		return "", "", errors.Wrapf(err, "resolving credentials for %s with %s", host, program)
+	}
+
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return "", "", errors.Wrapf(err, "parsing %s output for %s", program, host)
+	}
+	if creds.Username == tokenUsername {
+		return "", creds.Secret, nil
+	}
+	return creds.Username, creds.Secret, nil
+}
@@ -0,0 +1,81 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+// installFakeHelper writes a docker-credential-<name> script to a temporary
+// directory on PATH that prints the given stdout and exits with the given
+// status.
+func installFakeHelper(t *testing.T, name, stdout string, exitCode int) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("test helper is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nprintf '%s' '" + stdout + "'\nexit " + strconv.Itoa(exitCode) + "\n"
+	err := os.WriteFile(filepath.Join(dir, "docker-credential-"+name), []byte(script), 0o755)
+	assert.NilError(t, err)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCredentialHelpersGet(t *testing.T) {
+	installFakeHelper(t, "fake", `{"Username":"alice","Secret":"hunter2"}`, 0)
+
+	ch := NewCredentialHelpers(map[string]string{"registry.example.com": "fake"})
+	username, secret, err := ch.Get("registry.example.com")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(username, "alice"))
+	assert.Check(t, is.Equal(secret, "hunter2"))
+
+	// Hosts without a configured helper resolve to no credentials.
+	username, secret, err = ch.Get("other.example.com")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(username, ""))
+	assert.Check(t, is.Equal(secret, ""))
+}
+
+func TestCredentialHelpersGetIdentityToken(t *testing.T) {
+	installFakeHelper(t, "fake", `{"Username":"<token>","Secret":"sometoken"}`, 0)
+
+	ch := NewCredentialHelpers(map[string]string{"registry.example.com": "fake"})
+	username, secret, err := ch.Get("registry.example.com")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(username, ""))
+	assert.Check(t, is.Equal(secret, "sometoken"))
+}
+
+func TestCredentialHelpersGetNotFound(t *testing.T) {
+	installFakeHelper(t, "fake", "credentials not found in native keychain", 1)
+
+	ch := NewCredentialHelpers(map[string]string{"registry.example.com": "fake"})
+	username, secret, err := ch.Get("registry.example.com")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(username, ""))
+	assert.Check(t, is.Equal(secret, ""))
+}
+
+func TestCredentialHelpersGetFailure(t *testing.T) {
+	installFakeHelper(t, "fake", "keychain locked", 1)
+
+	ch := NewCredentialHelpers(map[string]string{"registry.example.com": "fake"})
+	_, _, err := ch.Get("registry.example.com")
+	assert.ErrorContains(t, err, "resolving credentials for registry.example.com")
+}
+
+func TestCredentialHelpersNil(t *testing.T) {
+	var ch *CredentialHelpers
+	assert.Check(t, is.Nil(NewCredentialHelpers(nil)))
+	assert.Check(t, !ch.Configured("registry.example.com"))
+	username, secret, err := ch.Get("registry.example.com")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(username, ""))
+	assert.Check(t, is.Equal(secret, ""))
+}
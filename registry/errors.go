@@ -34,3 +34,7 @@ func invalidParamf(format string, args ...interface{}) error {
 func invalidParamWrapf(err error, format string, args ...interface{}) error {
 	return errdefs.InvalidParameter(errors.Wrapf(err, format, args...))
 }
+
+func forbiddenf(format string, args ...interface{}) error {
+	return errdefs.Forbidden(errors.Errorf(format, args...))
+}
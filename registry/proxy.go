@@ -0,0 +1,63 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// ProxyDirect is the special proxy value that disables proxying for a
+// registry host, including proxies configured in the environment.
+const ProxyDirect = "direct"
+
+// loadRegistryProxies validates the configured per-registry proxies. Values
+// must be proxy URLs using the http, https or socks5 scheme, or the special
+// value "direct".
+func loadRegistryProxies(proxies map[string]string) error {
+	for host, value := range proxies {
+		if value == ProxyDirect {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil {
+			return invalidParamWrapf(err, "invalid proxy for registry %s", host)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return invalidParamf("invalid proxy %s for registry %s: unsupported scheme %q", value, host, u.Scheme)
+		}
+	}
+	return nil
+}
+
+// ProxyFor returns the proxy configured for the registry at the given host.
+// The second return value is false when no proxy is configured for it.
+func (s *Service) ProxyFor(host string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	proxy, ok := s.proxies[host]
+	return proxy, ok
+}
+
+// NewProxiedTransport returns an HTTP transport for the registry at the given
+// host that dials through proxy, which must be a value accepted by
+// loadRegistryProxies. TLS material from the host's certs.d directory is
+// applied the same way as for a direct connection.
+func NewProxiedTransport(host, proxy string, isSecure bool) (http.RoundTripper, error) {
+	tlsConfig, err := newTLSConfig(host, isSecure)
+	if err != nil {
+		return nil, err
+	}
+	tr := newTransport(tlsConfig)
+	if proxy == ProxyDirect {
+		tr.Proxy = nil
+		return tr, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, invalidParamWrapf(err, "invalid proxy for registry %s", host)
+	}
+	// http.Transport handles socks5 proxy URLs in addition to HTTP(S) ones.
+	tr.Proxy = http.ProxyURL(u)
+	return tr, nil
+}
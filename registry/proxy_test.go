@@ -0,0 +1,21 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestLoadRegistryProxies(t *testing.T) {
+	valid := map[string]string{
+		"registry.example.com": "http://proxy.example.com:3128",
+		"other.example.com":    "socks5://proxy.example.com:1080",
+		"direct.example.com":   ProxyDirect,
+	}
+	assert.NilError(t, loadRegistryProxies(valid))
+
+	invalid := map[string]string{
+		"registry.example.com": "ftp://proxy.example.com",
+	}
+	assert.Check(t, loadRegistryProxies(invalid) != nil)
+}
@@ -91,6 +91,7 @@ func (s *Service) searchUnfiltered(ctx context.Context, term string, limit int,
 	// Search is a long-running operation, just lock s.config to avoid block others.
 	s.mu.RLock()
 	index, err := newIndexInfo(s.config, indexName)
+	searchEndpoint, hasSearchEndpoint := s.searchEndpoints[indexName]
 	s.mu.RUnlock()
 
 	if err != nil {
@@ -101,6 +102,10 @@ func (s *Service) searchUnfiltered(ctx context.Context, term string, limit int,
 		remoteName = strings.TrimPrefix(remoteName, "library/")
 	}
 
+	if hasSearchEndpoint {
+		return s.searchWithEndpoint(ctx, searchEndpoint, index, remoteName, limit, authConfig, headers)
+	}
+
 	endpoint, err := newV1Endpoint(index, headers)
 	if err != nil {
 		return nil, err
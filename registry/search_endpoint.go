@@ -0,0 +1,183 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/containerd/log"
+	"github.com/docker/distribution/registry/client/transport"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+const (
+	// SearchEndpointV1 selects the Docker Hub style /v1/search API.
+	SearchEndpointV1 = "v1"
+	// SearchEndpointCatalog selects a filtered listing of the OCI
+	// distribution /v2/_catalog API. Most private registries don't
+	// implement a native search API, so a filtered catalog listing is the
+	// closest equivalent.
+	SearchEndpointCatalog = "catalog"
+)
+
+// SearchEndpointConfig configures the search backend used for a single
+// registry host. It is configured per registry in the daemon configuration
+// ("search-endpoints").
+type SearchEndpointConfig struct {
+	// Type selects the search API flavor; either SearchEndpointV1 or
+	// SearchEndpointCatalog.
+	Type string `json:"type"`
+
+	// URL overrides the base URL used for search requests. When empty, the
+	// registry host itself is used.
+	URL string `json:"url,omitempty"`
+}
+
+// validateSearchEndpoints checks the per-registry search endpoint
+// configuration for unknown types and malformed URLs.
+func validateSearchEndpoints(endpoints map[string]SearchEndpointConfig) error {
+	for host, ep := range endpoints {
+		switch ep.Type {
+		case SearchEndpointV1, SearchEndpointCatalog:
+		default:
+			return invalidParamf("invalid search endpoint type %q for registry %s", ep.Type, host)
+		}
+		if ep.URL != "" {
+			u, err := url.Parse(ep.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return invalidParamf("invalid search endpoint url %q for registry %s", ep.URL, host)
+			}
+		}
+	}
+	return nil
+}
+
+// searchWithEndpoint performs a search using the search endpoint configured
+// for the registry in the daemon configuration.
+func (s *Service) searchWithEndpoint(ctx context.Context, cfg SearchEndpointConfig, index *registry.IndexInfo, remoteName string, limit int, authConfig *registry.AuthConfig, headers http.Header) (*registry.SearchResults, error) {
+	tlsConfig, err := newTLSConfig(index.Name, index.Secure)
+	if err != nil {
+		return nil, err
+	}
+
+	address := cfg.URL
+	if address == "" {
+		address = GetAuthConfigKey(index)
+	}
+
+	if cfg.Type == SearchEndpointCatalog {
+		if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+			address = "https://" + address
+		}
+		base, err := url.Parse(strings.TrimSuffix(address, "/"))
+		if err != nil {
+			return nil, invalidParam(err)
+		}
+		client := httpClient(transport.NewTransport(newTransport(tlsConfig), Headers("", headers)...))
+		return catalogSearch(ctx, client, base, remoteName, limit, authConfig)
+	}
+
+	endpoint, err := newV1EndpointFromStr(address, tlsConfig, headers)
+	if err != nil {
+		return nil, err
+	}
+	client := endpoint.client
+	if err := authorizeClient(client, authConfig, endpoint); err != nil {
+		return nil, err
+	}
+	return newSession(client, endpoint).searchRepositories(remoteName, limit)
+}
+
+// maxCatalogPages bounds how many _catalog pages are fetched for a single
+// search, to protect the daemon against registries with enormous catalogs.
+const maxCatalogPages = 10
+
+// catalogSearch lists repositories through the v2 _catalog API and filters
+// them with the search term. Pagination links are followed until enough
+// matches were collected.
+func catalogSearch(ctx context.Context, client *http.Client, base *url.URL, term string, limit int, authConfig *registry.AuthConfig) (*registry.SearchResults, error) {
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 1 || limit > 100 {
+		return nil, invalidParamf("limit %d is outside the range of [1, 100]", limit)
+	}
+
+	u := base.String() + "/v2/_catalog?n=" + strconv.Itoa(limit)
+	results := &registry.SearchResults{Query: term}
+	for page := 0; page < maxCatalogPages && len(results.Results) < limit; page++ {
+		log.G(ctx).WithField("url", u).Debug("catalogSearch")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, invalidParamWrapf(err, "error building request")
+		}
+		if authConfig != nil && authConfig.Username != "" {
+			req.SetBasicAuth(authConfig.Username, authConfig.Password)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, errdefs.System(err)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, errdefs.Unknown(&jsonmessage.JSONError{
+				Message: "Unexpected status code " + strconv.Itoa(res.StatusCode),
+				Code:    res.StatusCode,
+			})
+		}
+
+		var body struct {
+			Repositories []string `json:"repositories"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if err != nil {
+			return nil, errdefs.System(invalidParamWrapf(err, "error decoding catalog response"))
+		}
+
+		for _, name := range body.Repositories {
+			if term != "" && !strings.Contains(name, term) {
+				continue
+			}
+			results.Results = append(results.Results, registry.SearchResult{Name: name})
+			if len(results.Results) >= limit {
+				break
+			}
+		}
+
+		next := nextCatalogPage(res.Header.Get("Link"), base)
+		if next == "" {
+			break
+		}
+		u = next
+	}
+
+	results.NumResults = len(results.Results)
+	return results, nil
+}
+
+// nextCatalogPage extracts the next page URL from a Link response header as
+// produced by the distribution registry, resolved against the endpoint base
+// URL. It returns an empty string when there is no next page.
+func nextCatalogPage(linkHeader string, base *url.URL) string {
+	if linkHeader == "" || !strings.Contains(linkHeader, `rel="next"`) {
+		return ""
+	}
+	start := strings.Index(linkHeader, "<")
+	end := strings.Index(linkHeader, ">")
+	if start == -1 || end == -1 || end < start {
+		return ""
+	}
+	next, err := url.Parse(linkHeader[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(next).String()
+}
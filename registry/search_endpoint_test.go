@@ -0,0 +1,67 @@
+package registry // import "github.com/docker/docker/registry"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestValidateSearchEndpoints(t *testing.T) {
+	valid := map[string]SearchEndpointConfig{
+		"registry.example.com": {Type: SearchEndpointCatalog},
+		"other.example.com":    {Type: SearchEndpointV1, URL: "https://search.example.com"},
+	}
+	assert.NilError(t, validateSearchEndpoints(valid))
+
+	invalid := []map[string]SearchEndpointConfig{
+		{"registry.example.com": {Type: "bogus"}},
+		{"registry.example.com": {Type: ""}},
+		{"registry.example.com": {Type: SearchEndpointCatalog, URL: "ftp://registry.example.com"}},
+	}
+	for _, endpoints := range invalid {
+		assert.Check(t, validateSearchEndpoints(endpoints) != nil, "expected an error for %v", endpoints)
+	}
+}
+
+func TestCatalogSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/_catalog" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("last") {
+		case "":
+			w.Header().Set("Link", `</v2/_catalog?last=project%2Fbar&n=25>; rel="next"`)
+			fmt.Fprint(w, `{"repositories":["project/foo","project/bar"]}`)
+		default:
+			fmt.Fprint(w, `{"repositories":["unrelated/baz","project/foo-suffix"]}`)
+		}
+	}))
+	defer srv.Close()
+
+	base, err := url.Parse(srv.URL)
+	assert.NilError(t, err)
+
+	results, err := catalogSearch(context.Background(), srv.Client(), base, "foo", 0, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(results.NumResults, 2))
+	assert.Check(t, is.Equal(results.Results[0].Name, "project/foo"))
+	assert.Check(t, is.Equal(results.Results[1].Name, "project/foo-suffix"))
+}
+
+func TestNextCatalogPage(t *testing.T) {
+	base, err := url.Parse("https://registry.example.com")
+	assert.NilError(t, err)
+
+	next := nextCatalogPage(`</v2/_catalog?last=foo&n=25>; rel="next"`, base)
+	assert.Check(t, is.Equal(next, "https://registry.example.com/v2/_catalog?last=foo&n=25"))
+
+	assert.Check(t, is.Equal(nextCatalogPage("", base), ""))
+	assert.Check(t, is.Equal(nextCatalogPage(`</v2/_catalog?last=foo&n=25>; rel="prev"`, base), ""))
+}
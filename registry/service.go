@@ -16,16 +16,44 @@ import (
 // Service is a registry service. It tracks configuration data such as a list
 // of mirrors.
 type Service struct {
-	config *serviceConfig
-	mu     sync.RWMutex
+	config            *serviceConfig
+	searchEndpoints   map[string]SearchEndpointConfig
+	allowedRegistries map[string]struct{}
+	blockedRegistries map[string]struct{}
+	proxies           map[string]string
+	clientCerts       map[string]ClientCertificateConfig
+	mu                sync.RWMutex
 }
 
 // NewService returns a new instance of [Service] ready to be installed into
 // an engine.
 func NewService(options ServiceOptions) (*Service, error) {
 	config, err := newServiceConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSearchEndpoints(options.SearchEndpoints); err != nil {
+		return nil, err
+	}
+	allowed, blocked, err := loadRegistryAccessPolicy(options.AllowedRegistries, options.BlockedRegistries)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRegistryProxies(options.Proxies); err != nil {
+		return nil, err
+	}
+	if err := loadClientCertificates(options.ClientCertificates); err != nil {
+		return nil, err
+	}
 
-	return &Service{config: config}, err
+	return &Service{
+		config:            config,
+		searchEndpoints:   options.SearchEndpoints,
+		allowedRegistries: allowed,
+		blockedRegistries: blocked,
+		proxies:           options.Proxies,
+		clientCerts:       options.ClientCertificates,
+	}, nil
 }
 
 // ServiceConfig returns a copy of the public registry service's configuration.
@@ -42,10 +70,28 @@ func (s *Service) ReplaceConfig(options ServiceOptions) (commit func(), err erro
 	if err != nil {
 		return nil, err
 	}
+	if err := validateSearchEndpoints(options.SearchEndpoints); err != nil {
+		return nil, err
+	}
+	allowed, blocked, err := loadRegistryAccessPolicy(options.AllowedRegistries, options.BlockedRegistries)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRegistryProxies(options.Proxies); err != nil {
+		return nil, err
+	}
+	if err := loadClientCertificates(options.ClientCertificates); err != nil {
+		return nil, err
+	}
 	return func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		s.config = config
+		s.searchEndpoints = options.SearchEndpoints
+		s.allowedRegistries = allowed
+		s.blockedRegistries = blocked
+		s.proxies = options.Proxies
+		s.clientCerts = options.ClientCertificates
 	}, nil
 }
 
@@ -136,6 +182,26 @@ func (s *Service) LookupPushEndpoints(hostname string) (endpoints []APIEndpoint,
 	return endpoints, err
 }
 
+// ClientCertificates returns the client TLS key pairs configured per
+// registry host.
+func (s *Service) ClientCertificates() map[string]ClientCertificateConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	certs := make(map[string]ClientCertificateConfig, len(s.clientCerts))
+	for host, cert := range s.clientCerts {
+		certs[host] = cert
+	}
+	return certs
+}
+
+// AllowsNondistributableArtifacts returns true if the registry at given host
+// is configured to allow push of nondistributable artifacts.
+func (s *Service) AllowsNondistributableArtifacts(host string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.allowNondistributableArtifacts(host)
+}
+
 // IsInsecureRegistry returns true if the registry at given host is configured as
 // insecure registry.
 func (s *Service) IsInsecureRegistry(host string) bool {